@@ -0,0 +1,240 @@
+package aof
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidManifest is returned by ReadManifest when the manifest can not be parsed.
+var ErrInvalidManifest = errors.New("aof: invalid manifest")
+
+// FileType is the type of a file listed in a manifest.
+type FileType byte
+
+const (
+	// BaseFile is the type of the base file, which contains the data set at the time of the last rewrite. The base
+	// file is either an AOF or an RDB file.
+	BaseFile FileType = 'b'
+
+	// HistoryFile is the type of files that were replaced by a rewrite and are going to be deleted.
+	HistoryFile FileType = 'h'
+
+	// IncrFile is the type of incremental files, which contain the commands executed after the last rewrite.
+	IncrFile FileType = 'i'
+)
+
+// ManifestFile is a single file listed in a manifest.
+type ManifestFile struct {
+	// Name is the file name, relative to the directory containing the manifest.
+	Name string
+
+	// Seq is the sequence number of the file.
+	Seq int
+
+	// Type is the type of the file.
+	Type FileType
+}
+
+// IsRDB returns true if the file is an RDB file, as indicated by the file extension.
+func (f ManifestFile) IsRDB() bool {
+	return strings.HasSuffix(f.Name, ".rdb")
+}
+
+// Manifest lists the files that make up an AOF, as written by Redis 7 and later into the directory configured by
+// appenddirname.
+type Manifest struct {
+	// Files contains the listed files in the order in which they appear in the manifest.
+	Files []ManifestFile
+}
+
+// Base returns the base file, if any.
+func (m *Manifest) Base() (ManifestFile, bool) {
+	for _, f := range m.Files {
+		if f.Type == BaseFile {
+			return f, true
+		}
+	}
+	return ManifestFile{}, false
+}
+
+// Incr returns the incremental files ordered by their sequence number, which is the order in which they must be
+// read after the base file.
+func (m *Manifest) Incr() []ManifestFile {
+	var fs []ManifestFile
+	for _, f := range m.Files {
+		if f.Type == IncrFile {
+			fs = append(fs, f)
+		}
+	}
+	sort.SliceStable(fs, func(i, j int) bool { return fs[i].Seq < fs[j].Seq })
+	return fs
+}
+
+// Paths returns the paths of the base file and of the incremental files, in the order in which they must be read,
+// relative to the directory dir containing the manifest.
+//
+// If skipRDB is true, a base file in RDB format is not included.
+func (m *Manifest) Paths(dir string, skipRDB bool) []string {
+	var paths []string
+	if base, ok := m.Base(); ok && !(skipRDB && base.IsRDB()) {
+		paths = append(paths, filepath.Join(dir, base.Name))
+	}
+	for _, f := range m.Incr() {
+		paths = append(paths, filepath.Join(dir, f.Name))
+	}
+	return paths
+}
+
+// ReadManifest reads a manifest as written by Redis.
+//
+// Each line of a manifest describes a single file using key-value pairs, for example:
+//
+//	file appendonly.aof.1.base.rdb seq 1 type b
+//
+// Empty lines and lines starting with # are ignored, as are unknown keys.
+func ReadManifest(r io.Reader) (*Manifest, error) {
+	var m Manifest
+	var hasBase bool
+
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		fields, err := splitManifestLine(line)
+		if err != nil || len(fields)%2 != 0 {
+			return nil, ErrInvalidManifest
+		}
+
+		var f ManifestFile
+		for i := 0; i < len(fields); i += 2 {
+			switch v := fields[i+1]; fields[i] {
+			case "file":
+				f.Name = v
+			case "seq":
+				if f.Seq, err = strconv.Atoi(v); err != nil {
+					return nil, ErrInvalidManifest
+				}
+			case "type":
+				if len(v) != 1 {
+					return nil, ErrInvalidManifest
+				}
+				f.Type = FileType(v[0])
+			}
+		}
+
+		switch {
+		case f.Name == "" || f.Seq <= 0:
+			return nil, ErrInvalidManifest
+		case f.Type == BaseFile:
+			if hasBase {
+				return nil, ErrInvalidManifest
+			}
+			hasBase = true
+		case f.Type != HistoryFile && f.Type != IncrFile:
+			return nil, ErrInvalidManifest
+		}
+
+		m.Files = append(m.Files, f)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+
+	return &m, nil
+}
+
+// splitManifestLine splits a line into fields. Fields containing spaces or special characters are quoted by Redis
+// using the same escape sequences as Go.
+func splitManifestLine(line string) ([]string, error) {
+	var fields []string
+	for {
+		line = strings.TrimLeft(line, " \t")
+		if line == "" {
+			return fields, nil
+		}
+
+		if line[0] != '"' {
+			i := strings.IndexAny(line, " \t")
+			if i == -1 {
+				i = len(line)
+			}
+			fields, line = append(fields, line[:i]), line[i:]
+			continue
+		}
+
+		// find the closing quote, skipping escaped characters
+		i := 1
+		for i < len(line) && line[i] != '"' {
+			if line[i] == '\\' {
+				i++
+			}
+			i++
+		}
+		if i >= len(line) {
+			return nil, ErrInvalidManifest
+		}
+
+		s, err := strconv.Unquote(line[:i+1])
+		if err != nil {
+			return nil, ErrInvalidManifest
+		}
+		fields, line = append(fields, s), line[i+1:]
+	}
+}
+
+// WriteTo writes the manifest to w, in the same format as Redis.
+func (m *Manifest) WriteTo(w io.Writer) (int64, error) {
+	var b []byte
+	for _, f := range m.Files {
+		b = append(b, "file "...)
+		b = appendFileName(b, f.Name)
+		b = append(b, " seq "...)
+		b = strconv.AppendInt(b, int64(f.Seq), 10)
+		b = append(b, " type "...)
+		b = append(b, byte(f.Type), '\n')
+	}
+	n, err := w.Write(b)
+	return int64(n), err
+}
+
+// appendFileName appends the file name s, quoting it like Redis if it contains spaces or special characters.
+func appendFileName(dst []byte, s string) []byte {
+	const hex = "0123456789abcdef"
+
+	if strings.IndexFunc(s, func(r rune) bool { return r <= ' ' || r > '~' || r == '"' || r == '\\' }) < 0 {
+		return append(dst, s...)
+	}
+
+	dst = append(dst, '"')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '\\', '"':
+			dst = append(dst, '\\', c)
+		case '\n':
+			dst = append(dst, '\\', 'n')
+		case '\r':
+			dst = append(dst, '\\', 'r')
+		case '\t':
+			dst = append(dst, '\\', 't')
+		case '\a':
+			dst = append(dst, '\\', 'a')
+		case '\b':
+			dst = append(dst, '\\', 'b')
+		default:
+			if c < ' ' || c > '~' {
+				dst = append(dst, '\\', 'x', hex[c>>4], hex[c&0xf])
+			} else {
+				dst = append(dst, c)
+			}
+		}
+	}
+	return append(dst, '"')
+}
@@ -0,0 +1,97 @@
+package aof_test
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/nussjustin/resp/aof"
+)
+
+func TestReadManifest(t *testing.T) {
+	const in = "file appendonly.aof.1.base.rdb seq 1 type b\n" +
+		"# comment\n" +
+		"\n" +
+		"file appendonly.aof.1.incr.aof seq 1 type h\n" +
+		"file \"append only.aof.3.incr.aof\" seq 3 type i\n" +
+		"type i seq 2 file appendonly.aof.2.incr.aof unknown key\n"
+
+	m, err := aof.ReadManifest(strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("got error %v", err)
+	}
+
+	expected := []aof.ManifestFile{
+		{Name: "appendonly.aof.1.base.rdb", Seq: 1, Type: aof.BaseFile},
+		{Name: "appendonly.aof.1.incr.aof", Seq: 1, Type: aof.HistoryFile},
+		{Name: "append only.aof.3.incr.aof", Seq: 3, Type: aof.IncrFile},
+		{Name: "appendonly.aof.2.incr.aof", Seq: 2, Type: aof.IncrFile},
+	}
+	if !reflect.DeepEqual(m.Files, expected) {
+		t.Errorf("got files %+v, expected %+v", m.Files, expected)
+	}
+
+	base, ok := m.Base()
+	if !ok || base != expected[0] || !base.IsRDB() {
+		t.Errorf("got base file %+v, expected %+v", base, expected[0])
+	}
+
+	dir := filepath.Join("data", "appendonlydir")
+
+	gotPaths := m.Paths(dir, true)
+	expectedPaths := []string{
+		filepath.Join(dir, "appendonly.aof.2.incr.aof"),
+		filepath.Join(dir, "append only.aof.3.incr.aof"),
+	}
+	if !reflect.DeepEqual(gotPaths, expectedPaths) {
+		t.Errorf("got paths %q, expected %q", gotPaths, expectedPaths)
+	}
+
+	if got := m.Paths(dir, false); len(got) != 3 || got[0] != filepath.Join(dir, "appendonly.aof.1.base.rdb") {
+		t.Errorf("got paths %q, expected base file to be included", got)
+	}
+}
+
+func TestReadManifestInvalid(t *testing.T) {
+	for _, in := range []string{
+		"file appendonly.aof.1.base.aof seq 1\n",
+		"file appendonly.aof.1.base.aof seq 1 type x\n",
+		"file appendonly.aof.1.base.aof seq x type b\n",
+		"file appendonly.aof.1.base.aof seq 0 type b\n",
+		"seq 1 type b\n",
+		"file appendonly.aof.1.base.aof seq 1 type\n",
+		"file \"appendonly.aof.1.base.aof seq 1 type b\n",
+		"file appendonly.aof.1.base.aof seq 1 type b\nfile appendonly.aof.2.base.aof seq 2 type b\n",
+	} {
+		if _, err := aof.ReadManifest(strings.NewReader(in)); err != aof.ErrInvalidManifest {
+			t.Errorf("got error %v for %q, expected %v", err, in, aof.ErrInvalidManifest)
+		}
+	}
+}
+
+func TestManifestWriteTo(t *testing.T) {
+	m := &aof.Manifest{Files: []aof.ManifestFile{
+		{Name: "appendonly.aof.1.base.rdb", Seq: 1, Type: aof.BaseFile},
+		{Name: "append\"only\xff.aof.1.incr.aof", Seq: 1, Type: aof.IncrFile},
+	}}
+
+	var sb strings.Builder
+	if _, err := m.WriteTo(&sb); err != nil {
+		t.Fatalf("got error %v", err)
+	}
+
+	const expected = "file appendonly.aof.1.base.rdb seq 1 type b\n" +
+		"file \"append\\\"only\\xff.aof.1.incr.aof\" seq 1 type i\n"
+	if got := sb.String(); got != expected {
+		t.Errorf("got %q, expected %q", got, expected)
+	}
+
+	got, err := aof.ReadManifest(strings.NewReader(sb.String()))
+	if err != nil {
+		t.Fatalf("got error %v", err)
+	}
+	if !reflect.DeepEqual(got, m) {
+		t.Errorf("got %+v after round trip, expected %+v", got, m)
+	}
+}
@@ -0,0 +1,457 @@
+package aof
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"strconv"
+)
+
+// RDB opcodes, see rdb.h in the Redis source.
+const (
+	rdbOpSlotInfo     = 0xf4
+	rdbOpFunction2    = 0xf5
+	rdbOpModuleAux    = 0xf7
+	rdbOpIdle         = 0xf8
+	rdbOpFreq         = 0xf9
+	rdbOpAux          = 0xfa
+	rdbOpResizeDB     = 0xfb
+	rdbOpExpireTimeMs = 0xfc
+	rdbOpExpireTime   = 0xfd
+	rdbOpSelectDB     = 0xfe
+	rdbOpEOF          = 0xff
+)
+
+// RDB value types, see rdb.h in the Redis source.
+const (
+	rdbTypeString           = 0
+	rdbTypeList             = 1
+	rdbTypeSet              = 2
+	rdbTypeZSet             = 3
+	rdbTypeHash             = 4
+	rdbTypeZSet2            = 5
+	rdbTypeModule2          = 7
+	rdbTypeHashZipmap       = 9
+	rdbTypeListZiplist      = 10
+	rdbTypeSetIntset        = 11
+	rdbTypeZSetZiplist      = 12
+	rdbTypeHashZiplist      = 13
+	rdbTypeListQuicklist    = 14
+	rdbTypeStreamListpacks  = 15
+	rdbTypeHashListpack     = 16
+	rdbTypeZSetListpack     = 17
+	rdbTypeListQuicklist2   = 18
+	rdbTypeStreamListpacks2 = 19
+	rdbTypeSetListpack      = 20
+	rdbTypeStreamListpacks3 = 21
+	rdbTypeHashMetadata     = 24
+	rdbTypeHashListpackEx   = 25
+)
+
+// rdbSkipper skips over an RDB file without decoding the contained data.
+type rdbSkipper struct {
+	br      *bufio.Reader
+	version int
+}
+
+// skipRDB skips the RDB file at the start of br, including the trailing checksum.
+//
+// An error is returned if the RDB file is invalid or contains types that are not known. If the input ends before the
+// end of the RDB file, io.ErrUnexpectedEOF is returned.
+func skipRDB(br *bufio.Reader) error {
+	s := rdbSkipper{br: br}
+	err := s.skip()
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+func (s *rdbSkipper) skip() error {
+	var header [9]byte
+	if _, err := io.ReadFull(s.br, header[:]); err != nil {
+		return err
+	}
+	if string(header[:5]) != "REDIS" {
+		return ErrBadFormat
+	}
+	version, err := strconv.Atoi(string(header[5:]))
+	if err != nil {
+		return ErrBadFormat
+	}
+	s.version = version
+
+	for {
+		op, err := s.br.ReadByte()
+		if err != nil {
+			return err
+		}
+
+		switch op {
+		case rdbOpEOF:
+			if s.version >= 5 {
+				// CRC64 checksum
+				return s.discard(8)
+			}
+			return nil
+		case rdbOpSlotInfo:
+			err = s.lengths(3)
+		case rdbOpFunction2:
+			err = s.string()
+		case rdbOpModuleAux:
+			if err = s.lengths(3); err == nil {
+				err = s.module()
+			}
+		case rdbOpIdle:
+			err = s.lengths(1)
+		case rdbOpFreq:
+			err = s.discard(1)
+		case rdbOpAux:
+			err = s.strings(2)
+		case rdbOpResizeDB:
+			err = s.lengths(2)
+		case rdbOpExpireTimeMs:
+			err = s.discard(8)
+		case rdbOpExpireTime:
+			err = s.discard(4)
+		case rdbOpSelectDB:
+			err = s.lengths(1)
+		default:
+			// key followed by the value
+			if err = s.string(); err == nil {
+				err = s.value(op)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// value skips a value of type t.
+func (s *rdbSkipper) value(t byte) error {
+	switch t {
+	case rdbTypeString, rdbTypeHashZipmap, rdbTypeListZiplist, rdbTypeSetIntset, rdbTypeZSetZiplist,
+		rdbTypeHashZiplist, rdbTypeHashListpack, rdbTypeZSetListpack, rdbTypeSetListpack:
+		return s.string()
+	case rdbTypeList, rdbTypeSet, rdbTypeListQuicklist:
+		n, err := s.length()
+		if err != nil {
+			return err
+		}
+		return s.strings(n)
+	case rdbTypeHash:
+		n, err := s.length()
+		if err != nil {
+			return err
+		}
+		return s.strings(n * 2)
+	case rdbTypeZSet:
+		n, err := s.length()
+		if err != nil {
+			return err
+		}
+		for ; n > 0; n-- {
+			if err := s.string(); err != nil {
+				return err
+			}
+			// the score is a string with a one byte length, where 253 to 255 are used for NaN and infinity
+			l, err := s.br.ReadByte()
+			if err != nil {
+				return err
+			}
+			if l < 253 {
+				if err := s.discard(uint64(l)); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	case rdbTypeZSet2:
+		n, err := s.length()
+		if err != nil {
+			return err
+		}
+		for ; n > 0; n-- {
+			if err := s.string(); err != nil {
+				return err
+			}
+			// binary double
+			if err := s.discard(8); err != nil {
+				return err
+			}
+		}
+		return nil
+	case rdbTypeListQuicklist2:
+		n, err := s.length()
+		if err != nil {
+			return err
+		}
+		for ; n > 0; n-- {
+			// container type followed by the node
+			if err := s.lengths(1); err != nil {
+				return err
+			}
+			if err := s.string(); err != nil {
+				return err
+			}
+		}
+		return nil
+	case rdbTypeHashMetadata:
+		// minimum expire time of all fields
+		if err := s.discard(8); err != nil {
+			return err
+		}
+		n, err := s.length()
+		if err != nil {
+			return err
+		}
+		for ; n > 0; n-- {
+			// time to live followed by field and value
+			if err := s.lengths(1); err != nil {
+				return err
+			}
+			if err := s.strings(2); err != nil {
+				return err
+			}
+		}
+		return nil
+	case rdbTypeHashListpackEx:
+		// minimum expire time of all fields followed by the listpack
+		if err := s.discard(8); err != nil {
+			return err
+		}
+		return s.string()
+	case rdbTypeModule2:
+		if err := s.lengths(1); err != nil {
+			return err
+		}
+		return s.module()
+	case rdbTypeStreamListpacks, rdbTypeStreamListpacks2, rdbTypeStreamListpacks3:
+		return s.stream(t)
+	default:
+		return ErrBadFormat
+	}
+}
+
+// module skips data written by a module, which consists of typed values up to an EOF marker.
+func (s *rdbSkipper) module() error {
+	for {
+		op, err := s.length()
+		if err != nil {
+			return err
+		}
+
+		switch op {
+		case 0: // EOF
+			return nil
+		case 1, 2: // signed and unsigned integer
+			err = s.lengths(1)
+		case 3: // float
+			err = s.discard(4)
+		case 4: // double
+			err = s.discard(8)
+		case 5: // string
+			err = s.string()
+		default:
+			return ErrBadFormat
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// stream skips a stream of type t.
+func (s *rdbSkipper) stream(t byte) error {
+	// listpacks, each with the master ID as key
+	n, err := s.length()
+	if err != nil {
+		return err
+	}
+	if err := s.strings(n * 2); err != nil {
+		return err
+	}
+
+	// length and last ID, followed by first ID, max deleted ID and number of added entries for newer versions
+	fields := uint64(3)
+	if t >= rdbTypeStreamListpacks2 {
+		fields += 5
+	}
+	if err := s.lengths(fields); err != nil {
+		return err
+	}
+
+	groups, err := s.length()
+	if err != nil {
+		return err
+	}
+	for ; groups > 0; groups-- {
+		// name, last ID and for newer versions the number of read entries
+		if err := s.string(); err != nil {
+			return err
+		}
+		fields := uint64(2)
+		if t >= rdbTypeStreamListpacks2 {
+			fields++
+		}
+		if err := s.lengths(fields); err != nil {
+			return err
+		}
+
+		// pending entries, each with raw ID, delivery time and delivery count
+		pending, err := s.length()
+		if err != nil {
+			return err
+		}
+		for ; pending > 0; pending-- {
+			if err := s.discard(16 + 8); err != nil {
+				return err
+			}
+			if err := s.lengths(1); err != nil {
+				return err
+			}
+		}
+
+		consumers, err := s.length()
+		if err != nil {
+			return err
+		}
+		for ; consumers > 0; consumers-- {
+			// name, seen time and for newer versions active time
+			if err := s.string(); err != nil {
+				return err
+			}
+			times := uint64(8)
+			if t >= rdbTypeStreamListpacks3 {
+				times += 8
+			}
+			if err := s.discard(times); err != nil {
+				return err
+			}
+
+			// raw IDs of pending entries
+			pending, err := s.length()
+			if err != nil {
+				return err
+			}
+			if err := s.discard(pending * 16); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// length reads a length. Special encodings, which are only valid for strings, are rejected.
+func (s *rdbSkipper) length() (uint64, error) {
+	n, special, err := s.encodedLength()
+	if err == nil && special {
+		err = ErrBadFormat
+	}
+	return n, err
+}
+
+// lengths skips n lengths.
+func (s *rdbSkipper) lengths(n uint64) error {
+	for ; n > 0; n-- {
+		if _, err := s.length(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodedLength reads a length. If special is true, n is the type of a specially encoded string instead.
+func (s *rdbSkipper) encodedLength() (n uint64, special bool, err error) {
+	b, err := s.br.ReadByte()
+	if err != nil {
+		return 0, false, err
+	}
+
+	switch b >> 6 {
+	case 0:
+		return uint64(b & 0x3f), false, nil
+	case 1:
+		b1, err := s.br.ReadByte()
+		if err != nil {
+			return 0, false, err
+		}
+		return uint64(b&0x3f)<<8 | uint64(b1), false, nil
+	case 2:
+		var buf [8]byte
+		switch b {
+		case 0x80:
+			if _, err := io.ReadFull(s.br, buf[:4]); err != nil {
+				return 0, false, err
+			}
+			return uint64(binary.BigEndian.Uint32(buf[:4])), false, nil
+		case 0x81:
+			if _, err := io.ReadFull(s.br, buf[:]); err != nil {
+				return 0, false, err
+			}
+			return binary.BigEndian.Uint64(buf[:]), false, nil
+		default:
+			return 0, false, ErrBadFormat
+		}
+	default:
+		return uint64(b & 0x3f), true, nil
+	}
+}
+
+// string skips a string, which can be stored as integer or be compressed.
+func (s *rdbSkipper) string() error {
+	n, special, err := s.encodedLength()
+	if err != nil {
+		return err
+	}
+	if !special {
+		return s.discard(n)
+	}
+
+	switch n {
+	case 0: // 8 bit integer
+		return s.discard(1)
+	case 1: // 16 bit integer
+		return s.discard(2)
+	case 2: // 32 bit integer
+		return s.discard(4)
+	case 3: // LZF compressed string, followed by the uncompressed length
+		clen, err := s.length()
+		if err != nil {
+			return err
+		}
+		if _, err := s.length(); err != nil {
+			return err
+		}
+		return s.discard(clen)
+	default:
+		return ErrBadFormat
+	}
+}
+
+// strings skips n strings.
+func (s *rdbSkipper) strings(n uint64) error {
+	for ; n > 0; n-- {
+		if err := s.string(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// discard skips n bytes.
+func (s *rdbSkipper) discard(n uint64) error {
+	for n > 0 {
+		m := n
+		if m > 1<<30 {
+			m = 1 << 30
+		}
+		if _, err := s.br.Discard(int(m)); err != nil {
+			return err
+		}
+		n -= m
+	}
+	return nil
+}
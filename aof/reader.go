@@ -0,0 +1,236 @@
+// Package aof implements reading and writing Redis append-only files (AOF).
+//
+// An AOF is a sequence of commands, each encoded as RESP array of bulk strings, that Redis replays on startup to
+// restore the data set. Since Redis 7 the AOF can be split into multiple files that are listed in a manifest, see
+// ReadManifest.
+//
+// Files that start with an RDB preamble, as written by Redis when aof-use-rdb-preamble is enabled, are supported by
+// skipping the preamble. The data set stored in the preamble is not decoded, only the commands following it are
+// returned.
+package aof
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/nussjustin/resp"
+)
+
+// ErrBadFormat is returned by Reader.Read when the input contains data that is not a valid command or a
+// malformed RDB preamble.
+var ErrBadFormat = errors.New("aof: bad file format")
+
+// countingReader counts the number of bytes read from the underlying io.Reader.
+type countingReader struct {
+	r io.Reader
+	n int64
+
+	// inValue is set while reading a value, in which case the end of the input is reported as io.ErrUnexpectedEOF.
+	inValue bool
+}
+
+func (cr *countingReader) Read(b []byte) (int, error) {
+	n, err := cr.r.Read(b)
+	cr.n += int64(n)
+	if err == io.EOF && cr.inValue {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+// Reader reads commands from an AOF.
+//
+// Like Redis, Reader skips timestamp annotations (lines of the form #TS:<unix time>) and handles files that end in
+// the middle of a command, as written when Redis is killed while writing the AOF, the same way Redis does when
+// aof-load-truncated is enabled: The incomplete command is ignored, Read returns io.EOF and Truncated returns true.
+//
+// Commands inside a MULTI/EXEC transaction are only returned once the EXEC was read, so that the commands of a
+// transaction that is cut off at the end of the file are never returned.
+type Reader struct {
+	cr countingReader
+	br *bufio.Reader
+	rr *resp.Reader
+
+	// offset is the number of bytes up to the end of the last complete command or transaction.
+	offset int64
+
+	// pending contains the commands of the last transaction that were not yet returned.
+	pending []resp.Command
+
+	// ts is the time of the last timestamp annotation that was read before the last returned command.
+	ts time.Time
+
+	// pendingTS is the time of the last timestamp annotation that was read.
+	pendingTS time.Time
+
+	preamble  bool
+	truncated bool
+	err       error
+}
+
+// NewReader returns a new Reader reading from r.
+func NewReader(r io.Reader) *Reader {
+	ar := &Reader{cr: countingReader{r: r}}
+	ar.br = bufio.NewReader(&ar.cr)
+	ar.rr = resp.NewReader(ar.br)
+	return ar
+}
+
+// Offset returns the number of bytes of the input up to the end of the last complete command or transaction that
+// was read.
+//
+// If Truncated returns true, Offset is the size to which the file can be truncated to remove the incomplete data.
+func (r *Reader) Offset() int64 {
+	return r.offset
+}
+
+// Timestamp returns the time of the last timestamp annotation that preceded the last command returned by Read, or
+// the zero time if there was none.
+func (r *Reader) Timestamp() time.Time {
+	return r.ts
+}
+
+// Preamble returns true if the input started with an RDB preamble, which was skipped.
+func (r *Reader) Preamble() bool {
+	return r.preamble
+}
+
+// Truncated returns true if the input ended with an incomplete command or transaction.
+func (r *Reader) Truncated() bool {
+	return r.truncated
+}
+
+// Read reads the next command into cmd. The slices in cmd.Args are reused if possible.
+//
+// At the end of the input, including when the input ends with an incomplete command, Read returns io.EOF. Once Read
+// returned an error, all further calls return the same error.
+func (r *Reader) Read(cmd *resp.Command) error {
+	if len(r.pending) == 0 && r.err == nil {
+		r.err = r.readNext()
+	}
+	if len(r.pending) == 0 {
+		return r.err
+	}
+
+	next := r.pending[0]
+	r.pending = r.pending[1:]
+
+	args := cmd.Args[:0]
+	for i, arg := range next.Args {
+		var buf []byte
+		if i < cap(args) {
+			buf = args[:i+1][i][:0]
+		}
+		args = append(args, append(buf, arg...))
+	}
+	cmd.Args = args
+	r.ts = r.pendingTS
+	return nil
+}
+
+// readNext reads the next command or transaction into r.pending.
+func (r *Reader) readNext() error {
+	var cmd resp.Command
+	if err := r.readCommand(&cmd); err != nil {
+		return err
+	}
+
+	if !cmd.Is("MULTI") {
+		r.offset = r.consumed()
+		r.pending = append(r.pending[:0], cmd)
+		return nil
+	}
+
+	r.pending = append(r.pending[:0], cmd)
+	for {
+		var cmd resp.Command
+		if err := r.readCommand(&cmd); err == io.EOF {
+			// the transaction was not completed, so it is ignored like the rest of a truncated command
+			r.pending = r.pending[:0]
+			r.truncated = true
+			return io.EOF
+		} else if err != nil {
+			r.pending = r.pending[:0]
+			return err
+		}
+
+		r.pending = append(r.pending, cmd)
+
+		if cmd.Is("EXEC") {
+			r.offset = r.consumed()
+			return nil
+		}
+	}
+}
+
+// consumed returns the number of bytes consumed from the input.
+func (r *Reader) consumed() int64 {
+	return r.cr.n - int64(r.br.Buffered())
+}
+
+// readCommand reads the next command, skipping any annotations and an RDB preamble at the start of the input.
+func (r *Reader) readCommand(cmd *resp.Command) error {
+	for {
+		b, err := r.br.Peek(1)
+		if err != nil {
+			return err
+		}
+
+		switch b[0] {
+		case '#':
+			line, err := r.br.ReadSlice('\n')
+			if err == io.EOF {
+				r.truncated = true
+				return io.EOF
+			} else if err != nil {
+				return err
+			}
+			r.annotation(line)
+		case '*':
+			r.cr.inValue = true
+			err := r.rr.ReadCommand(cmd)
+			r.cr.inValue = false
+
+			switch err {
+			case nil:
+				return nil
+			case io.EOF, io.ErrUnexpectedEOF:
+				r.truncated = true
+				return io.EOF
+			case resp.ErrInvalidCommand:
+				return ErrBadFormat
+			default:
+				return err
+			}
+		default:
+			if b, _ := r.br.Peek(5); r.consumed() != 0 || string(b) != "REDIS" {
+				return ErrBadFormat
+			}
+
+			r.cr.inValue = true
+			err := skipRDB(r.br)
+			r.cr.inValue = false
+
+			if err != nil {
+				return err
+			}
+			r.preamble = true
+			r.offset = r.consumed()
+		}
+	}
+}
+
+// annotation parses an annotation line. Unknown annotations are ignored, like Redis does.
+func (r *Reader) annotation(line []byte) {
+	line = bytes.TrimRight(line, "\r\n")
+	if !bytes.HasPrefix(line, []byte("#TS:")) {
+		return
+	}
+	if sec, err := strconv.ParseInt(string(line[len("#TS:"):]), 10, 64); err == nil {
+		r.pendingTS = time.Unix(sec, 0)
+	}
+}
@@ -0,0 +1,227 @@
+package aof_test
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nussjustin/resp"
+	"github.com/nussjustin/resp/aof"
+)
+
+// readAll reads all commands from r, returning each command as space separated string.
+func readAll(tb testing.TB, r *aof.Reader) ([]string, error) {
+	tb.Helper()
+
+	var cmds []string
+	var cmd resp.Command
+	for {
+		if err := r.Read(&cmd); err != nil {
+			return cmds, err
+		}
+
+		args := make([]string, len(cmd.Args))
+		for i, arg := range cmd.Args {
+			args[i] = string(arg)
+		}
+		cmds = append(cmds, strings.Join(args, " "))
+	}
+}
+
+const (
+	selectCmd = "*2\r\n$6\r\nSELECT\r\n$1\r\n0\r\n"
+	setCmd    = "*3\r\n$3\r\nSET\r\n$1\r\na\r\n$1\r\n1\r\n"
+	multiCmd  = "*1\r\n$5\r\nMULTI\r\n"
+	incrCmd   = "*2\r\n$4\r\nINCR\r\n$1\r\na\r\n"
+	execCmd   = "*1\r\n$4\r\nEXEC\r\n"
+
+	// rdbPreamble is a small RDB file with values of different types, as written at the start of an AOF when
+	// aof-use-rdb-preamble is enabled.
+	rdbPreamble = "REDIS0011" +
+		"\xfa\x09redis-ver\x057.2.4" + // aux field
+		"\xfa\x08aof-base\xc0\x01" + // aux field with integer value
+		"\xfe\x00\xfb\x04\x01" + // select database 0 and resize
+		"\xfc\x00\x00\x00\x00\x00\x00\x00\x00\x00\x01a\x011" + // string with expire time
+		"\x00\x01z\xc3\x02\x05ab" + // compressed string
+		"\x12\x01l\x01\x02\x03lp1" + // quicklist
+		"\x05\x01s\x01\x01m\x00\x00\x00\x00\x00\x00\xf0\x3f" + // sorted set
+		"\xff\x00\x00\x00\x00\x00\x00\x00\x00" // end of file and checksum
+)
+
+func TestReader(t *testing.T) {
+	for _, test := range []struct {
+		Name      string
+		In        string
+		Expected  []string
+		Err       error
+		Truncated bool
+		Offset    int
+	}{
+		{Name: "empty", Err: io.EOF},
+		{
+			Name:     "commands",
+			In:       selectCmd + setCmd,
+			Expected: []string{"SELECT 0", "SET a 1"},
+			Err:      io.EOF,
+			Offset:   len(selectCmd + setCmd),
+		},
+		{
+			Name:     "annotations",
+			In:       "#TS:1628217470\r\n" + selectCmd + "#unknown\r\n" + setCmd,
+			Expected: []string{"SELECT 0", "SET a 1"},
+			Err:      io.EOF,
+			Offset:   len("#TS:1628217470\r\n" + selectCmd + "#unknown\r\n" + setCmd),
+		},
+		{
+			Name:     "transaction",
+			In:       multiCmd + incrCmd + incrCmd + execCmd + setCmd,
+			Expected: []string{"MULTI", "INCR a", "INCR a", "EXEC", "SET a 1"},
+			Err:      io.EOF,
+			Offset:   len(multiCmd + incrCmd + incrCmd + execCmd + setCmd),
+		},
+		{
+			Name:      "truncated command",
+			In:        selectCmd + setCmd[:len(setCmd)-3],
+			Expected:  []string{"SELECT 0"},
+			Err:       io.EOF,
+			Truncated: true,
+			Offset:    len(selectCmd),
+		},
+		{
+			Name:      "truncated header",
+			In:        selectCmd + "*3\r",
+			Expected:  []string{"SELECT 0"},
+			Err:       io.EOF,
+			Truncated: true,
+			Offset:    len(selectCmd),
+		},
+		{
+			Name:      "truncated annotation",
+			In:        selectCmd + "#TS:16",
+			Expected:  []string{"SELECT 0"},
+			Err:       io.EOF,
+			Truncated: true,
+			Offset:    len(selectCmd),
+		},
+		{
+			Name:      "incomplete transaction",
+			In:        selectCmd + multiCmd + incrCmd + incrCmd,
+			Expected:  []string{"SELECT 0"},
+			Err:       io.EOF,
+			Truncated: true,
+			Offset:    len(selectCmd),
+		},
+		{
+			Name:     "bad format",
+			In:       selectCmd + "SET a 1\r\n",
+			Expected: []string{"SELECT 0"},
+			Err:      aof.ErrBadFormat,
+			Offset:   len(selectCmd),
+		},
+		{
+			Name:     "not a command",
+			In:       selectCmd + "*1\r\n:1\r\n" + setCmd,
+			Expected: []string{"SELECT 0"},
+			Err:      aof.ErrBadFormat,
+			Offset:   len(selectCmd),
+		},
+		{
+			Name:     "malformed last command",
+			In:       selectCmd + "*1\r\n$2\r\nabcd",
+			Expected: []string{"SELECT 0"},
+			Err:      resp.ErrUnexpectedEOL,
+			Offset:   len(selectCmd),
+		},
+		{
+			Name:     "RDB preamble",
+			In:       rdbPreamble + selectCmd + setCmd,
+			Expected: []string{"SELECT 0", "SET a 1"},
+			Err:      io.EOF,
+			Offset:   len(rdbPreamble + selectCmd + setCmd),
+		},
+		{
+			Name: "truncated RDB preamble",
+			In:   rdbPreamble[:len(rdbPreamble)-4],
+			Err:  io.ErrUnexpectedEOF,
+		},
+		{
+			Name: "invalid RDB preamble",
+			In:   "REDIS0011\xfa\x82",
+			Err:  aof.ErrBadFormat,
+		},
+		{
+			Name:     "RDB preamble after commands",
+			In:       selectCmd + rdbPreamble,
+			Expected: []string{"SELECT 0"},
+			Err:      aof.ErrBadFormat,
+			Offset:   len(selectCmd),
+		},
+	} {
+		test := test
+
+		t.Run(test.Name, func(t *testing.T) {
+			r := aof.NewReader(strings.NewReader(test.In))
+
+			got, err := readAll(t, r)
+			if err != test.Err {
+				t.Errorf("got error %v, expected %v", err, test.Err)
+			}
+			if !reflect.DeepEqual(got, test.Expected) {
+				t.Errorf("got commands %q, expected %q", got, test.Expected)
+			}
+			if r.Truncated() != test.Truncated {
+				t.Errorf("got truncated %t, expected %t", r.Truncated(), test.Truncated)
+			}
+			if r.Offset() != int64(test.Offset) {
+				t.Errorf("got offset %d, expected %d", r.Offset(), test.Offset)
+			}
+
+			var cmd resp.Command
+			if err := r.Read(&cmd); err != test.Err {
+				t.Errorf("got error %v on next read, expected %v", err, test.Err)
+			}
+		})
+	}
+}
+
+func TestReaderTimestamp(t *testing.T) {
+	in := selectCmd + "#TS:1628217470\r\n" + setCmd + setCmd + "#TS:1628217471\r\n" + setCmd
+	r := aof.NewReader(strings.NewReader(in))
+
+	var cmd resp.Command
+	for i, expected := range []int64{0, 1628217470, 1628217470, 1628217471} {
+		if err := r.Read(&cmd); err != nil {
+			t.Fatalf("got error %v reading command %d", err, i)
+		}
+
+		var expectedTime time.Time
+		if expected != 0 {
+			expectedTime = time.Unix(expected, 0)
+		}
+		if got := r.Timestamp(); !got.Equal(expectedTime) {
+			t.Errorf("got timestamp %v for command %d, expected %v", got, i, expectedTime)
+		}
+	}
+}
+
+func TestReaderPreamble(t *testing.T) {
+	for _, test := range []struct {
+		In       string
+		Expected bool
+	}{
+		{In: selectCmd, Expected: false},
+		{In: rdbPreamble + selectCmd, Expected: true},
+	} {
+		r := aof.NewReader(strings.NewReader(test.In))
+
+		var cmd resp.Command
+		if err := r.Read(&cmd); err != nil {
+			t.Fatalf("got error %v", err)
+		}
+		if got := r.Preamble(); got != test.Expected {
+			t.Errorf("got preamble %t for %q, expected %t", got, test.In, test.Expected)
+		}
+	}
+}
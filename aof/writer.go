@@ -0,0 +1,56 @@
+package aof
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/nussjustin/resp"
+)
+
+// Writer writes commands in AOF format.
+//
+// Writes are buffered, so Flush must be called after writing the last command.
+type Writer struct {
+	bw *bufio.Writer
+	rw *resp.Writer
+
+	buf []byte
+}
+
+// NewWriter returns a new Writer writing to w.
+func NewWriter(w io.Writer) *Writer {
+	bw := bufio.NewWriter(w)
+	return &Writer{bw: bw, rw: resp.NewWriter(bw)}
+}
+
+// Flush writes any buffered data to the underlying io.Writer.
+func (w *Writer) Flush() error {
+	return w.bw.Flush()
+}
+
+// Write writes the command cmd.
+func (w *Writer) Write(cmd *resp.Command) error {
+	if _, err := w.rw.WriteArrayHeader(len(cmd.Args)); err != nil {
+		return err
+	}
+	for _, arg := range cmd.Args {
+		if _, err := w.rw.WriteBulkStringBytes(arg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteTimestamp writes a timestamp annotation for the time t, which Redis uses to record the time at which the
+// following commands were executed.
+//
+// The time is written with a precision of seconds.
+func (w *Writer) WriteTimestamp(t time.Time) error {
+	w.buf = append(w.buf[:0], "#TS:"...)
+	w.buf = strconv.AppendInt(w.buf, t.Unix(), 10)
+	w.buf = append(w.buf, '\r', '\n')
+	_, err := w.bw.Write(w.buf)
+	return err
+}
@@ -0,0 +1,76 @@
+package aof_test
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nussjustin/resp"
+	"github.com/nussjustin/resp/aof"
+)
+
+func TestWriter(t *testing.T) {
+	var buf bytes.Buffer
+
+	w := aof.NewWriter(&buf)
+	if err := w.WriteTimestamp(time.Unix(1628217470, 0)); err != nil {
+		t.Fatalf("got error %v", err)
+	}
+	for _, args := range [][][]byte{
+		{[]byte("SELECT"), []byte("0")},
+		{[]byte("SET"), []byte("a"), []byte("1")},
+	} {
+		if err := w.Write(&resp.Command{Args: args}); err != nil {
+			t.Fatalf("got error %v", err)
+		}
+	}
+
+	if buf.Len() != 0 {
+		t.Errorf("got %q before flush, expected no output", buf.String())
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("got error %v", err)
+	}
+
+	const expected = "#TS:1628217470\r\n" + selectCmd + setCmd
+	if got := buf.String(); got != expected {
+		t.Errorf("got %q, expected %q", got, expected)
+	}
+}
+
+func TestWriterFilter(t *testing.T) {
+	r := aof.NewReader(strings.NewReader(selectCmd + multiCmd + incrCmd + execCmd + setCmd + incrCmd))
+
+	var buf bytes.Buffer
+	w := aof.NewWriter(&buf)
+
+	var cmd resp.Command
+	for {
+		err := r.Read(&cmd)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("got error %v", err)
+		}
+		if cmd.Is("SET") {
+			continue
+		}
+		if err := w.Write(&cmd); err != nil {
+			t.Fatalf("got error %v", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("got error %v", err)
+	}
+
+	got, err := readAll(t, aof.NewReader(&buf))
+	if err != io.EOF {
+		t.Fatalf("got error %v", err)
+	}
+	if expected := []string{"SELECT 0", "MULTI", "INCR a", "EXEC", "INCR a"}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("got %q, expected %q", got, expected)
+	}
+}
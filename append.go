@@ -0,0 +1,283 @@
+package resp
+
+import (
+	"math"
+	"strconv"
+)
+
+func appendNumber(dst []byte, prefix byte, n int64) []byte {
+	dst = append(dst, prefix)
+	dst = strconv.AppendInt(dst, n, 10)
+	return append(dst, '\r', '\n')
+}
+
+func appendLine(dst []byte, prefix byte, s string) []byte {
+	dst = append(dst, prefix)
+	dst = append(dst, s...)
+	return append(dst, '\r', '\n')
+}
+
+func appendLineBytes(dst []byte, prefix byte, s []byte) []byte {
+	dst = append(dst, prefix)
+	dst = append(dst, s...)
+	return append(dst, '\r', '\n')
+}
+
+func appendBlob(dst []byte, prefix byte, s string) []byte {
+	dst = appendNumber(dst, prefix, int64(len(s)))
+	dst = append(dst, s...)
+	return append(dst, '\r', '\n')
+}
+
+func appendBlobBytes(dst []byte, prefix byte, s []byte) []byte {
+	dst = appendNumber(dst, prefix, int64(len(s)))
+	dst = append(dst, s...)
+	return append(dst, '\r', '\n')
+}
+
+// AppendArrayHeader appends an array header for an array of length n to dst and returns the extended slice.
+//
+// If n is < -1, AppendArrayHeader panics with ErrInvalidArrayLength.
+func AppendArrayHeader(dst []byte, n int) []byte {
+	if n < -1 {
+		panic(ErrInvalidArrayLength)
+	}
+	return appendNumber(dst, '*', int64(n))
+}
+
+// AppendBulkStringHeader appends a bulk string header for a bulk string of length n to dst and returns the extended
+// slice.
+//
+// If n is < -1, AppendBulkStringHeader panics with ErrInvalidBulkStringLength.
+func AppendBulkStringHeader(dst []byte, n int) []byte {
+	if n < -1 {
+		panic(ErrInvalidBulkStringLength)
+	}
+	return appendNumber(dst, '$', int64(n))
+}
+
+// AppendBulkString appends the string s as bulk string to dst and returns the extended slice.
+//
+// If you need to append a nil bulk string, use AppendBulkStringBytes instead.
+func AppendBulkString(dst []byte, s string) []byte {
+	return appendBlob(dst, '$', s)
+}
+
+// AppendBulkStringBytes appends the byte slice s as bulk string to dst and returns the extended slice.
+//
+// If s is nil, a nil bulk string is appended.
+func AppendBulkStringBytes(dst []byte, s []byte) []byte {
+	if s == nil {
+		return AppendBulkStringHeader(dst, -1)
+	}
+	return appendBlobBytes(dst, '$', s)
+}
+
+// AppendError appends the string s unvalidated as a simple error to dst and returns the extended slice.
+func AppendError(dst []byte, s string) []byte {
+	return appendLine(dst, '-', s)
+}
+
+// AppendErrorBytes appends the byte slice s unvalidated as a simple error to dst and returns the extended slice.
+func AppendErrorBytes(dst []byte, s []byte) []byte {
+	return appendLineBytes(dst, '-', s)
+}
+
+// AppendInteger appends the integer i as the native RESP integer type to dst and returns the extended slice.
+func AppendInteger(dst []byte, i int) []byte {
+	return appendNumber(dst, ':', int64(i))
+}
+
+// AppendSimpleString appends the string s unvalidated as a simple string to dst and returns the extended slice.
+func AppendSimpleString(dst []byte, s string) []byte {
+	return appendLine(dst, '+', s)
+}
+
+// AppendSimpleStringBytes appends the byte slice s unvalidated as a simple string to dst and returns the extended
+// slice.
+func AppendSimpleStringBytes(dst []byte, s []byte) []byte {
+	return appendLineBytes(dst, '+', s)
+}
+
+// AppendAttributeHeader appends a RESP3 attribute header for an attribute map with n key-value pairs to dst and
+// returns the extended slice.
+//
+// If n is < 0, AppendAttributeHeader panics with ErrInvalidAttributeLength.
+func AppendAttributeHeader(dst []byte, n int) []byte {
+	if n < 0 {
+		panic(ErrInvalidAttributeLength)
+	}
+	return appendNumber(dst, '|', int64(n))
+}
+
+// AppendBigNumber appends the string s unvalidated as a RESP3 big number to dst and returns the extended slice.
+func AppendBigNumber(dst []byte, s string) []byte {
+	return appendLine(dst, '(', s)
+}
+
+// AppendBlobError appends the string s as a RESP3 blob error to dst and returns the extended slice.
+func AppendBlobError(dst []byte, s string) []byte {
+	return appendBlob(dst, '!', s)
+}
+
+// AppendBlobErrorBytes appends the byte slice s as a RESP3 blob error to dst and returns the extended slice.
+func AppendBlobErrorBytes(dst []byte, s []byte) []byte {
+	return appendBlobBytes(dst, '!', s)
+}
+
+// AppendBoolean appends b as a RESP3 boolean to dst and returns the extended slice.
+func AppendBoolean(dst []byte, b bool) []byte {
+	if b {
+		return append(dst, "#t\r\n"...)
+	}
+	return append(dst, "#f\r\n"...)
+}
+
+// AppendDouble appends f as a RESP3 double to dst and returns the extended slice.
+//
+// Infinite values are appended as inf and -inf and NaN is appended as nan.
+func AppendDouble(dst []byte, f float64) []byte {
+	return appendDouble(dst, f, 64)
+}
+
+// appendDouble appends f as RESP3 double, formatted as if it had bitSize bits.
+func appendDouble(dst []byte, f float64, bitSize int) []byte {
+	dst = append(dst, ',')
+	switch {
+	case math.IsInf(f, 1):
+		dst = append(dst, "inf"...)
+	case math.IsInf(f, -1):
+		dst = append(dst, "-inf"...)
+	case math.IsNaN(f):
+		dst = append(dst, "nan"...)
+	default:
+		dst = strconv.AppendFloat(dst, f, 'g', -1, bitSize)
+	}
+	return append(dst, '\r', '\n')
+}
+
+// AppendMapHeader appends a RESP3 map header for a map with n key-value pairs to dst and returns the extended slice.
+//
+// If n is < 0, AppendMapHeader panics with ErrInvalidMapLength.
+func AppendMapHeader(dst []byte, n int) []byte {
+	if n < 0 {
+		panic(ErrInvalidMapLength)
+	}
+	return appendNumber(dst, '%', int64(n))
+}
+
+// AppendNull appends a RESP3 null to dst and returns the extended slice.
+func AppendNull(dst []byte) []byte {
+	return append(dst, "_\r\n"...)
+}
+
+// AppendPushHeader appends a RESP3 push header for a push message with n elements to dst and returns the extended
+// slice.
+//
+// If n is < 0, AppendPushHeader panics with ErrInvalidPushLength.
+func AppendPushHeader(dst []byte, n int) []byte {
+	if n < 0 {
+		panic(ErrInvalidPushLength)
+	}
+	return appendNumber(dst, '>', int64(n))
+}
+
+// AppendSetHeader appends a RESP3 set header for a set with n elements to dst and returns the extended slice.
+//
+// If n is < 0, AppendSetHeader panics with ErrInvalidSetLength.
+func AppendSetHeader(dst []byte, n int) []byte {
+	if n < 0 {
+		panic(ErrInvalidSetLength)
+	}
+	return appendNumber(dst, '~', int64(n))
+}
+
+// AppendVerbatimString appends the string s as a RESP3 verbatim string using the given 3 character format (e.g. txt)
+// to dst and returns the extended slice.
+//
+// The format is not validated.
+func AppendVerbatimString(dst []byte, format string, s string) []byte {
+	dst = appendNumber(dst, '=', int64(len(format)+1+len(s)))
+	dst = append(dst, format...)
+	dst = append(dst, ':')
+	dst = append(dst, s...)
+	return append(dst, '\r', '\n')
+}
+
+// AppendValue appends the value v, including all nested values, to dst and returns the extended slice.
+//
+// If the type of the value or of a nested value is unknown, ErrUnexpectedType is returned together with dst
+// unchanged. If a map or attribute has an odd number of elements, ErrInvalidMapLength or ErrInvalidAttributeLength is
+// returned together with dst unchanged.
+func AppendValue(dst []byte, v Value) ([]byte, error) {
+	b, err := appendValue(dst, v)
+	if err != nil {
+		return dst, err
+	}
+	return b, nil
+}
+
+func appendValue(dst []byte, v Value) ([]byte, error) {
+	switch v.Type {
+	case TypeArray, TypeSet, TypePush, TypeMap, TypeAttribute:
+		if v.Null && v.Type == TypeArray {
+			return AppendArrayHeader(dst, -1), nil
+		}
+		if v.Null {
+			return AppendNull(dst), nil
+		}
+		switch v.Type {
+		case TypeArray:
+			dst = AppendArrayHeader(dst, len(v.Elems))
+		case TypeSet:
+			dst = AppendSetHeader(dst, len(v.Elems))
+		case TypePush:
+			dst = AppendPushHeader(dst, len(v.Elems))
+		case TypeMap:
+			if len(v.Elems)%2 != 0 {
+				return dst, ErrInvalidMapLength
+			}
+			dst = AppendMapHeader(dst, len(v.Elems)/2)
+		case TypeAttribute:
+			if len(v.Elems)%2 != 0 {
+				return dst, ErrInvalidAttributeLength
+			}
+			dst = AppendAttributeHeader(dst, len(v.Elems)/2)
+		}
+		for _, e := range v.Elems {
+			var err error
+			if dst, err = appendValue(dst, e); err != nil {
+				return dst, err
+			}
+		}
+		return dst, nil
+	case TypeBulkString:
+		if v.Null {
+			return AppendBulkStringHeader(dst, -1), nil
+		}
+		return appendBlobBytes(dst, '$', v.Str), nil
+	case TypeBlobError:
+		return AppendBlobErrorBytes(dst, v.Str), nil
+	case TypeVerbatimString:
+		return appendBlobBytes(dst, '=', v.Str), nil
+	case TypeSimpleString:
+		return AppendSimpleStringBytes(dst, v.Str), nil
+	case TypeError:
+		return AppendErrorBytes(dst, v.Str), nil
+	case TypeBigNumber:
+		return appendLineBytes(dst, '(', v.Str), nil
+	case TypeInteger:
+		return AppendInteger(dst, v.Int), nil
+	case TypeDouble:
+		if v.Str != nil {
+			return appendLineBytes(dst, ',', v.Str), nil
+		}
+		return AppendDouble(dst, v.Float), nil
+	case TypeBoolean:
+		return AppendBoolean(dst, v.Bool), nil
+	case TypeNull:
+		return AppendNull(dst), nil
+	default:
+		return dst, ErrUnexpectedType
+	}
+}
@@ -0,0 +1,245 @@
+package resp_test
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/nussjustin/resp"
+)
+
+func TestAppend(t *testing.T) {
+	for _, test := range []struct {
+		Name     string
+		Append   func([]byte) []byte
+		Write    func(*resp.Writer) (int, error)
+		Expected string
+	}{
+		{
+			Name:     "ArrayHeader",
+			Append:   func(b []byte) []byte { return resp.AppendArrayHeader(b, 3) },
+			Write:    func(w *resp.Writer) (int, error) { return w.WriteArrayHeader(3) },
+			Expected: "*3\r\n",
+		},
+		{
+			Name:     "ArrayHeader/null",
+			Append:   func(b []byte) []byte { return resp.AppendArrayHeader(b, -1) },
+			Write:    func(w *resp.Writer) (int, error) { return w.WriteArrayHeader(-1) },
+			Expected: "*-1\r\n",
+		},
+		{
+			Name:     "BulkStringHeader",
+			Append:   func(b []byte) []byte { return resp.AppendBulkStringHeader(b, 5) },
+			Write:    func(w *resp.Writer) (int, error) { return w.WriteBulkStringHeader(5) },
+			Expected: "$5\r\n",
+		},
+		{
+			Name:     "BulkString",
+			Append:   func(b []byte) []byte { return resp.AppendBulkString(b, "hello") },
+			Write:    func(w *resp.Writer) (int, error) { return w.WriteBulkString("hello") },
+			Expected: "$5\r\nhello\r\n",
+		},
+		{
+			Name:     "BulkStringBytes",
+			Append:   func(b []byte) []byte { return resp.AppendBulkStringBytes(b, []byte("hello")) },
+			Write:    func(w *resp.Writer) (int, error) { return w.WriteBulkStringBytes([]byte("hello")) },
+			Expected: "$5\r\nhello\r\n",
+		},
+		{
+			Name:     "BulkStringBytes/null",
+			Append:   func(b []byte) []byte { return resp.AppendBulkStringBytes(b, nil) },
+			Write:    func(w *resp.Writer) (int, error) { return w.WriteBulkStringBytes(nil) },
+			Expected: "$-1\r\n",
+		},
+		{
+			Name:     "Error",
+			Append:   func(b []byte) []byte { return resp.AppendError(b, "ERR failed") },
+			Write:    func(w *resp.Writer) (int, error) { return w.WriteError("ERR failed") },
+			Expected: "-ERR failed\r\n",
+		},
+		{
+			Name:     "ErrorBytes",
+			Append:   func(b []byte) []byte { return resp.AppendErrorBytes(b, []byte("ERR failed")) },
+			Write:    func(w *resp.Writer) (int, error) { return w.WriteErrorBytes([]byte("ERR failed")) },
+			Expected: "-ERR failed\r\n",
+		},
+		{
+			Name:     "Integer",
+			Append:   func(b []byte) []byte { return resp.AppendInteger(b, -100) },
+			Write:    func(w *resp.Writer) (int, error) { return w.WriteInteger(-100) },
+			Expected: ":-100\r\n",
+		},
+		{
+			Name:     "SimpleString",
+			Append:   func(b []byte) []byte { return resp.AppendSimpleString(b, "OK") },
+			Write:    func(w *resp.Writer) (int, error) { return w.WriteSimpleString("OK") },
+			Expected: "+OK\r\n",
+		},
+		{
+			Name:     "SimpleStringBytes",
+			Append:   func(b []byte) []byte { return resp.AppendSimpleStringBytes(b, []byte("OK")) },
+			Write:    func(w *resp.Writer) (int, error) { return w.WriteSimpleStringBytes([]byte("OK")) },
+			Expected: "+OK\r\n",
+		},
+		{
+			Name:     "AttributeHeader",
+			Append:   func(b []byte) []byte { return resp.AppendAttributeHeader(b, 1) },
+			Write:    func(w *resp.Writer) (int, error) { return w.WriteAttributeHeader(1) },
+			Expected: "|1\r\n",
+		},
+		{
+			Name:     "BigNumber",
+			Append:   func(b []byte) []byte { return resp.AppendBigNumber(b, "12345678901234567890") },
+			Write:    func(w *resp.Writer) (int, error) { return w.WriteBigNumber("12345678901234567890") },
+			Expected: "(12345678901234567890\r\n",
+		},
+		{
+			Name:     "BlobError",
+			Append:   func(b []byte) []byte { return resp.AppendBlobError(b, "ERR failed") },
+			Write:    func(w *resp.Writer) (int, error) { return w.WriteBlobError("ERR failed") },
+			Expected: "!10\r\nERR failed\r\n",
+		},
+		{
+			Name:     "BlobErrorBytes",
+			Append:   func(b []byte) []byte { return resp.AppendBlobErrorBytes(b, []byte("ERR failed")) },
+			Write:    func(w *resp.Writer) (int, error) { return w.WriteBlobErrorBytes([]byte("ERR failed")) },
+			Expected: "!10\r\nERR failed\r\n",
+		},
+		{
+			Name:     "Boolean",
+			Append:   func(b []byte) []byte { return resp.AppendBoolean(b, true) },
+			Write:    func(w *resp.Writer) (int, error) { return w.WriteBoolean(true) },
+			Expected: "#t\r\n",
+		},
+		{
+			Name:     "Double",
+			Append:   func(b []byte) []byte { return resp.AppendDouble(b, 1.5) },
+			Write:    func(w *resp.Writer) (int, error) { return w.WriteDouble(1.5) },
+			Expected: ",1.5\r\n",
+		},
+		{
+			Name:     "Double/inf",
+			Append:   func(b []byte) []byte { return resp.AppendDouble(b, math.Inf(-1)) },
+			Write:    func(w *resp.Writer) (int, error) { return w.WriteDouble(math.Inf(-1)) },
+			Expected: ",-inf\r\n",
+		},
+		{
+			Name:     "MapHeader",
+			Append:   func(b []byte) []byte { return resp.AppendMapHeader(b, 2) },
+			Write:    func(w *resp.Writer) (int, error) { return w.WriteMapHeader(2) },
+			Expected: "%2\r\n",
+		},
+		{
+			Name:     "Null",
+			Append:   resp.AppendNull,
+			Write:    (*resp.Writer).WriteNull,
+			Expected: "_\r\n",
+		},
+		{
+			Name:     "PushHeader",
+			Append:   func(b []byte) []byte { return resp.AppendPushHeader(b, 2) },
+			Write:    func(w *resp.Writer) (int, error) { return w.WritePushHeader(2) },
+			Expected: ">2\r\n",
+		},
+		{
+			Name:     "SetHeader",
+			Append:   func(b []byte) []byte { return resp.AppendSetHeader(b, 2) },
+			Write:    func(w *resp.Writer) (int, error) { return w.WriteSetHeader(2) },
+			Expected: "~2\r\n",
+		},
+		{
+			Name:     "VerbatimString",
+			Append:   func(b []byte) []byte { return resp.AppendVerbatimString(b, "txt", "hello") },
+			Write:    func(w *resp.Writer) (int, error) { return w.WriteVerbatimString("txt", "hello") },
+			Expected: "=9\r\ntxt:hello\r\n",
+		},
+	} {
+		test := test
+
+		t.Run(test.Name, func(t *testing.T) {
+			assertBytes(t, test.Append(nil), test.Expected)
+			assertBytes(t, test.Append([]byte("prefix")), "prefix"+test.Expected)
+
+			var buf bytes.Buffer
+			if _, err := test.Write(resp.NewWriter(&buf)); err != nil {
+				t.Fatalf("write failed: %s", err)
+			}
+			assertBytes(t, buf.Bytes(), test.Expected)
+		})
+	}
+}
+
+func TestAppendPanics(t *testing.T) {
+	for _, test := range []struct {
+		Name   string
+		Append func()
+		Err    error
+	}{
+		{"ArrayHeader", func() { resp.AppendArrayHeader(nil, -2) }, resp.ErrInvalidArrayLength},
+		{"AttributeHeader", func() { resp.AppendAttributeHeader(nil, -1) }, resp.ErrInvalidAttributeLength},
+		{"BulkStringHeader", func() { resp.AppendBulkStringHeader(nil, -2) }, resp.ErrInvalidBulkStringLength},
+		{"MapHeader", func() { resp.AppendMapHeader(nil, -1) }, resp.ErrInvalidMapLength},
+		{"PushHeader", func() { resp.AppendPushHeader(nil, -1) }, resp.ErrInvalidPushLength},
+		{"SetHeader", func() { resp.AppendSetHeader(nil, -1) }, resp.ErrInvalidSetLength},
+	} {
+		test := test
+
+		t.Run(test.Name, func(t *testing.T) {
+			defer func() {
+				if got := recover(); got != test.Err {
+					t.Errorf("got panic %v, expected %v", got, test.Err)
+				}
+			}()
+			test.Append()
+		})
+	}
+}
+
+func TestAppendValue(t *testing.T) {
+	v := resp.Array(
+		resp.BulkString("hello"),
+		resp.Map(resp.SimpleString("a"), resp.Integer(1)),
+		resp.NullBulkString(),
+	)
+
+	got, err := resp.AppendValue([]byte("prefix"), v)
+	if err != nil {
+		t.Fatalf("got error %v, expected no error", err)
+	}
+	assertBytes(t, got, "prefix*3\r\n$5\r\nhello\r\n%1\r\n+a\r\n:1\r\n$-1\r\n")
+
+	v.Elems = append(v.Elems, resp.Value{})
+
+	got, err = resp.AppendValue([]byte("prefix"), v)
+	if err != resp.ErrUnexpectedType {
+		t.Fatalf("got error %v, expected %v", err, resp.ErrUnexpectedType)
+	}
+	assertBytes(t, got, "prefix")
+
+	for _, test := range []struct {
+		Value resp.Value
+		Err   error
+	}{
+		{resp.Value{Type: resp.TypeMap, Elems: []resp.Value{resp.SimpleString("a")}}, resp.ErrInvalidMapLength},
+		{
+			resp.Array(resp.Value{Type: resp.TypeAttribute, Elems: []resp.Value{resp.SimpleString("a")}}),
+			resp.ErrInvalidAttributeLength,
+		},
+	} {
+		got, err := resp.AppendValue([]byte("prefix"), test.Value)
+		if err != test.Err {
+			t.Errorf("got error %v, expected %v", err, test.Err)
+		}
+		assertBytes(t, got, "prefix")
+	}
+}
+
+func BenchmarkAppendBulkString(b *testing.B) {
+	buf := make([]byte, 0, 64)
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		buf = resp.AppendBulkString(buf[:0], "hello world")
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/nussjustin/resp"
+)
+
+var typeNames = map[resp.Type]string{
+	resp.TypeArray:          "array",
+	resp.TypeBulkString:     "bulk string",
+	resp.TypeError:          "error",
+	resp.TypeInteger:        "integer",
+	resp.TypeSimpleString:   "simple string",
+	resp.TypeAttribute:      "attribute",
+	resp.TypeBigNumber:      "big number",
+	resp.TypeBlobError:      "blob error",
+	resp.TypeBoolean:        "boolean",
+	resp.TypeDouble:         "double",
+	resp.TypeMap:            "map",
+	resp.TypeNull:           "null",
+	resp.TypePush:           "push",
+	resp.TypeSet:            "set",
+	resp.TypeVerbatimString: "verbatim string",
+}
+
+// dumper writes a description of each value in a byte stream to out.
+type dumper struct {
+	out io.Writer
+
+	// max is the maximum number of bytes shown for each string. If max is 0, strings are shown completely.
+	max int
+
+	lines []dumpLine
+	open  []int
+}
+
+// dump decodes all values in data, writing one line for each value and each nested value, and returns the number of
+// framing errors.
+//
+// After an error, dump resynchronises at the next line that starts with a known type and continues from there.
+func (d *dumper) dump(data []byte) int {
+	var errs int
+
+	br := bytes.NewReader(data)
+	rr := resp.NewReader(br)
+
+	pos := func() int { return len(data) - br.Len() - rr.Buffered() }
+
+	for off := 0; off < len(data); {
+		err := d.value(rr, pos)
+		if err == nil {
+			off = pos()
+			continue
+		}
+
+		errs++
+
+		// the Reader does not distinguish incomplete values from invalid ones, so only on error parse the value again
+		if _, _, perr := resp.Parse(data[off:]); perr == resp.ErrIncomplete {
+			_, _ = fmt.Fprintf(d.out, "%8d  error: incomplete value, only %d bytes left\n", off, len(data)-off)
+		} else {
+			_, _ = fmt.Fprintf(d.out, "%8d  error: %s\n", off, err)
+		}
+
+		next := resync(data, off+1)
+		if next < len(data) {
+			_, _ = fmt.Fprintf(d.out, "%8d  resynchronised after skipping %d bytes\n", next, next-off)
+		}
+		off = next
+
+		br.Reset(data[off:])
+		rr.Reset(br)
+	}
+
+	return errs
+}
+
+// resync returns the position of the first line at or after from that starts with a known type, or len(data).
+func resync(data []byte, from int) int {
+	for i := from; i < len(data); i++ {
+		if data[i-1] == '\n' && typeNames[resp.Type(data[i])] != "" {
+			return i
+		}
+	}
+	return len(data)
+}
+
+// dumpLine is the description of a single value, which is written once the whole top-level value was read.
+type dumpLine struct {
+	off, size int
+	text      string
+}
+
+// value reads the next top-level value from rr and writes the lines for it and its nested values. The offset of rr
+// in the dumped data is reported by pos.
+//
+// Lines are only written once the value was read completely, since the size of an aggregate is only known at its end.
+func (d *dumper) value(rr *resp.Reader, pos func() int) error {
+	d.lines = d.lines[:0]
+	d.open = d.open[:0]
+
+	for {
+		start := pos()
+
+		ev, err := rr.Next()
+		if err != nil {
+			return err
+		}
+
+		switch ev.Kind {
+		case resp.EventEnd:
+			i := d.open[len(d.open)-1]
+			d.open = d.open[:len(d.open)-1]
+			d.lines[i].size = pos() - d.lines[i].off
+		case resp.EventStart:
+			d.open = append(d.open, len(d.lines))
+			d.lines = append(d.lines, dumpLine{off: start, text: d.describe(ev)})
+		default:
+			d.lines = append(d.lines, dumpLine{off: start, size: pos() - start, text: d.describe(ev)})
+		}
+
+		if len(d.open) == 0 && ev.Kind != resp.EventStart {
+			break
+		}
+	}
+
+	for i := range d.lines {
+		_, _ = fmt.Fprintf(d.out, "%8d  %s (%d bytes)\n", d.lines[i].off, d.lines[i].text, d.lines[i].size)
+	}
+
+	return nil
+}
+
+// describe returns the description of the value or aggregate started by ev, indented by its depth.
+func (d *dumper) describe(ev resp.Event) string {
+	var sb strings.Builder
+	sb.WriteString(strings.Repeat("  ", ev.Depth))
+	sb.WriteString(typeNames[ev.Type])
+
+	switch {
+	case ev.Null:
+		if ev.Type != resp.TypeNull {
+			sb.WriteString(" null")
+		}
+	case ev.Kind == resp.EventStart:
+		_, _ = fmt.Fprintf(&sb, " len=%d", ev.Len)
+	case ev.Kind == resp.EventString:
+		_, _ = fmt.Fprintf(&sb, " len=%d %s", ev.Len, d.quote(ev.Str))
+	case ev.Type == resp.TypeInteger:
+		_, _ = fmt.Fprintf(&sb, " %d", ev.Int)
+	case ev.Type == resp.TypeBoolean:
+		_, _ = fmt.Fprintf(&sb, " %t", ev.Bool)
+	case ev.Type == resp.TypeDouble:
+		_, _ = fmt.Fprintf(&sb, " %s", ev.Str)
+	default:
+		_, _ = fmt.Fprintf(&sb, " %s", d.quote(ev.Str))
+	}
+
+	return sb.String()
+}
+
+func (d *dumper) quote(s []byte) string {
+	if d.max > 0 && len(s) > d.max {
+		return strconv.Quote(string(s[:d.max])) + "..."
+	}
+	return strconv.Quote(string(s))
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDumper(t *testing.T) {
+	for _, test := range []struct {
+		Name     string
+		In       string
+		Max      int
+		Expected string
+		Errors   int
+	}{
+		{Name: "empty"},
+		{
+			Name: "values",
+			In:   "*2\r\n$3\r\nGET\r\n$3\r\nfoo\r\n+OK\r\n$-1\r\n_\r\n,1.5\r\n#t\r\n",
+			Expected: "" +
+				"       0  array len=2 (22 bytes)\n" +
+				"       4    bulk string len=3 \"GET\" (9 bytes)\n" +
+				"      13    bulk string len=3 \"foo\" (9 bytes)\n" +
+				"      22  simple string \"OK\" (5 bytes)\n" +
+				"      27  bulk string null (5 bytes)\n" +
+				"      32  null (3 bytes)\n" +
+				"      35  double 1.5 (6 bytes)\n" +
+				"      41  boolean true (4 bytes)\n",
+		},
+		{
+			Name: "nested",
+			In:   "%1\r\n+a\r\n*1\r\n:5\r\n",
+			Expected: "" +
+				"       0  map len=1 (16 bytes)\n" +
+				"       4    simple string \"a\" (4 bytes)\n" +
+				"       8    array len=1 (8 bytes)\n" +
+				"      12      integer 5 (4 bytes)\n",
+		},
+		{
+			Name:     "truncated strings",
+			In:       "$11\r\nhello world\r\n",
+			Max:      5,
+			Expected: "       0  bulk string len=11 \"hello\"... (18 bytes)\n",
+		},
+		{
+			Name: "resynchronise",
+			In:   "+OK\r\n:x\r\n$3\r\nabcdef\r\n:1\r\n",
+			Expected: "" +
+				"       0  simple string \"OK\" (5 bytes)\n" +
+				"       5  error: invalid integer\n" +
+				"       9  resynchronised after skipping 4 bytes\n" +
+				"       9  error: missing or invalid EOL\n" +
+				"      21  resynchronised after skipping 12 bytes\n" +
+				"      21  integer 1 (4 bytes)\n",
+			Errors: 2,
+		},
+		{
+			Name: "incomplete",
+			In:   "+OK\r\n$5\r\nab",
+			Expected: "" +
+				"       0  simple string \"OK\" (5 bytes)\n" +
+				"       5  error: incomplete value, only 6 bytes left\n",
+			Errors: 1,
+		},
+	} {
+		test := test
+
+		t.Run(test.Name, func(t *testing.T) {
+			var out strings.Builder
+			d := &dumper{out: &out, max: test.Max}
+
+			if errs := d.dump([]byte(test.In)); errs != test.Errors {
+				t.Errorf("got %d errors, expected %d", errs, test.Errors)
+			}
+			if got := out.String(); got != test.Expected {
+				t.Errorf("got\n%s\nexpected\n%s", got, test.Expected)
+			}
+		})
+	}
+}
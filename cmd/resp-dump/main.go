@@ -0,0 +1,96 @@
+// Command resp-dump decodes a stream of RESP values and prints each value with its offset, type and length.
+//
+// The stream is read from the files given as arguments or from stdin. Using -pcap the input is read as a capture in
+// pcap format, for example as written by tcpdump -w, and the TCP payload of each direction of each connection is
+// decoded separately:
+//
+//	tcpdump -i lo -w redis.pcap port 6379
+//	resp-dump -pcap redis.pcap
+//
+// Each value is printed on its own line, starting with the offset of the value in the stream. Nested values are
+// indented below their parent. Framing errors are reported at the offset of the value that could not be decoded,
+// after which decoding continues at the next line that starts with a known type.
+//
+// The exit status is 1 if any framing errors were found.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+)
+
+func main() {
+	pcap := flag.Bool("pcap", false, "read input in pcap format and decode the payload of each TCP stream")
+	max := flag.Int("max", 64, "maximum number of bytes shown for each string (0 for no limit)")
+	flag.Parse()
+
+	d := &dumper{out: os.Stdout, max: *max}
+
+	files := flag.Args()
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+
+	var errs int
+	for _, name := range files {
+		n, err := dumpFile(d, name, *pcap)
+		if err != nil {
+			log.Fatalf("failed to read %s: %s", name, err)
+		}
+		errs += n
+	}
+
+	if errs > 0 {
+		os.Exit(1)
+	}
+}
+
+// dumpFile decodes the contents of the named file, or of stdin if name is "-", and returns the number of framing
+// errors.
+func dumpFile(d *dumper, name string, pcap bool) (int, error) {
+	var r io.Reader = os.Stdin
+	if name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return 0, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	if !pcap {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return 0, err
+		}
+		return d.dump(data), nil
+	}
+
+	streams, err := readPcap(r)
+	if err != nil {
+		return 0, err
+	}
+	return d.dumpStreams(streams), nil
+}
+
+// dumpStreams decodes each stream, preceded by a line naming the stream, and returns the number of framing errors.
+func (d *dumper) dumpStreams(streams []*tcpStream) int {
+	var errs, n int
+	for _, s := range streams {
+		if len(s.data) == 0 {
+			continue
+		}
+		if n++; n > 1 {
+			_, _ = fmt.Fprintln(d.out)
+		}
+		_, _ = fmt.Fprintf(d.out, "stream %s -> %s (%d bytes)\n", s.src, s.dst, len(s.data))
+		if s.gaps > 0 {
+			_, _ = fmt.Fprintf(d.out, "warning: %d gaps in the capture, values may be missing\n", s.gaps)
+		}
+		errs += d.dump(s.data)
+	}
+	return errs
+}
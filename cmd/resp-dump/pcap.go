@@ -0,0 +1,187 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+)
+
+// errInvalidPcap is returned by readPcap for input that is not a pcap file.
+var errInvalidPcap = errors.New("invalid pcap file")
+
+// tcpStream is the payload sent in one direction of a TCP connection.
+type tcpStream struct {
+	// src and dst are the addresses of the sender and receiver, including the port.
+	src, dst string
+
+	data []byte
+
+	// gaps is the number of times data was missing from the capture.
+	gaps int
+
+	next    uint32
+	started bool
+}
+
+// add adds the payload of a segment with the given sequence number, skipping retransmitted data.
+func (s *tcpStream) add(seq uint32, syn bool, payload []byte) {
+	if syn {
+		s.next, s.started = seq+1, true
+		return
+	}
+	if !s.started {
+		s.next, s.started = seq, true
+	}
+	if len(payload) == 0 {
+		return
+	}
+
+	switch diff := int32(s.next - seq); {
+	case diff < 0:
+		s.gaps++
+	case int(diff) >= len(payload):
+		return
+	default:
+		payload = payload[diff:]
+		seq = s.next
+	}
+
+	s.data = append(s.data, payload...)
+	s.next = seq + uint32(len(payload))
+}
+
+// readPcap reads a capture in pcap format and returns the reassembled TCP payloads, in the order in which each
+// stream was first seen.
+//
+// Supported link types are Ethernet, Linux cooked capture, BSD loopback and raw IP. Packets that are not TCP over
+// IPv4 or IPv6 are ignored.
+func readPcap(r io.Reader) ([]*tcpStream, error) {
+	var hdr [24]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return nil, errInvalidPcap
+	}
+
+	var order binary.ByteOrder
+	switch binary.LittleEndian.Uint32(hdr[:4]) {
+	case 0xa1b2c3d4, 0xa1b23c4d:
+		order = binary.LittleEndian
+	case 0xd4c3b2a1, 0x4d3cb2a1:
+		order = binary.BigEndian
+	default:
+		return nil, errInvalidPcap
+	}
+
+	linkType := order.Uint32(hdr[20:24]) & 0xffff
+
+	var streams []*tcpStream
+	byKey := make(map[string]*tcpStream)
+
+	for {
+		var rec [16]byte
+		if _, err := io.ReadFull(r, rec[:]); err == io.EOF {
+			return streams, nil
+		} else if err != nil {
+			return streams, fmt.Errorf("reading packet header: %w", err)
+		}
+
+		pkt := make([]byte, order.Uint32(rec[8:12]))
+		if _, err := io.ReadFull(r, pkt); err != nil {
+			return streams, fmt.Errorf("reading packet: %w", err)
+		}
+
+		ip, err := linkPayload(linkType, pkt)
+		if err != nil {
+			return streams, err
+		}
+
+		src, dst, tcp := ipPayload(ip)
+		if len(tcp) < 20 {
+			continue
+		}
+
+		off := int(tcp[12]>>4) * 4
+		if off < 20 || off > len(tcp) {
+			continue
+		}
+
+		srcAddr := net.JoinHostPort(src.String(), strconv.Itoa(int(binary.BigEndian.Uint16(tcp[0:2]))))
+		dstAddr := net.JoinHostPort(dst.String(), strconv.Itoa(int(binary.BigEndian.Uint16(tcp[2:4]))))
+
+		key := srcAddr + " " + dstAddr
+		s := byKey[key]
+		if s == nil {
+			s = &tcpStream{src: srcAddr, dst: dstAddr}
+			byKey[key] = s
+			streams = append(streams, s)
+		}
+
+		s.add(binary.BigEndian.Uint32(tcp[4:8]), tcp[13]&0x02 != 0, tcp[off:])
+	}
+}
+
+// linkPayload returns the IP packet contained in the link layer frame pkt.
+func linkPayload(linkType uint32, pkt []byte) ([]byte, error) {
+	switch linkType {
+	case 0: // BSD loopback
+		if len(pkt) < 4 {
+			return nil, nil
+		}
+		return pkt[4:], nil
+	case 1: // Ethernet
+		if len(pkt) < 14 {
+			return nil, nil
+		}
+		etherType, pkt := binary.BigEndian.Uint16(pkt[12:14]), pkt[14:]
+		for etherType == 0x8100 && len(pkt) >= 4 {
+			etherType, pkt = binary.BigEndian.Uint16(pkt[2:4]), pkt[4:]
+		}
+		return pkt, nil
+	case 12, 101: // raw IP
+		return pkt, nil
+	case 113: // Linux cooked capture
+		if len(pkt) < 16 {
+			return nil, nil
+		}
+		return pkt[16:], nil
+	default:
+		return nil, fmt.Errorf("unsupported pcap link type %d", linkType)
+	}
+}
+
+// ipPayload returns the addresses and the TCP segment of an IPv4 or IPv6 packet. If ip is not a TCP packet, the
+// returned segment is nil.
+func ipPayload(ip []byte) (src, dst net.IP, tcp []byte) {
+	if len(ip) == 0 {
+		return nil, nil, nil
+	}
+
+	switch ip[0] >> 4 {
+	case 4:
+		if len(ip) < 20 || ip[9] != 6 {
+			return nil, nil, nil
+		}
+		// ignore fragments
+		if binary.BigEndian.Uint16(ip[6:8])&0x3fff != 0 {
+			return nil, nil, nil
+		}
+		hlen, total := int(ip[0]&0x0f)*4, int(binary.BigEndian.Uint16(ip[2:4]))
+		if hlen < 20 || total < hlen || total > len(ip) {
+			return nil, nil, nil
+		}
+		return net.IP(ip[12:16]), net.IP(ip[16:20]), ip[hlen:total]
+	case 6:
+		if len(ip) < 40 || ip[6] != 6 {
+			return nil, nil, nil
+		}
+		total := 40 + int(binary.BigEndian.Uint16(ip[4:6]))
+		if total > len(ip) {
+			return nil, nil, nil
+		}
+		return net.IP(ip[8:24]), net.IP(ip[24:40]), ip[40:total]
+	default:
+		return nil, nil, nil
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+type testSegment struct {
+	src, dst         [4]byte
+	srcPort, dstPort uint16
+	seq              uint32
+	syn              bool
+	payload          string
+}
+
+// appendPcap appends an Ethernet frame containing seg as IPv4/TCP packet in pcap format.
+func appendPcap(dst []byte, seg testSegment) []byte {
+	tcp := make([]byte, 20, 20+len(seg.payload))
+	binary.BigEndian.PutUint16(tcp[0:2], seg.srcPort)
+	binary.BigEndian.PutUint16(tcp[2:4], seg.dstPort)
+	binary.BigEndian.PutUint32(tcp[4:8], seg.seq)
+	tcp[12] = 5 << 4
+	if seg.syn {
+		tcp[13] = 0x02
+	}
+	tcp = append(tcp, seg.payload...)
+
+	ip := make([]byte, 20, 20+len(tcp))
+	ip[0] = 0x45
+	binary.BigEndian.PutUint16(ip[2:4], uint16(20+len(tcp)))
+	ip[9] = 6
+	copy(ip[12:16], seg.src[:])
+	copy(ip[16:20], seg.dst[:])
+	ip = append(ip, tcp...)
+
+	eth := make([]byte, 14, 14+len(ip))
+	binary.BigEndian.PutUint16(eth[12:14], 0x0800)
+	eth = append(eth, ip...)
+
+	var rec [16]byte
+	binary.LittleEndian.PutUint32(rec[8:12], uint32(len(eth)))
+	binary.LittleEndian.PutUint32(rec[12:16], uint32(len(eth)))
+	return append(append(dst, rec[:]...), eth...)
+}
+
+func TestReadPcap(t *testing.T) {
+	var hdr [24]byte
+	binary.LittleEndian.PutUint32(hdr[0:4], 0xa1b2c3d4)
+	binary.LittleEndian.PutUint16(hdr[4:6], 2)
+	binary.LittleEndian.PutUint16(hdr[6:8], 4)
+	binary.LittleEndian.PutUint32(hdr[16:20], 65535)
+	binary.LittleEndian.PutUint32(hdr[20:24], 1)
+
+	client, server := [4]byte{127, 0, 0, 1}, [4]byte{127, 0, 0, 2}
+
+	b := hdr[:]
+	for _, seg := range []testSegment{
+		{src: client, dst: server, srcPort: 50000, dstPort: 6379, seq: 99, syn: true},
+		{src: server, dst: client, srcPort: 6379, dstPort: 50000, seq: 499, syn: true},
+		{src: client, dst: server, srcPort: 50000, dstPort: 6379, seq: 100, payload: "*2\r\n$3\r\nGET\r\n"},
+		// retransmission including new data
+		{src: client, dst: server, srcPort: 50000, dstPort: 6379, seq: 104, payload: "$3\r\nGET\r\n$3\r\nfoo\r\n"},
+		// retransmission
+		{src: client, dst: server, srcPort: 50000, dstPort: 6379, seq: 113, payload: "$3\r\nfoo\r\n"},
+		{src: server, dst: client, srcPort: 6379, dstPort: 50000, seq: 500, payload: "$-1\r\n"},
+		// gap
+		{src: server, dst: client, srcPort: 6379, dstPort: 50000, seq: 510, payload: "+OK\r\n"},
+	} {
+		b = appendPcap(b, seg)
+	}
+
+	streams, err := readPcap(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("got error %v", err)
+	}
+
+	var out strings.Builder
+	d := &dumper{out: &out}
+	if errs := d.dumpStreams(streams); errs != 0 {
+		t.Errorf("got %d errors, expected none", errs)
+	}
+
+	const expected = "" +
+		"stream 127.0.0.1:50000 -> 127.0.0.2:6379 (22 bytes)\n" +
+		"       0  array len=2 (22 bytes)\n" +
+		"       4    bulk string len=3 \"GET\" (9 bytes)\n" +
+		"      13    bulk string len=3 \"foo\" (9 bytes)\n" +
+		"\n" +
+		"stream 127.0.0.2:6379 -> 127.0.0.1:50000 (10 bytes)\n" +
+		"warning: 1 gaps in the capture, values may be missing\n" +
+		"       0  bulk string null (5 bytes)\n" +
+		"       5  simple string \"OK\" (5 bytes)\n"
+
+	if got := out.String(); got != expected {
+		t.Errorf("got\n%s\nexpected\n%s", got, expected)
+	}
+}
+
+func TestReadPcapInvalid(t *testing.T) {
+	if _, err := readPcap(strings.NewReader("*1\r\n$4\r\nPING\r\n")); err != errInvalidPcap {
+		t.Errorf("got error %v, expected %v", err, errInvalidPcap)
+	}
+}
@@ -0,0 +1,122 @@
+// Command resp-record is a proxy that records all RESP traffic between clients and a server.
+//
+// Clients connect to the address given by -listen and each connection is forwarded to the server given by -target.
+// All requests and replies are written with timestamps to the file given by -o, from which they can be replayed
+// against another server using resp-replay:
+//
+//	resp-record -listen 127.0.0.1:6380 -target 127.0.0.1:6379 -o traffic.resp
+//
+// Addresses starting with a slash are used as unix socket paths.
+//
+// Values are forwarded as they are read, so the proxy supports pipelining, pub/sub and RESP3. Inline commands are
+// not supported and cause the connection to be closed.
+package main
+
+import (
+	"errors"
+	"flag"
+	"io"
+	"log"
+	"net"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/nussjustin/resp"
+	"github.com/nussjustin/resp/internal/record"
+)
+
+func network(addr string) string {
+	if strings.HasPrefix(addr, "/") {
+		return "unix"
+	}
+	return "tcp"
+}
+
+func main() {
+	listen := flag.String("listen", "127.0.0.1:6380", "address to listen on for client connections")
+	target := flag.String("target", "127.0.0.1:6379", "address of the server")
+	out := flag.String("o", "traffic.resp", "file to write the recording to")
+	flag.Parse()
+
+	f, err := os.Create(*out)
+	if err != nil {
+		log.Fatalf("failed to create recording: %s", err)
+	}
+	defer f.Close()
+
+	l, err := net.Listen(network(*listen), *listen)
+	if err != nil {
+		log.Fatalf("failed to listen: %s", err)
+	}
+
+	rc := &recorder{target: *target, rec: record.NewWriter(f), now: time.Now}
+
+	log.Printf("recording traffic from %s to %s into %s", l.Addr(), *target, *out)
+	log.Fatal(rc.serve(l))
+}
+
+// recorder accepts client connections, forwards them to the target and records all values sent on them.
+type recorder struct {
+	target string
+	rec    *record.Writer
+	now    func() time.Time
+	lastID int64
+}
+
+func (rc *recorder) serve(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go rc.handle(conn)
+	}
+}
+
+func (rc *recorder) handle(client net.Conn) {
+	defer client.Close()
+
+	id := int(atomic.AddInt64(&rc.lastID, 1))
+
+	server, err := net.Dial(network(rc.target), rc.target)
+	if err != nil {
+		log.Printf("conn %d: failed to connect to %s: %s", id, rc.target, err)
+		return
+	}
+	defer server.Close()
+
+	errc := make(chan error, 2)
+	go func() { errc <- rc.forward(id, record.Request, server, client) }()
+	go func() { errc <- rc.forward(id, record.Reply, client, server) }()
+
+	// the first error ends the connection, closing both sides to unblock the other direction
+	if err := <-errc; err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+		log.Printf("conn %d: %s", id, err)
+	}
+	_ = client.Close()
+	_ = server.Close()
+	<-errc
+}
+
+// forward reads values from src, records them and writes them to dst until an error occurs.
+func (rc *recorder) forward(id int, dir record.Direction, dst io.Writer, src io.Reader) error {
+	r := resp.NewReader(src)
+	w := resp.NewWriter(dst)
+
+	for {
+		v, err := r.ReadValue()
+		if err != nil {
+			return err
+		}
+
+		if err := rc.rec.Write(record.Record{Time: rc.now(), Conn: id, Dir: dir, Value: v}); err != nil {
+			return err
+		}
+
+		if _, err := w.WriteValue(v); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nussjustin/resp"
+	"github.com/nussjustin/resp/internal/record"
+	"github.com/nussjustin/resp/resptest"
+)
+
+// syncBuffer is a bytes.Buffer that can be written to from multiple goroutines.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (sb *syncBuffer) Write(b []byte) (int, error) {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+	return sb.buf.Write(b)
+}
+
+func (sb *syncBuffer) Bytes() []byte {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+	return append([]byte(nil), sb.buf.Bytes()...)
+}
+
+func TestRecorder(t *testing.T) {
+	srv := resptest.NewServer()
+	defer srv.Close()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %s", err)
+	}
+	defer l.Close()
+
+	var buf syncBuffer
+
+	now := time.Unix(1700000000, 0)
+	rc := &recorder{target: srv.Addr, rec: record.NewWriter(&buf), now: func() time.Time { return now }}
+	go func() { _ = rc.serve(l) }()
+
+	ctx := context.Background()
+
+	conn, err := resp.Dial(ctx, "tcp", l.Addr().String())
+	if err != nil {
+		t.Fatalf("failed to connect: %s", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Do(ctx, "SET", "key", "value"); err != nil {
+		t.Fatalf("failed to send SET: %s", err)
+	}
+	if v, err := conn.Do(ctx, "GET", "key"); err != nil || string(v.Str) != "value" {
+		t.Fatalf("got %#v (error %v) from GET, expected value", v, err)
+	}
+
+	expected := []record.Record{
+		{
+			Conn:  1,
+			Dir:   record.Request,
+			Value: resp.Array(resp.BulkString("SET"), resp.BulkString("key"), resp.BulkString("value")),
+		},
+		{Conn: 1, Dir: record.Reply, Value: resp.SimpleString("OK")},
+		{Conn: 1, Dir: record.Request, Value: resp.Array(resp.BulkString("GET"), resp.BulkString("key"))},
+		{Conn: 1, Dir: record.Reply, Value: resp.BulkString("value")},
+	}
+
+	r := record.NewReader(bytes.NewReader(buf.Bytes()))
+	for i, e := range expected {
+		got, err := r.Read()
+		if err != nil {
+			t.Fatalf("failed to read record %d: %s", i, err)
+		}
+		if !got.Time.Equal(now) {
+			t.Errorf("record %d: got time %s, expected %s", i, got.Time, now)
+		}
+
+		gotb, _ := resp.AppendValue(nil, got.Value)
+		eb, _ := resp.AppendValue(nil, e.Value)
+		if got.Conn != e.Conn || got.Dir != e.Dir || !bytes.Equal(gotb, eb) {
+			t.Errorf("record %d: got %d %s %q, expected %d %s %q", i, got.Conn, got.Dir, gotb, e.Conn, e.Dir, eb)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+// Command resp-replay replays requests recorded using resp-record against a server and reports all replies that
+// differ from the recorded replies.
+//
+//	resp-replay -addr 127.0.0.1:6379 -i traffic.resp
+//
+// Each recorded connection is replayed using its own connection to the server, in the order in which the values were
+// recorded. Addresses starting with a slash are used as unix socket paths.
+//
+// resp-replay exits with status 1 if any reply differs.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/nussjustin/resp"
+	"github.com/nussjustin/resp/internal/record"
+)
+
+func network(addr string) string {
+	if strings.HasPrefix(addr, "/") {
+		return "unix"
+	}
+	return "tcp"
+}
+
+func main() {
+	addr := flag.String("addr", "127.0.0.1:6379", "address of the server")
+	in := flag.String("i", "traffic.resp", "file to read the recording from")
+	timeout := flag.Duration("timeout", 5*time.Second, "maximum time to wait for each reply")
+	flag.Parse()
+
+	f, err := os.Open(*in)
+	if err != nil {
+		log.Fatalf("failed to open recording: %s", err)
+	}
+	defer f.Close()
+
+	rp := &replayer{addr: *addr, timeout: *timeout, out: os.Stdout}
+	defer rp.close()
+
+	if err := rp.replay(record.NewReader(bufio.NewReader(f))); err != nil {
+		log.Fatalf("failed to replay recording: %s", err)
+	}
+
+	log.Printf("replayed %d requests, %d of %d replies differ", rp.requests, rp.diffs, rp.replies)
+	if rp.diffs > 0 {
+		rp.close()
+		os.Exit(1)
+	}
+}
+
+// replayConn is the connection to the server used for a single recorded connection.
+type replayConn struct {
+	conn net.Conn
+	r    *resp.Reader
+	w    *resp.Writer
+
+	// last is the last request sent on the connection, used when reporting differences.
+	last resp.Value
+}
+
+// replayer replays recordings and writes differences to out.
+type replayer struct {
+	addr    string
+	timeout time.Duration
+	out     io.Writer
+
+	conns map[int]*replayConn
+
+	requests, replies, diffs int
+}
+
+func (rp *replayer) close() {
+	for id, c := range rp.conns {
+		_ = c.conn.Close()
+		delete(rp.conns, id)
+	}
+}
+
+func (rp *replayer) conn(id int) (*replayConn, error) {
+	if c := rp.conns[id]; c != nil {
+		return c, nil
+	}
+
+	conn, err := net.Dial(network(rp.addr), rp.addr)
+	if err != nil {
+		return nil, err
+	}
+
+	if rp.conns == nil {
+		rp.conns = make(map[int]*replayConn)
+	}
+
+	c := &replayConn{conn: conn, r: resp.NewReader(conn), w: resp.NewWriter(conn)}
+	rp.conns[id] = c
+	return c, nil
+}
+
+func (rp *replayer) replay(rr *record.Reader) error {
+	for {
+		rec, err := rr.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		c, err := rp.conn(rec.Conn)
+		if err != nil {
+			return err
+		}
+
+		switch rec.Dir {
+		case record.Request:
+			rp.requests++
+			c.last = rec.Value
+			if _, err := c.w.WriteValue(rec.Value); err != nil {
+				return fmt.Errorf("conn %d: %w", rec.Conn, err)
+			}
+		case record.Reply:
+			rp.replies++
+			if err := rp.compare(rec.Conn, c, rec.Value); err != nil {
+				return fmt.Errorf("conn %d: %w", rec.Conn, err)
+			}
+		}
+	}
+}
+
+// compare reads the next reply from c and reports a difference if it does not match expected.
+func (rp *replayer) compare(id int, c *replayConn, expected resp.Value) error {
+	if err := c.conn.SetReadDeadline(time.Now().Add(rp.timeout)); err != nil {
+		return err
+	}
+
+	got, err := c.r.ReadValue()
+
+	var ne net.Error
+	if errors.As(err, &ne) && ne.Timeout() && c.r.Err() == nil {
+		rp.diff(id, c.last, nil, expected)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	gotb, _ := resp.AppendValue(nil, got)
+	if expectedb, _ := resp.AppendValue(nil, expected); !bytes.Equal(gotb, expectedb) {
+		rp.diff(id, c.last, gotb, expected)
+	}
+	return nil
+}
+
+func (rp *replayer) diff(id int, req resp.Value, got []byte, expected resp.Value) {
+	rp.diffs++
+
+	// req is the zero Value if no request was sent on the connection before the reply
+	reqs := "no request"
+	if reqb, err := resp.AppendValue(nil, req); err == nil {
+		reqs = fmt.Sprintf("%q", reqb)
+	}
+
+	expectedb, _ := resp.AppendValue(nil, expected)
+
+	if got == nil {
+		_, _ = fmt.Fprintf(rp.out, "conn %d: %s: got no reply, expected %q\n", id, reqs, expectedb)
+		return
+	}
+	_, _ = fmt.Fprintf(rp.out, "conn %d: %s: got %q, expected %q\n", id, reqs, got, expectedb)
+}
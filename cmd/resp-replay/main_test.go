@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nussjustin/resp"
+	"github.com/nussjustin/resp/internal/record"
+	"github.com/nussjustin/resp/resptest"
+)
+
+func cmd(args ...string) resp.Value {
+	v := resp.Array()
+	for _, arg := range args {
+		v.Elems = append(v.Elems, resp.BulkString(arg))
+	}
+	return v
+}
+
+func TestReplayer(t *testing.T) {
+	srv := resptest.NewServer()
+	defer srv.Close()
+
+	for _, test := range []struct {
+		Name    string
+		Records []record.Record
+		Diffs   int
+		Out     string
+	}{
+		{
+			Name: "same",
+			Records: []record.Record{
+				{Conn: 1, Dir: record.Request, Value: cmd("SET", "key", "value")},
+				{Conn: 2, Dir: record.Request, Value: cmd("PING")},
+				{Conn: 1, Dir: record.Reply, Value: resp.SimpleString("OK")},
+				{Conn: 2, Dir: record.Reply, Value: resp.SimpleString("PONG")},
+				{Conn: 1, Dir: record.Request, Value: cmd("GET", "key")},
+				{Conn: 1, Dir: record.Reply, Value: resp.BulkString("value")},
+			},
+		},
+		{
+			Name: "different",
+			Records: []record.Record{
+				{Conn: 1, Dir: record.Request, Value: cmd("GET", "missing")},
+				{Conn: 1, Dir: record.Reply, Value: resp.BulkString("value")},
+			},
+			Diffs: 1,
+			Out:   `conn 1: "*2\r\n$3\r\nGET\r\n$7\r\nmissing\r\n": got "$-1\r\n", expected "$5\r\nvalue\r\n"` + "\n",
+		},
+		{
+			Name: "no reply",
+			Records: []record.Record{
+				{Conn: 1, Dir: record.Reply, Value: resp.SimpleString("OK")},
+			},
+			Diffs: 1,
+			Out:   `conn 1: no request: got no reply, expected "+OK\r\n"` + "\n",
+		},
+	} {
+		test := test
+
+		t.Run(test.Name, func(t *testing.T) {
+			srv.FlushAll()
+
+			var buf bytes.Buffer
+			w := record.NewWriter(&buf)
+			for _, rec := range test.Records {
+				if err := w.Write(rec); err != nil {
+					t.Fatalf("failed to write record: %s", err)
+				}
+			}
+
+			var out strings.Builder
+			rp := &replayer{addr: srv.Addr, timeout: 50 * time.Millisecond, out: &out}
+			defer rp.close()
+
+			if err := rp.replay(record.NewReader(&buf)); err != nil {
+				t.Fatalf("failed to replay: %s", err)
+			}
+			if rp.diffs != test.Diffs {
+				t.Errorf("got %d differences, expected %d", rp.diffs, test.Diffs)
+			}
+			if out.String() != test.Out {
+				t.Errorf("got output %q, expected %q", out.String(), test.Out)
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+// Command resp is a command-line client for servers speaking RESP, similar to redis-cli.
+//
+// Commands can be given as arguments, in which case a single command is sent and the reply is printed:
+//
+//	resp -h 127.0.0.1 -p 6379 SET key value
+//
+// Without arguments, commands are read line by line from stdin until the end of the input or until the command quit
+// or exit is entered. Lines are split into arguments using the same quoting rules as redis-cli.
+//
+// Replies are printed in the same format as redis-cli uses for terminals. If stdout is not a terminal or -raw is
+// given, replies are printed in raw format instead. Using -json replies are printed as JSON, one reply per line.
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/nussjustin/resp"
+)
+
+func main() {
+	host := flag.String("h", "127.0.0.1", "server hostname")
+	port := flag.Int("p", 6379, "server port")
+	socket := flag.String("s", "", "server socket, overriding hostname and port")
+	resp3 := flag.Bool("3", false, "switch to RESP3 using HELLO after connecting")
+	raw := flag.Bool("raw", false, "use raw formatting for replies (default when stdout is not a terminal)")
+	noRaw := flag.Bool("no-raw", false, "use formatted output even when stdout is not a terminal")
+	jsonOut := flag.Bool("json", false, "print replies as JSON")
+	flag.Parse()
+
+	network, addr := "tcp", net.JoinHostPort(*host, strconv.Itoa(*port))
+	if *socket != "" {
+		network, addr = "unix", *socket
+	}
+
+	ctx := context.Background()
+
+	conn, err := resp.Dial(ctx, network, addr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not connect to %s: %s\n", addr, err)
+		os.Exit(1)
+	}
+	defer conn.Close()
+
+	c := &client{conn: conn, out: os.Stdout, mode: modeFormatted}
+	switch {
+	case *jsonOut:
+		c.mode = modeJSON
+	case *raw || (!*noRaw && !isTerminal(os.Stdout)):
+		c.mode = modeRaw
+	}
+
+	if *resp3 {
+		if _, err := conn.Do(ctx, "HELLO", "3"); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to switch to RESP3: %s\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if args := flag.Args(); len(args) > 0 {
+		err = c.do(ctx, args)
+	} else {
+		prompt := ""
+		if isTerminal(os.Stdin) {
+			prompt = addr + "> "
+		}
+		err = c.repl(ctx, os.Stdin, os.Stderr, prompt)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+type outputMode int
+
+const (
+	modeFormatted outputMode = iota
+	modeRaw
+	modeJSON
+)
+
+// client sends commands over a connection and prints the replies.
+type client struct {
+	conn *resp.Conn
+	out  io.Writer
+	mode outputMode
+}
+
+// do sends a single command and prints the reply. Error replies are printed like other replies.
+func (c *client) do(ctx context.Context, args []string) error {
+	iargs := make([]interface{}, len(args))
+	for i, arg := range args {
+		iargs[i] = arg
+	}
+
+	v, err := c.conn.Do(ctx, iargs...)
+	if err != nil && !errors.As(err, new(resp.ReplyError)) {
+		return err
+	}
+
+	return c.print(v)
+}
+
+func (c *client) print(v resp.Value) error {
+	switch c.mode {
+	case modeJSON:
+		b, err := resp.AppendJSON(nil, v, nil)
+		if err != nil {
+			return err
+		}
+		_, err = c.out.Write(append(b, '\n'))
+		return err
+	case modeRaw:
+		return resp.Format(c.out, v, &resp.FormatOptions{Raw: true})
+	default:
+		return resp.Format(c.out, v, nil)
+	}
+}
+
+// repl reads commands from in line by line, until the end of the input or a quit or exit command.
+//
+// If prompt is not empty, it is written to promptOut before reading each line.
+func (c *client) repl(ctx context.Context, in io.Reader, promptOut io.Writer, prompt string) error {
+	sc := bufio.NewScanner(in)
+	sc.Buffer(nil, 512*1024*1024)
+
+	for {
+		if prompt != "" {
+			_, _ = io.WriteString(promptOut, prompt)
+		}
+		if !sc.Scan() {
+			return sc.Err()
+		}
+
+		args, err := splitArgs(sc.Text())
+		if err != nil {
+			_, _ = fmt.Fprintln(c.out, err)
+			continue
+		}
+		if len(args) == 0 {
+			continue
+		}
+		if name := strings.ToLower(args[0]); len(args) == 1 && (name == "quit" || name == "exit") {
+			return nil
+		}
+
+		if err := c.do(ctx, args); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/nussjustin/resp"
+	"github.com/nussjustin/resp/resptest"
+)
+
+func newTestClient(tb testing.TB, mode outputMode) (*client, *strings.Builder) {
+	srv := resptest.NewServer()
+	tb.Cleanup(srv.Close)
+
+	conn, err := srv.Dial(context.Background())
+	if err != nil {
+		tb.Fatalf("failed to connect: %s", err)
+	}
+	tb.Cleanup(func() { _ = conn.Close() })
+
+	var out strings.Builder
+	return &client{conn: conn, out: &out, mode: mode}, &out
+}
+
+func TestClientREPL(t *testing.T) {
+	for _, test := range []struct {
+		Name     string
+		Mode     outputMode
+		Expected string
+	}{
+		{
+			Name: "formatted",
+			Mode: modeFormatted,
+			Expected: "OK\n(integer) 2\n1) \"a\"\n2) \"b c\"\n" +
+				"(error) ERR unknown command 'UNKNOWN', with args beginning with: \n" +
+				"Invalid argument(s)\n(nil)\n",
+		},
+		{
+			Name:     "raw",
+			Mode:     modeRaw,
+			Expected: "OK\n2\na\nb c\nERR unknown command 'UNKNOWN', with args beginning with: \nInvalid argument(s)\n\n",
+		},
+		{
+			Name: "json",
+			Mode: modeJSON,
+			Expected: "\"OK\"\n2\n[\"a\",\"b c\"]\n" +
+				"{\"error\":\"ERR unknown command 'UNKNOWN', with args beginning with: \"}\n" +
+				"Invalid argument(s)\nnull\n",
+		},
+	} {
+		test := test
+
+		t.Run(test.Name, func(t *testing.T) {
+			c, out := newTestClient(t, test.Mode)
+
+			in := "SET key value\n\nRPUSH list a \"b c\"\nLRANGE list 0 -1\nUNKNOWN\nGET \"key\nGET missing\nquit\nGET key\n"
+
+			var prompts strings.Builder
+			if err := c.repl(context.Background(), strings.NewReader(in), &prompts, "> "); err != nil {
+				t.Fatalf("got error %v", err)
+			}
+			if got := out.String(); got != test.Expected {
+				t.Errorf("got output %q, expected %q", got, test.Expected)
+			}
+			if got, expected := prompts.String(), strings.Repeat("> ", 8); got != expected {
+				t.Errorf("got prompts %q, expected %q", got, expected)
+			}
+		})
+	}
+}
+
+func TestClientJSON(t *testing.T) {
+	c, out := newTestClient(t, modeJSON)
+
+	for _, v := range []resp.Value{
+		resp.Map(resp.SimpleString("a"), resp.Set(resp.Boolean(true), resp.Double(1.5)), resp.Integer(1), resp.Null()),
+		resp.BigNumber("12345678901234567890"),
+		resp.VerbatimString("txt", "hello"),
+	} {
+		if err := c.print(v); err != nil {
+			t.Fatalf("got error %v", err)
+		}
+	}
+
+	expected := "{\"a\":[true,1.5],\"1\":null}\n12345678901234567890\n\"hello\"\n"
+	if got := out.String(); got != expected {
+		t.Errorf("got %q, expected %q", got, expected)
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import "errors"
+
+// errInvalidArgs is returned by splitArgs for lines with unbalanced quotes.
+var errInvalidArgs = errors.New("Invalid argument(s)")
+
+func isSpace(c byte) bool {
+	switch c {
+	case ' ', '\n', '\r', '\t', '\v', '\f':
+		return true
+	default:
+		return false
+	}
+}
+
+func hexValue(c byte) (byte, bool) {
+	switch {
+	case c >= '0' && c <= '9':
+		return c - '0', true
+	case c >= 'a' && c <= 'f':
+		return c - 'a' + 10, true
+	case c >= 'A' && c <= 'F':
+		return c - 'A' + 10, true
+	default:
+		return 0, false
+	}
+}
+
+// splitArgs splits a line into arguments using the same rules as redis-cli.
+//
+// Arguments are separated by whitespace. Arguments in double quotes can contain whitespace and the escape sequences
+// \n, \r, \t, \b, \a, \\, \" and \xHH. Arguments in single quotes can contain whitespace and \' for a single quote.
+// A closing quote must be followed by whitespace or the end of the line.
+func splitArgs(line string) ([]string, error) {
+	var args []string
+
+	for i := 0; ; {
+		for i < len(line) && isSpace(line[i]) {
+			i++
+		}
+		if i == len(line) {
+			return args, nil
+		}
+
+		var arg []byte
+		var inDouble, inSingle bool
+
+		for done := false; !done; {
+			switch {
+			case inDouble:
+				if i == len(line) {
+					return nil, errInvalidArgs
+				}
+				if line[i] == '\\' && i+3 < len(line) && line[i+1] == 'x' {
+					hi, ok1 := hexValue(line[i+2])
+					lo, ok2 := hexValue(line[i+3])
+					if ok1 && ok2 {
+						arg = append(arg, hi<<4|lo)
+						i += 4
+						continue
+					}
+				}
+				if line[i] == '\\' && i+1 < len(line) {
+					c := line[i+1]
+					switch c {
+					case 'n':
+						c = '\n'
+					case 'r':
+						c = '\r'
+					case 't':
+						c = '\t'
+					case 'b':
+						c = '\b'
+					case 'a':
+						c = '\a'
+					}
+					arg = append(arg, c)
+					i += 2
+					continue
+				}
+				if line[i] == '"' {
+					// the closing quote must be followed by a space or nothing at all
+					if i+1 < len(line) && !isSpace(line[i+1]) {
+						return nil, errInvalidArgs
+					}
+					done = true
+				} else {
+					arg = append(arg, line[i])
+				}
+				i++
+			case inSingle:
+				if i == len(line) {
+					return nil, errInvalidArgs
+				}
+				if line[i] == '\\' && i+1 < len(line) && line[i+1] == '\'' {
+					arg = append(arg, '\'')
+					i += 2
+					continue
+				}
+				if line[i] == '\'' {
+					if i+1 < len(line) && !isSpace(line[i+1]) {
+						return nil, errInvalidArgs
+					}
+					done = true
+				} else {
+					arg = append(arg, line[i])
+				}
+				i++
+			default:
+				if i == len(line) {
+					done = true
+					break
+				}
+				switch c := line[i]; {
+				case isSpace(c):
+					done = true
+				case c == '"':
+					inDouble = true
+				case c == '\'':
+					inSingle = true
+				default:
+					arg = append(arg, c)
+				}
+				i++
+			}
+		}
+
+		args = append(args, string(arg))
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitArgs(t *testing.T) {
+	for _, test := range []struct {
+		In       string
+		Expected []string
+		Err      error
+	}{
+		{In: "", Expected: nil},
+		{In: "   ", Expected: nil},
+		{In: "GET key", Expected: []string{"GET", "key"}},
+		{In: "  SET\tkey   value  ", Expected: []string{"SET", "key", "value"}},
+		{In: `SET key "hello world"`, Expected: []string{"SET", "key", "hello world"}},
+		{In: `SET key ""`, Expected: []string{"SET", "key", ""}},
+		{In: `SET key "a\"b\\c\n\r\t\b\a\x41\x4g"`, Expected: []string{"SET", "key", "a\"b\\c\n\r\t\b\aAx4g"}},
+		{In: `SET key 'it\'s "quoted"'`, Expected: []string{"SET", "key", `it's "quoted"`}},
+		{In: `SET key 'a\nb'`, Expected: []string{"SET", "key", `a\nb`}},
+		{In: `SET k"e y"`, Expected: []string{"SET", "ke y"}},
+		{In: `SET key "unbalanced`, Err: errInvalidArgs},
+		{In: `SET key 'unbalanced`, Err: errInvalidArgs},
+		{In: `SET key "a"b`, Err: errInvalidArgs},
+		{In: `SET key 'a'b`, Err: errInvalidArgs},
+	} {
+		got, err := splitArgs(test.In)
+		if err != test.Err {
+			t.Errorf("%q: got error %v, expected %v", test.In, err, test.Err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.Expected) {
+			t.Errorf("%q: got %q, expected %q", test.In, got, test.Expected)
+		}
+	}
+}
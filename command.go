@@ -0,0 +1,92 @@
+package resp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+)
+
+// ErrInvalidCommand is returned by Reader.ReadCommand when the next value is not a non-null array of non-null bulk
+// strings.
+var ErrInvalidCommand = errors.New("command must be an array of bulk strings")
+
+// Command is a single command sent by a client.
+type Command struct {
+	// Args contains the command name followed by all arguments.
+	//
+	// When used by a Server, Args and the slices contained in it are reused for the next command and must not be
+	// retained after the Handler returns.
+	Args [][]byte
+
+	ctx  context.Context
+	conn *ServerConn
+}
+
+// Name returns the name of the command, as sent by the client, or an empty string if the command has no arguments.
+func (c *Command) Name() string {
+	if len(c.Args) == 0 {
+		return ""
+	}
+	return string(c.Args[0])
+}
+
+// Is returns true if the command name matches the given name, ignoring case.
+func (c *Command) Is(name string) bool {
+	return len(c.Args) > 0 && bytes.EqualFold(c.Args[0], []byte(name))
+}
+
+// Context returns the context of the command.
+//
+// For commands read by a Server, the context is canceled when the connection is closed. Otherwise
+// context.Background is returned.
+func (c *Command) Context() context.Context {
+	if c.ctx == nil {
+		return context.Background()
+	}
+	return c.ctx
+}
+
+// Conn returns the connection the command was received on or nil if the command was not read by a Server.
+func (c *Command) Conn() *ServerConn {
+	return c.conn
+}
+
+// ReadCommand reads a command sent as an array of bulk strings into cmd.
+//
+// The slices in cmd.Args are reused if possible. If the command is not an array of bulk strings, ErrInvalidCommand
+// is returned and the Reader is broken.
+func (rr *Reader) ReadCommand(cmd *Command) error {
+	n, err := rr.ReadArrayHeader()
+	if err != nil {
+		return err
+	}
+	if n < 0 {
+		return rr.broken(ErrInvalidCommand)
+	}
+
+	args := cmd.Args[:0]
+	for i := 0; i < n; i++ {
+		var buf []byte
+		if i < cap(args) {
+			buf = args[:i+1][i][:0]
+		}
+
+		if t, err := rr.Peek(); err != nil {
+			return rr.broken(err)
+		} else if t != TypeBulkString {
+			return rr.broken(ErrInvalidCommand)
+		}
+
+		b, err := rr.ReadBulkString(buf)
+		if err != nil {
+			return rr.broken(err)
+		}
+		if b == nil {
+			return rr.broken(ErrInvalidCommand)
+		}
+		args = append(args, b)
+	}
+
+	cmd.Args = args
+	return nil
+}
@@ -0,0 +1,51 @@
+package resp_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/nussjustin/resp"
+)
+
+func TestReaderReadCommand(t *testing.T) {
+	r := resp.NewReader(strings.NewReader("*3\r\n$3\r\nSET\r\n$1\r\nk\r\n$0\r\n\r\n*2\r\n$3\r\nGET\r\n$1\r\nk\r\n*0\r\n"))
+
+	var cmd resp.Command
+	for _, expected := range [][][]byte{
+		{[]byte("SET"), []byte("k"), {}},
+		{[]byte("GET"), []byte("k")},
+		{},
+	} {
+		if err := r.ReadCommand(&cmd); err != nil {
+			t.Fatalf("failed to read command: %s", err)
+		}
+		if !reflect.DeepEqual(cmd.Args, expected) {
+			t.Errorf("got %q, expected %q", cmd.Args, expected)
+		}
+	}
+
+	if name := (&resp.Command{Args: [][]byte{[]byte("get")}}).Name(); name != "get" {
+		t.Errorf("got name %q, expected %q", name, "get")
+	}
+	if !(&resp.Command{Args: [][]byte{[]byte("get")}}).Is("GET") {
+		t.Error("expected command to match name case-insensitively")
+	}
+}
+
+func TestReaderReadCommandInvalid(t *testing.T) {
+	for _, in := range []string{
+		"*-1\r\n",
+		"*1\r\n:1\r\n",
+		"*1\r\n$-1\r\n",
+	} {
+		r := resp.NewReader(strings.NewReader(in))
+
+		if err := r.ReadCommand(new(resp.Command)); err != resp.ErrInvalidCommand {
+			t.Errorf("got error %v for %q, expected %v", err, in, resp.ErrInvalidCommand)
+		}
+		if err := r.Err(); err != resp.ErrInvalidCommand {
+			t.Errorf("got error %v from Err for %q, expected %v", err, in, resp.ErrInvalidCommand)
+		}
+	}
+}
@@ -0,0 +1,423 @@
+package resp
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// ErrConnBroken is returned by Conn when a previous operation failed in a way that left the connection in an unknown
+// state. Broken connections should be closed.
+var ErrConnBroken = errors.New("connection is broken after a previous error")
+
+// ErrPendingReplies is returned by Conn.Do and Pipeline.Exec when commands were sent using Conn.Send whose replies
+// were not yet read using Conn.Receive.
+var ErrPendingReplies = errors.New("replies of sent commands must be received first")
+
+// Conn is a client connection to a RESP server.
+//
+// Requests written by Conn are buffered and only flushed when calling Do, Send or Pipeline.Exec.
+//
+// All methods taking a context.Context apply the deadline of the context to the underlying net.Conn and interrupt
+// pending reads and writes when the context is canceled. If this happens after a command was sent, but before its
+// reply was read completely, the Conn is marked as broken and must be closed.
+//
+// A Conn must not be used concurrently from multiple goroutines.
+type Conn struct {
+	conn net.Conn
+	bw   *bufio.Writer
+	rw   ReadWriter
+
+	// err is the first error that broke the connection.
+	err error
+
+	// pending is the number of commands sent using Send for which no value was read using Receive yet.
+	pending int
+}
+
+// Dial connects to the RESP server at the given address and returns a new Conn.
+//
+// See net.Dial for a description of the network and address parameters.
+func Dial(ctx context.Context, network, address string) (*Conn, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, network, address)
+	if err != nil {
+		return nil, err
+	}
+	return NewConn(conn), nil
+}
+
+// NewConn returns a new Conn using the given net.Conn.
+func NewConn(conn net.Conn) *Conn {
+	c := &Conn{conn: conn, bw: bufio.NewWriter(conn)}
+	c.rw.Reader.Reset(conn)
+	c.rw.Writer.Reset(c.bw)
+	return c
+}
+
+// Close closes the underlying net.Conn.
+func (c *Conn) Close() error {
+	if c.err == nil {
+		c.err = net.ErrClosed
+	}
+	return c.conn.Close()
+}
+
+// Err returns the error that broke the connection or nil if the connection is still usable.
+func (c *Conn) Err() error {
+	return c.err
+}
+
+// NetConn returns the underlying net.Conn.
+func (c *Conn) NetConn() net.Conn {
+	return c.conn
+}
+
+// ReadWriter returns the ReadWriter used by the Conn.
+//
+// Data written using the returned ReadWriter is buffered and must be flushed using Flush.
+func (c *Conn) ReadWriter() *ReadWriter {
+	return &c.rw
+}
+
+// Flush writes any buffered data to the underlying net.Conn.
+func (c *Conn) Flush() error {
+	if c.err != nil {
+		return c.err
+	}
+	return c.fail(c.bw.Flush())
+}
+
+// fail marks the connection as broken if err is not nil and not a ReplyError.
+func (c *Conn) fail(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := err.(ReplyError); !ok && c.err == nil {
+		c.err = err
+	}
+	return err
+}
+
+// Do sends a single command, consisting of the given arguments, and returns the reply.
+//
+// Arguments can be strings, byte slices, integers, floats and booleans. All arguments are sent as bulk strings.
+//
+// If the reply is an error, the reply is returned together with the error as ReplyError.
+//
+// RESP3 push messages read while waiting for the reply are discarded. RESP3 attributes are read together with the
+// reply they describe, but are not returned.
+//
+// If there are replies to commands sent using Send that were not yet read using Receive, Do returns
+// ErrPendingReplies without sending the command.
+func (c *Conn) Do(ctx context.Context, args ...interface{}) (Value, error) {
+	if c.err != nil {
+		return Value{}, ErrConnBroken
+	}
+	if c.pending > 0 {
+		return Value{}, ErrPendingReplies
+	}
+
+	var v Value
+	err := c.withContext(ctx, func() error {
+		if err := c.writeCommand(args); err != nil {
+			return err
+		}
+		if err := c.Flush(); err != nil {
+			return err
+		}
+		var err error
+		v, err = readReply(&c.rw.Reader)
+		return c.fail(err)
+	})
+	if err != nil {
+		return Value{}, err
+	}
+	return v, v.Err()
+}
+
+// Send sends a single command, consisting of the given arguments, without waiting for a reply.
+//
+// This can be used for commands that do not reply directly, for example when subscribing to channels. Replies can be
+// read using Receive.
+//
+// See Do for the supported argument types.
+func (c *Conn) Send(ctx context.Context, args ...interface{}) error {
+	if c.err != nil {
+		return ErrConnBroken
+	}
+
+	return c.withContext(ctx, func() error {
+		if err := c.writeCommand(args); err != nil {
+			return err
+		}
+		c.pending++
+		return c.Flush()
+	})
+}
+
+// Receive reads the next value from the connection.
+//
+// Contrary to Do and Send, Receive does not break the connection when ctx is done before the first byte of the next
+// value was read.
+func (c *Conn) Receive(ctx context.Context) (Value, error) {
+	if c.err != nil {
+		return Value{}, ErrConnBroken
+	}
+
+	var v Value
+	err := c.withContext(ctx, func() error {
+		var err error
+		v, err = c.rw.ReadValue()
+		if err != nil && c.rw.Reader.Err() == nil && isTimeout(err) {
+			return err
+		}
+		if err == nil && c.pending > 0 {
+			c.pending--
+		}
+		return c.fail(err)
+	})
+	return v, err
+}
+
+// aLongTimeAgo is a non-zero time in the past, used to interrupt pending reads and writes.
+var aLongTimeAgo = time.Unix(1, 0)
+
+// withContext calls fn with the deadline of ctx applied to the underlying net.Conn, interrupting fn if ctx is
+// canceled. If fn failed because of ctx, the context error is returned.
+func (c *Conn) withContext(ctx context.Context, fn func() error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	deadline, hasDeadline := ctx.Deadline()
+	done := ctx.Done()
+
+	if hasDeadline {
+		if err := c.conn.SetDeadline(deadline); err != nil {
+			return c.fail(err)
+		}
+	}
+
+	var stop, stopped chan struct{}
+	if done != nil {
+		stop, stopped = make(chan struct{}), make(chan struct{})
+		go func() {
+			defer close(stopped)
+			select {
+			case <-done:
+				_ = c.conn.SetDeadline(aLongTimeAgo)
+			case <-stop:
+			}
+		}()
+	}
+
+	err := fn()
+
+	if done != nil {
+		close(stop)
+		<-stopped
+	}
+
+	if hasDeadline || done != nil {
+		if derr := c.conn.SetDeadline(time.Time{}); derr != nil && err == nil {
+			err = c.fail(derr)
+		}
+	}
+
+	if err != nil && isTimeout(err) {
+		if cerr := ctx.Err(); cerr != nil {
+			return cerr
+		}
+		if hasDeadline && !time.Now().Before(deadline) {
+			return context.DeadlineExceeded
+		}
+	}
+	return err
+}
+
+func isTimeout(err error) bool {
+	nerr, ok := err.(net.Error)
+	return ok && nerr.Timeout()
+}
+
+// Pipeline returns a new Pipeline for sending multiple commands at once.
+func (c *Conn) Pipeline() *Pipeline {
+	return &Pipeline{c: c}
+}
+
+// Pipeline queues multiple commands and sends them at once, reading all replies in order.
+//
+// Queued commands are buffered by the Pipeline itself and only written to the connection by Exec, so the Conn can be
+// used normally while commands are queued.
+type Pipeline struct {
+	c   *Conn
+	buf []byte
+	n   int
+	err error
+}
+
+// Len returns the number of queued commands.
+func (p *Pipeline) Len() int {
+	return p.n
+}
+
+// Queue queues a command consisting of the given arguments.
+//
+// See Conn.Do for the supported argument types. Errors are returned by Exec.
+func (p *Pipeline) Queue(args ...interface{}) {
+	if p.err != nil {
+		return
+	}
+	buf, err := appendCommand(p.buf, args)
+	if err != nil {
+		p.err = err
+		return
+	}
+	p.buf = buf
+	p.n++
+}
+
+// Exec sends all queued commands and returns the replies in the order the commands were queued.
+//
+// Error replies are returned as part of the replies and not as error. Push messages and attributes are handled the same
+// as by Conn.Do. If queueing a command failed, all commands
+// queued before the failed command are still sent and their replies returned together with the error.
+//
+// If ctx is already done or there are replies to commands sent using Conn.Send that were not yet read, Exec returns
+// an error and all commands stay queued. Otherwise the Pipeline is reset and can be reused after Exec returns.
+func (p *Pipeline) Exec(ctx context.Context) ([]Value, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if p.c.err == nil && p.c.pending > 0 {
+		return nil, ErrPendingReplies
+	}
+
+	buf, n, queueErr := p.buf, p.n, p.err
+	p.buf, p.n, p.err = p.buf[:0], 0, nil
+
+	if p.c.err != nil {
+		return nil, ErrConnBroken
+	}
+
+	vs := make([]Value, n)
+	err := p.c.withContext(ctx, func() error {
+		if _, err := p.c.bw.Write(buf); err != nil {
+			return p.c.fail(err)
+		}
+		if err := p.c.Flush(); err != nil {
+			return err
+		}
+		for i := range vs {
+			var err error
+			if vs[i], err = readReply(&p.c.rw.Reader); err != nil {
+				return p.c.fail(err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return vs, queueErr
+}
+
+// readReply reads the reply to a command, discarding RESP3 push messages and attributes, which are not part of the
+// reply.
+func readReply(rr *Reader) (Value, error) {
+	for {
+		v, err := rr.ReadValue()
+		if err != nil || (v.Type != TypePush && v.Type != TypeAttribute) {
+			return v, err
+		}
+	}
+}
+
+// writeCommand writes the given arguments as an array of bulk strings.
+//
+// If an argument has an unsupported type, nothing is written and the connection stays usable.
+func (c *Conn) writeCommand(args []interface{}) error {
+	buf, err := appendCommand(c.rw.Writer.buf[:0], args)
+	c.rw.Writer.buf = buf
+	if err != nil {
+		return err
+	}
+	_, err = c.rw.Writer.Write(buf)
+	return c.fail(err)
+}
+
+// AppendCommand appends a command consisting of the given arguments to dst as an array of bulk strings and returns
+// the extended slice.
+//
+// Arguments are converted in the same way as by Conn.Do. If an argument has an unsupported type, an error is returned
+// together with dst unchanged.
+func AppendCommand(dst []byte, args ...interface{}) ([]byte, error) {
+	b, err := appendCommand(dst, args)
+	if err != nil {
+		return dst, err
+	}
+	return b, nil
+}
+
+// appendCommand appends the given arguments as an array of bulk strings to dst.
+func appendCommand(dst []byte, args []interface{}) ([]byte, error) {
+	dst = AppendArrayHeader(dst, len(args))
+
+	var scratch [64]byte
+
+	for _, arg := range args {
+		var s []byte
+		switch arg := arg.(type) {
+		case string:
+			dst = AppendBulkString(dst, arg)
+			continue
+		case []byte:
+			s = arg
+		case int:
+			s = strconv.AppendInt(scratch[:0], int64(arg), 10)
+		case int8:
+			s = strconv.AppendInt(scratch[:0], int64(arg), 10)
+		case int16:
+			s = strconv.AppendInt(scratch[:0], int64(arg), 10)
+		case int32:
+			s = strconv.AppendInt(scratch[:0], int64(arg), 10)
+		case int64:
+			s = strconv.AppendInt(scratch[:0], arg, 10)
+		case uint:
+			s = strconv.AppendUint(scratch[:0], uint64(arg), 10)
+		case uint8:
+			s = strconv.AppendUint(scratch[:0], uint64(arg), 10)
+		case uint16:
+			s = strconv.AppendUint(scratch[:0], uint64(arg), 10)
+		case uint32:
+			s = strconv.AppendUint(scratch[:0], uint64(arg), 10)
+		case uint64:
+			s = strconv.AppendUint(scratch[:0], arg, 10)
+		case float32:
+			s = strconv.AppendFloat(scratch[:0], float64(arg), 'g', -1, 32)
+		case float64:
+			s = strconv.AppendFloat(scratch[:0], arg, 'g', -1, 64)
+		case bool:
+			s = strconv.AppendInt(scratch[:0], boolToInt(arg), 10)
+		case nil:
+			return dst, &UnsupportedValueError{Str: "nil"}
+		default:
+			return dst, &UnsupportedTypeError{Type: reflect.TypeOf(arg)}
+		}
+		dst = appendBlobBytes(dst, '$', s)
+	}
+
+	return dst, nil
+}
+
+func boolToInt(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
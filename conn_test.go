@@ -0,0 +1,395 @@
+package resp_test
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nussjustin/resp"
+)
+
+// serveConn reads requests from conn and writes the replies returned by fn until conn is closed.
+func serveConn(tb testing.TB, conn net.Conn, fn func(req resp.Value) resp.Value) {
+	rw := resp.NewReadWriter(conn)
+	for {
+		req, err := rw.ReadValue()
+		if err != nil {
+			if err != io.EOF && err != io.ErrClosedPipe {
+				tb.Errorf("failed to read request: %s", err)
+			}
+			return
+		}
+		if _, err := rw.WriteValue(fn(req)); err != nil {
+			// the client may have closed the connection without waiting for the reply
+			return
+		}
+	}
+}
+
+func newTestConn(tb testing.TB, fn func(req resp.Value) resp.Value) *resp.Conn {
+	client, server := net.Pipe()
+	go serveConn(tb, server, fn)
+
+	c := resp.NewConn(client)
+	tb.Cleanup(func() {
+		_ = c.Close()
+		_ = server.Close()
+	})
+	return c
+}
+
+func echoHandler(req resp.Value) resp.Value {
+	var parts []string
+	for _, arg := range req.Elems {
+		parts = append(parts, string(arg.Str))
+	}
+	if parts[0] == "ERR" {
+		return resp.Error(strings.Join(parts, " "))
+	}
+	return resp.BulkString(strings.Join(parts, " "))
+}
+
+func TestConnDo(t *testing.T) {
+	c := newTestConn(t, echoHandler)
+	ctx := context.Background()
+
+	v, err := c.Do(ctx, "ECHO", []byte("hello"), 1, int64(-2), uint8(3), 1.5, true)
+	if err != nil {
+		t.Fatalf("do failed: %s", err)
+	}
+	if expected := resp.BulkString("ECHO hello 1 -2 3 1.5 1"); !reflect.DeepEqual(v, expected) {
+		t.Errorf("got %#v, expected %#v", v, expected)
+	}
+
+	v, err = c.Do(ctx, "ERR", "something")
+	if err != resp.ReplyError("ERR something") {
+		t.Errorf("got error %v, expected %v", err, resp.ReplyError("ERR something"))
+	}
+	if expected := resp.Error("ERR something"); !reflect.DeepEqual(v, expected) {
+		t.Errorf("got %#v, expected %#v", v, expected)
+	}
+
+	if _, err := c.Do(ctx, "ECHO", struct{}{}); err == nil {
+		t.Error("expected error for unsupported argument")
+	}
+	if err := c.Err(); err != nil {
+		t.Fatalf("connection broken after argument error: %s", err)
+	}
+
+	if _, err := c.Do(ctx, "ECHO", "still working"); err != nil {
+		t.Fatalf("do failed: %s", err)
+	}
+
+	canceledCtx, cancel := context.WithCancel(ctx)
+	cancel()
+
+	if _, err := c.Do(canceledCtx, "ECHO", "canceled"); err != context.Canceled {
+		t.Errorf("got error %v, expected %v", err, context.Canceled)
+	}
+}
+
+func TestAppendCommand(t *testing.T) {
+	got, err := resp.AppendCommand([]byte("prefix"), "SET", []byte("k"), 1, 1.5, true)
+	if err != nil {
+		t.Fatalf("got error %v, expected no error", err)
+	}
+	assertBytes(t, got, "prefix*5\r\n$3\r\nSET\r\n$1\r\nk\r\n$1\r\n1\r\n$3\r\n1.5\r\n$1\r\n1\r\n")
+
+	got, err = resp.AppendCommand([]byte("prefix"), "GET", nil)
+	if _, ok := err.(*resp.UnsupportedValueError); !ok {
+		t.Errorf("got error %#v, expected *resp.UnsupportedValueError", err)
+	}
+	assertBytes(t, got, "prefix")
+}
+
+func TestConnDoBroken(t *testing.T) {
+	client, server := net.Pipe()
+	_ = server.Close()
+
+	c := resp.NewConn(client)
+	defer c.Close()
+
+	if _, err := c.Do(context.Background(), "PING"); err == nil {
+		t.Fatal("expected error")
+	}
+	if c.Err() == nil {
+		t.Fatal("expected connection to be broken")
+	}
+	if _, err := c.Do(context.Background(), "PING"); err != resp.ErrConnBroken {
+		t.Errorf("got error %v, expected %v", err, resp.ErrConnBroken)
+	}
+}
+
+func TestPipelineExec(t *testing.T) {
+	c := newTestConn(t, echoHandler)
+
+	p := c.Pipeline()
+	p.Queue("ECHO", "a")
+	p.Queue("ERR", "b")
+	p.Queue("ECHO", "c")
+
+	if p.Len() != 3 {
+		t.Errorf("got length %d, expected 3", p.Len())
+	}
+
+	vs, err := p.Exec(context.Background())
+	if err != nil {
+		t.Fatalf("exec failed: %s", err)
+	}
+
+	expected := []resp.Value{
+		resp.BulkString("ECHO a"),
+		resp.Error("ERR b"),
+		resp.BulkString("ECHO c"),
+	}
+	if !reflect.DeepEqual(vs, expected) {
+		t.Errorf("got %#v, expected %#v", vs, expected)
+	}
+
+	if p.Len() != 0 {
+		t.Errorf("got length %d after exec, expected 0", p.Len())
+	}
+
+	p.Queue("ECHO", "d")
+	p.Queue("ECHO", nil)
+	p.Queue("ECHO", "e")
+
+	vs, err = p.Exec(context.Background())
+	if err == nil {
+		t.Error("expected error for unsupported argument")
+	}
+	if expected := []resp.Value{resp.BulkString("ECHO d")}; !reflect.DeepEqual(vs, expected) {
+		t.Errorf("got %#v, expected %#v", vs, expected)
+	}
+}
+
+func TestConnDoAttributesAndPush(t *testing.T) {
+	client, server := net.Pipe()
+	t.Cleanup(func() { _ = server.Close() })
+
+	go func() {
+		rw := resp.NewReadWriter(server)
+		for {
+			req, err := rw.ReadValue()
+			if err != nil {
+				return
+			}
+			_, _ = server.Write([]byte(">2\r\n$10\r\ninvalidate\r\n*1\r\n$1\r\nk\r\n|1\r\n+ttl\r\n:100\r\n"))
+			_, _ = rw.WriteValue(echoHandler(req))
+		}
+	}()
+
+	c := resp.NewConn(client)
+	t.Cleanup(func() { _ = c.Close() })
+
+	ctx := context.Background()
+
+	for _, arg := range []string{"a", "b"} {
+		got, err := c.Do(ctx, "ECHO", arg)
+		if expected := resp.BulkString("ECHO " + arg); err != nil || !reflect.DeepEqual(got, expected) {
+			t.Errorf("got (%#v, %v), expected (%#v, nil)", got, err, expected)
+		}
+	}
+
+	p := c.Pipeline()
+	p.Queue("ECHO", "c")
+	p.Queue("ECHO", "d")
+
+	vs, err := p.Exec(ctx)
+	if err != nil {
+		t.Fatalf("exec failed: %s", err)
+	}
+	if expected := []resp.Value{resp.BulkString("ECHO c"), resp.BulkString("ECHO d")}; !reflect.DeepEqual(vs, expected) {
+		t.Errorf("got %#v, expected %#v", vs, expected)
+	}
+}
+
+func TestPipelineQueueBuffered(t *testing.T) {
+	c := newTestConn(t, echoHandler)
+	ctx := context.Background()
+
+	abandoned := c.Pipeline()
+	abandoned.Queue("ECHO", "abandoned")
+
+	p := c.Pipeline()
+	p.Queue("ECHO", "a")
+
+	if v, err := c.Do(ctx, "ECHO", "b"); err != nil {
+		t.Fatalf("do failed: %s", err)
+	} else if expected := resp.BulkString("ECHO b"); !reflect.DeepEqual(v, expected) {
+		t.Errorf("got %#v, expected %#v", v, expected)
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+
+	if _, err := p.Exec(canceled); err != context.Canceled {
+		t.Fatalf("got error %v, expected %v", err, context.Canceled)
+	}
+	if p.Len() != 1 {
+		t.Errorf("got length %d after canceled exec, expected 1", p.Len())
+	}
+
+	if v, err := c.Do(ctx, "ECHO", "c"); err != nil {
+		t.Fatalf("do failed: %s", err)
+	} else if expected := resp.BulkString("ECHO c"); !reflect.DeepEqual(v, expected) {
+		t.Errorf("got %#v, expected %#v", v, expected)
+	}
+
+	vs, err := p.Exec(ctx)
+	if err != nil {
+		t.Fatalf("exec failed: %s", err)
+	}
+	if expected := []resp.Value{resp.BulkString("ECHO a")}; !reflect.DeepEqual(vs, expected) {
+		t.Errorf("got %#v, expected %#v", vs, expected)
+	}
+}
+
+func TestConnPendingReplies(t *testing.T) {
+	c := newTestConn(t, echoHandler)
+	ctx := context.Background()
+
+	if err := c.Send(ctx, "ECHO", "a"); err != nil {
+		t.Fatalf("send failed: %s", err)
+	}
+
+	if _, err := c.Do(ctx, "ECHO", "b"); err != resp.ErrPendingReplies {
+		t.Errorf("got error %v from Do, expected %v", err, resp.ErrPendingReplies)
+	}
+
+	p := c.Pipeline()
+	p.Queue("ECHO", "c")
+	if _, err := p.Exec(ctx); err != resp.ErrPendingReplies {
+		t.Errorf("got error %v from Exec, expected %v", err, resp.ErrPendingReplies)
+	}
+
+	if v, err := c.Receive(ctx); err != nil {
+		t.Fatalf("receive failed: %s", err)
+	} else if expected := resp.BulkString("ECHO a"); !reflect.DeepEqual(v, expected) {
+		t.Errorf("got %#v, expected %#v", v, expected)
+	}
+
+	if v, err := c.Do(ctx, "ECHO", "d"); err != nil {
+		t.Fatalf("do failed: %s", err)
+	} else if expected := resp.BulkString("ECHO d"); !reflect.DeepEqual(v, expected) {
+		t.Errorf("got %#v, expected %#v", v, expected)
+	}
+
+	vs, err := p.Exec(ctx)
+	if err != nil {
+		t.Fatalf("exec failed: %s", err)
+	}
+	if expected := []resp.Value{resp.BulkString("ECHO c")}; !reflect.DeepEqual(vs, expected) {
+		t.Errorf("got %#v, expected %#v", vs, expected)
+	}
+}
+
+func TestConnDoDeadline(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+
+	c := resp.NewConn(client)
+	defer c.Close()
+
+	go func() {
+		r := resp.NewReader(server)
+		if _, err := r.ReadValue(); err != nil {
+			return
+		}
+		// send only part of the reply
+		_, _ = server.Write([]byte("$10\r\nhel"))
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if _, err := c.Do(ctx, "GET", "key"); err != context.DeadlineExceeded {
+		t.Fatalf("got error %v, expected %v", err, context.DeadlineExceeded)
+	}
+	if c.Err() == nil {
+		t.Error("expected connection to be broken")
+	}
+	if err := c.ReadWriter().Reader.Err(); err == nil {
+		t.Error("expected reader to be broken")
+	}
+	if _, err := c.Do(context.Background(), "GET", "key"); err != resp.ErrConnBroken {
+		t.Errorf("got error %v, expected %v", err, resp.ErrConnBroken)
+	}
+}
+
+func TestConnDoCancel(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+
+	c := resp.NewConn(client)
+	defer c.Close()
+
+	go func() {
+		// never reply
+		_, _ = io.Copy(ioutil.Discard, server)
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	if _, err := c.Do(ctx, "BLPOP", "list", 0); err != context.Canceled {
+		t.Fatalf("got error %v, expected %v", err, context.Canceled)
+	}
+	if c.Err() == nil {
+		t.Error("expected connection to be broken")
+	}
+}
+
+func TestConnSendReceive(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+
+	c := resp.NewConn(client)
+	defer c.Close()
+
+	values := make(chan resp.Value)
+	go func() {
+		rw := resp.NewReadWriter(server)
+		v, err := rw.ReadValue()
+		if err != nil {
+			return
+		}
+		values <- v
+		for v := range values {
+			_, _ = rw.WriteValue(v)
+		}
+	}()
+
+	if err := c.Send(context.Background(), "SUBSCRIBE", "channel"); err != nil {
+		t.Fatalf("send failed: %s", err)
+	}
+	if v := <-values; len(v.Elems) != 2 {
+		t.Fatalf("got %#v, expected command with 2 arguments", v)
+	}
+
+	// nothing was received yet, so a timeout does not break the connection
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if _, err := c.Receive(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("got error %v, expected %v", err, context.DeadlineExceeded)
+	}
+	if err := c.Err(); err != nil {
+		t.Fatalf("connection broken after timeout: %s", err)
+	}
+
+	expected := resp.Push(resp.BulkString("message"), resp.BulkString("channel"), resp.BulkString("hello"))
+	values <- expected
+	close(values)
+
+	if v, err := c.Receive(context.Background()); err != nil {
+		t.Fatalf("receive failed: %s", err)
+	} else if !reflect.DeepEqual(v, expected) {
+		t.Errorf("got %#v, expected %#v", v, expected)
+	}
+}
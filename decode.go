@@ -0,0 +1,636 @@
+package resp
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrInvalidDecodeTarget is returned by Decode and Unmarshal when the given value is not a non-nil pointer.
+	ErrInvalidDecodeTarget = errors.New("decode target must be a non-nil pointer")
+
+	// ErrTrailingData is returned by Unmarshal when the given data contains more than one value.
+	ErrTrailingData = errors.New("invalid data after first value")
+)
+
+// ReplyError is returned when decoding a RESP error or blob error.
+type ReplyError string
+
+// Error implements the error interface.
+func (e ReplyError) Error() string {
+	return string(e)
+}
+
+// Prefix returns the first word of the error (for example ERR or WRONGTYPE).
+func (e ReplyError) Prefix() string {
+	if idx := strings.IndexByte(string(e), ' '); idx != -1 {
+		return string(e[:idx])
+	}
+	return string(e)
+}
+
+// Unmarshaler is implemented by types that can decode themselves from RESP.
+//
+// UnmarshalRESP must read exactly one value from the given Reader, including any nested values.
+type Unmarshaler interface {
+	UnmarshalRESP(r *Reader) error
+}
+
+// UnmarshalTypeError is returned by Decode and Unmarshal when a value can not be decoded into a specific Go type.
+type UnmarshalTypeError struct {
+	Type   Type
+	GoType reflect.Type
+}
+
+// Error implements the error interface.
+func (e *UnmarshalTypeError) Error() string {
+	return "can not decode RESP type " + strconv.Quote(e.Type.String()) + " into Go value of type " + e.GoType.String()
+}
+
+// Unmarshal decodes the single RESP value in data into the value pointed to by v.
+//
+// See Reader.Decode for details on how values are decoded.
+func Unmarshal(data []byte, v interface{}) error {
+	r := NewReader(bytes.NewReader(data))
+	if err := r.Decode(v); err != nil {
+		return err
+	}
+	if _, err := r.Peek(); err != io.EOF {
+		return ErrTrailingData
+	}
+	return nil
+}
+
+// Decode reads the next value and stores it in the value pointed to by v.
+//
+// Values are decoded as follows:
+//
+//   - Types implementing Unmarshaler are decoded by calling their UnmarshalRESP method
+//   - Simple strings, bulk strings, verbatim strings and big numbers can be decoded into strings and byte slices and
+//     are parsed when decoding into booleans, integers or floats. The format prefix of verbatim strings is removed
+//   - Integers, doubles and booleans can be decoded into numeric types, booleans and strings
+//   - Arrays, sets and push messages can be decoded into slices and arrays
+//   - Maps and arrays with an even number of elements (for example the reply of HGETALL using RESP2) can be decoded
+//     into maps and structs, using the same field names as Writer.Encode
+//   - Nulls set pointers, interfaces, maps and slices to nil and leave other values unchanged
+//   - Attributes are skipped
+//
+// When decoding into an empty interface, strings are stored as string, integers as int64, doubles as float64,
+// arrays as []interface{} and maps as map[string]interface{}.
+//
+// If the value, or any nested value, is an error, Decode returns the error as ReplyError. Similarly if a value can
+// not be decoded into the given Go type an *UnmarshalTypeError is returned. In both cases Decode still consumes the
+// complete value so that the next value can be read.
+func (rr *Reader) Decode(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return ErrInvalidDecodeTarget
+	}
+
+	d := decoder{rr: rr}
+	if err := d.value(rv); err != nil {
+		return err
+	}
+	return d.savedErr
+}
+
+type decoder struct {
+	rr  *Reader
+	buf []byte
+
+	// savedErr holds the first non-fatal error (ReplyError or *UnmarshalTypeError) encountered while decoding.
+	savedErr error
+}
+
+func (d *decoder) saveError(err error) {
+	if d.savedErr == nil {
+		d.savedErr = err
+	}
+}
+
+func (d *decoder) typeError(t Type, v reflect.Value) {
+	d.saveError(&UnmarshalTypeError{Type: t, GoType: v.Type()})
+}
+
+var unmarshalerType = reflect.TypeOf((*Unmarshaler)(nil)).Elem()
+
+// indirect walks down v, allocating pointers as needed, until it reaches a non-pointer or an Unmarshaler.
+func indirect(v reflect.Value) (Unmarshaler, reflect.Value) {
+	for {
+		if v.Kind() == reflect.Interface && !v.IsNil() {
+			e := v.Elem()
+			if e.Kind() == reflect.Ptr && !e.IsNil() {
+				v = e
+				continue
+			}
+		}
+
+		if v.Kind() != reflect.Ptr {
+			if v.CanAddr() && reflect.PtrTo(v.Type()).Implements(unmarshalerType) {
+				return v.Addr().Interface().(Unmarshaler), reflect.Value{}
+			}
+			return nil, v
+		}
+
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+
+		if v.Type().Implements(unmarshalerType) {
+			return v.Interface().(Unmarshaler), reflect.Value{}
+		}
+
+		v = v.Elem()
+	}
+}
+
+// peekNull returns true if the next value is a RESP3 null or a RESP2 null bulk string or null array.
+func (rr *Reader) peekNull() (bool, error) {
+	t, err := rr.Peek()
+	if err != nil {
+		return false, err
+	}
+	switch t {
+	case TypeNull:
+		return true, nil
+	case TypeArray, TypeBulkString:
+		b, err := rr.br.Peek(2)
+		if err != nil {
+			return false, err
+		}
+		return b[1] == '-', nil
+	default:
+		return false, nil
+	}
+}
+
+func (d *decoder) value(v reflect.Value) error {
+	t, err := d.rr.Peek()
+	for err == nil && t == TypeAttribute {
+		var n int
+		if n, err = d.rr.ReadAttributeHeader(); err == nil {
+			if err = d.discard(n * 2); err == nil {
+				t, err = d.rr.Peek()
+				err = d.rr.broken(err)
+			}
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	null, err := d.rr.peekNull()
+	if err != nil {
+		return err
+	}
+
+	if null {
+		if err := d.rr.discardValue(); err != nil {
+			return err
+		}
+		// v is always a pointer to the target value
+		switch e := v.Elem(); e.Kind() {
+		case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice:
+			e.Set(reflect.Zero(e.Type()))
+		}
+		return nil
+	}
+
+	u, v := indirect(v)
+	if u != nil {
+		return u.UnmarshalRESP(d.rr)
+	}
+
+	switch t {
+	case TypeError, TypeBlobError:
+		var b []byte
+		if t == TypeError {
+			b, err = d.rr.ReadError(d.buf[:0])
+		} else {
+			b, err = d.rr.ReadBlobError(d.buf[:0])
+		}
+		if err != nil {
+			return err
+		}
+		d.buf = b
+		d.saveError(ReplyError(b))
+		return nil
+	case TypeSimpleString, TypeBulkString, TypeBigNumber, TypeVerbatimString:
+		var b []byte
+		switch t {
+		case TypeSimpleString:
+			b, err = d.rr.ReadSimpleString(d.buf[:0])
+		case TypeBulkString:
+			b, err = d.rr.ReadBulkString(d.buf[:0])
+		case TypeBigNumber:
+			b, err = d.rr.ReadBigNumber(d.buf[:0])
+		case TypeVerbatimString:
+			b, err = d.rr.ReadVerbatimString(d.buf[:0])
+		}
+		if err != nil {
+			return err
+		}
+		d.buf = b
+		if t == TypeVerbatimString {
+			b = b[4:]
+		}
+		d.literal(t, b, v)
+		return nil
+	case TypeInteger:
+		n, err := d.rr.ReadInteger()
+		if err != nil {
+			return err
+		}
+		d.integer(t, int64(n), v)
+		return nil
+	case TypeDouble:
+		f, err := d.rr.ReadDouble()
+		if err != nil {
+			return err
+		}
+		d.double(f, v)
+		return nil
+	case TypeBoolean:
+		b, err := d.rr.ReadBoolean()
+		if err != nil {
+			return err
+		}
+		if b {
+			d.integer(t, 1, v)
+		} else {
+			d.integer(t, 0, v)
+		}
+		return nil
+	case TypeArray, TypeSet, TypePush:
+		var n int
+		switch t {
+		case TypeArray:
+			n, err = d.rr.ReadArrayHeader()
+		case TypeSet:
+			n, err = d.rr.ReadSetHeader()
+		case TypePush:
+			n, err = d.rr.ReadPushHeader()
+		}
+		if err != nil {
+			return err
+		}
+		return d.array(t, n, v)
+	case TypeMap:
+		n, err := d.rr.ReadMapHeader()
+		if err != nil {
+			return err
+		}
+		return d.mapping(t, n, v)
+	default:
+		return ErrUnexpectedType
+	}
+}
+
+func (d *decoder) literal(t Type, b []byte, v reflect.Value) {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(string(b))
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.Uint8 {
+			d.typeError(t, v)
+			return
+		}
+		v.SetBytes(append(v.Bytes()[:0], b...))
+	case reflect.Bool:
+		x, err := strconv.ParseBool(string(b))
+		if err != nil {
+			d.typeError(t, v)
+			return
+		}
+		v.SetBool(x)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		x, err := strconv.ParseInt(string(b), 10, 64)
+		if err != nil || v.OverflowInt(x) {
+			d.typeError(t, v)
+			return
+		}
+		v.SetInt(x)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		x, err := strconv.ParseUint(string(b), 10, 64)
+		if err != nil || v.OverflowUint(x) {
+			d.typeError(t, v)
+			return
+		}
+		v.SetUint(x)
+	case reflect.Float32, reflect.Float64:
+		x, err := parseDouble(b)
+		if err != nil || v.OverflowFloat(x) {
+			d.typeError(t, v)
+			return
+		}
+		v.SetFloat(x)
+	case reflect.Interface:
+		if v.NumMethod() != 0 {
+			d.typeError(t, v)
+			return
+		}
+		v.Set(reflect.ValueOf(string(b)))
+	default:
+		d.typeError(t, v)
+	}
+}
+
+func (d *decoder) integer(t Type, n int64, v reflect.Value) {
+	switch v.Kind() {
+	case reflect.String:
+		if t == TypeBoolean {
+			v.SetString(strconv.FormatBool(n != 0))
+		} else {
+			v.SetString(strconv.FormatInt(n, 10))
+		}
+	case reflect.Bool:
+		v.SetBool(n != 0)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.OverflowInt(n) {
+			d.typeError(t, v)
+			return
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if n < 0 || v.OverflowUint(uint64(n)) {
+			d.typeError(t, v)
+			return
+		}
+		v.SetUint(uint64(n))
+	case reflect.Float32, reflect.Float64:
+		v.SetFloat(float64(n))
+	case reflect.Interface:
+		if v.NumMethod() != 0 {
+			d.typeError(t, v)
+			return
+		}
+		if t == TypeBoolean {
+			v.Set(reflect.ValueOf(n != 0))
+		} else {
+			v.Set(reflect.ValueOf(n))
+		}
+	default:
+		d.typeError(t, v)
+	}
+}
+
+func (d *decoder) double(f float64, v reflect.Value) {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(strconv.FormatFloat(f, 'g', -1, 64))
+	case reflect.Float32, reflect.Float64:
+		if v.OverflowFloat(f) {
+			d.typeError(TypeDouble, v)
+			return
+		}
+		v.SetFloat(f)
+	case reflect.Interface:
+		if v.NumMethod() != 0 {
+			d.typeError(TypeDouble, v)
+			return
+		}
+		v.Set(reflect.ValueOf(f))
+	default:
+		d.typeError(TypeDouble, v)
+	}
+}
+
+func (d *decoder) discard(n int) error {
+	for i := 0; i < n; i++ {
+		if err := d.rr.discardValue(); err != nil {
+			return d.rr.broken(err)
+		}
+	}
+	return nil
+}
+
+func (d *decoder) array(t Type, n int, v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			d.typeError(t, v)
+			return d.discard(n)
+		}
+		if v.Cap() >= n {
+			v.SetLen(n)
+			for i := 0; i < n; i++ {
+				v.Index(i).Set(reflect.Zero(v.Type().Elem()))
+			}
+		} else {
+			v.Set(reflect.MakeSlice(v.Type(), 0, preallocSize(n, maxPreallocElems)))
+		}
+		for i := 0; i < n; i++ {
+			if i == v.Len() {
+				v.Set(reflect.Append(v, reflect.Zero(v.Type().Elem())))
+			}
+			if err := d.value(v.Index(i).Addr()); err != nil {
+				return d.rr.broken(err)
+			}
+		}
+		return nil
+	case reflect.Array:
+		for i := 0; i < n; i++ {
+			if i >= v.Len() {
+				if err := d.rr.discardValue(); err != nil {
+					return d.rr.broken(err)
+				}
+				continue
+			}
+			if err := d.value(v.Index(i).Addr()); err != nil {
+				return d.rr.broken(err)
+			}
+		}
+		for i := n; i < v.Len(); i++ {
+			v.Index(i).Set(reflect.Zero(v.Type().Elem()))
+		}
+		return nil
+	case reflect.Interface:
+		if v.NumMethod() != 0 {
+			d.typeError(t, v)
+			return d.discard(n)
+		}
+		s := make([]interface{}, 0, preallocSize(n, maxPreallocElems))
+		for i := 0; i < n; i++ {
+			var elem interface{}
+			if err := d.value(reflect.ValueOf(&elem)); err != nil {
+				return d.rr.broken(err)
+			}
+			s = append(s, elem)
+		}
+		v.Set(reflect.ValueOf(s))
+		return nil
+	case reflect.Map, reflect.Struct:
+		if n%2 != 0 {
+			d.typeError(t, v)
+			return d.discard(n)
+		}
+		return d.mapping(t, n/2, v)
+	default:
+		d.typeError(t, v)
+		return d.discard(n)
+	}
+}
+
+func (d *decoder) mapping(t Type, n int, v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Map:
+		mt := v.Type()
+		if v.IsNil() {
+			v.Set(reflect.MakeMapWithSize(mt, preallocSize(n, maxPreallocElems)))
+		}
+		for i := 0; i < n; i++ {
+			key := reflect.New(mt.Key())
+			if err := d.value(key); err != nil {
+				return d.rr.broken(err)
+			}
+			elem := reflect.New(mt.Elem())
+			if err := d.value(elem); err != nil {
+				return d.rr.broken(err)
+			}
+			v.SetMapIndex(key.Elem(), elem.Elem())
+		}
+		return nil
+	case reflect.Struct:
+		fields := cachedFields(v.Type())
+		for i := 0; i < n; i++ {
+			var name string
+			if err := d.value(reflect.ValueOf(&name)); err != nil {
+				return d.rr.broken(err)
+			}
+			f := lookupField(fields, name)
+			if f == nil {
+				if err := d.rr.discardValue(); err != nil {
+					return d.rr.broken(err)
+				}
+				continue
+			}
+			fv, ok := fieldByIndexAlloc(v, f.index)
+			if !ok {
+				if err := d.rr.discardValue(); err != nil {
+					return d.rr.broken(err)
+				}
+				continue
+			}
+			if err := d.value(fv.Addr()); err != nil {
+				return d.rr.broken(err)
+			}
+		}
+		return nil
+	case reflect.Interface:
+		if v.NumMethod() != 0 {
+			d.typeError(t, v)
+			return d.discard(n * 2)
+		}
+		m := make(map[string]interface{}, preallocSize(n, maxPreallocElems))
+		for i := 0; i < n; i++ {
+			var key, elem interface{}
+			if err := d.value(reflect.ValueOf(&key)); err != nil {
+				return d.rr.broken(err)
+			}
+			if err := d.value(reflect.ValueOf(&elem)); err != nil {
+				return d.rr.broken(err)
+			}
+			switch k := key.(type) {
+			case string:
+				m[k] = elem
+			case int64:
+				m[strconv.FormatInt(k, 10)] = elem
+			default:
+				d.typeError(t, v)
+			}
+		}
+		v.Set(reflect.ValueOf(m))
+		return nil
+	default:
+		d.typeError(t, v)
+		return d.discard(n * 2)
+	}
+}
+
+func lookupField(fields []field, name string) *field {
+	for i := range fields {
+		if fields[i].name == name {
+			return &fields[i]
+		}
+	}
+	for i := range fields {
+		if strings.EqualFold(fields[i].name, name) {
+			return &fields[i]
+		}
+	}
+	return nil
+}
+
+// fieldByIndexAlloc returns the field at the given index, allocating nil embedded pointers as needed.
+func fieldByIndexAlloc(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return reflect.Value{}, false
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, v.CanSet()
+}
+
+// discardValue reads and discards the next value, including all nested values.
+func (rr *Reader) discardValue() error {
+	t, err := rr.Peek()
+	if err != nil {
+		return err
+	}
+
+	var n int
+
+	switch t {
+	case TypeArray:
+		n, err = rr.ReadArrayHeader()
+	case TypeSet:
+		n, err = rr.ReadSetHeader()
+	case TypePush:
+		n, err = rr.ReadPushHeader()
+	case TypeMap:
+		n, err = rr.ReadMapHeader()
+		n *= 2
+	case TypeAttribute:
+		n, err = rr.ReadAttributeHeader()
+		// the attribute is followed by the value it describes
+		n = n*2 + 1
+	case TypeBulkString:
+		rr.buf, err = rr.ReadBulkString(rr.buf[:0])
+	case TypeBlobError:
+		rr.buf, err = rr.ReadBlobError(rr.buf[:0])
+	case TypeVerbatimString:
+		rr.buf, err = rr.ReadVerbatimString(rr.buf[:0])
+	case TypeSimpleString:
+		rr.buf, err = rr.ReadSimpleString(rr.buf[:0])
+	case TypeError:
+		rr.buf, err = rr.ReadError(rr.buf[:0])
+	case TypeBigNumber:
+		rr.buf, err = rr.ReadBigNumber(rr.buf[:0])
+	case TypeInteger:
+		_, err = rr.ReadInteger()
+	case TypeDouble:
+		_, err = rr.ReadDouble()
+	case TypeBoolean:
+		_, err = rr.ReadBoolean()
+	case TypeNull:
+		err = rr.ReadNull()
+	default:
+		return ErrUnexpectedType
+	}
+
+	for i := 0; err == nil && i < n; i++ {
+		err = rr.broken(rr.discardValue())
+	}
+	return err
+}
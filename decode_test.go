@@ -0,0 +1,283 @@
+package resp_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/nussjustin/resp"
+)
+
+type decodeUser struct {
+	ID       int               `resp:"id"`
+	Name     string            `resp:"name"`
+	Email    *string           `resp:"email"`
+	Score    float64           `resp:"score"`
+	Active   bool              `resp:"active"`
+	Tags     []string          `resp:"tags"`
+	Meta     map[string]string `resp:"meta"`
+	Ignored  string            `resp:"-"`
+	Optional string
+}
+
+type decodeUpper string
+
+func (u *decodeUpper) UnmarshalRESP(r *resp.Reader) error {
+	s, err := r.ReadSimpleString(nil)
+	if err != nil {
+		return err
+	}
+	*u = decodeUpper(strings.ToUpper(string(s)))
+	return nil
+}
+
+func TestUnmarshal(t *testing.T) {
+	email := "user@example.com"
+
+	for _, test := range []struct {
+		Name     string
+		In       string
+		New      func() interface{}
+		Expected interface{}
+	}{
+		{
+			Name:     "bulk string into string",
+			In:       "$5\r\nhello\r\n",
+			New:      func() interface{} { return new(string) },
+			Expected: "hello",
+		},
+		{
+			Name:     "simple string into bytes",
+			In:       "+OK\r\n",
+			New:      func() interface{} { return new([]byte) },
+			Expected: []byte("OK"),
+		},
+		{
+			Name:     "verbatim string into string",
+			In:       "=15\r\ntxt:Some string\r\n",
+			New:      func() interface{} { return new(string) },
+			Expected: "Some string",
+		},
+		{
+			Name:     "bulk string into int",
+			In:       "$3\r\n-12\r\n",
+			New:      func() interface{} { return new(int) },
+			Expected: -12,
+		},
+		{
+			Name:     "bulk string into float",
+			In:       "$4\r\n1.25\r\n",
+			New:      func() interface{} { return new(float64) },
+			Expected: 1.25,
+		},
+		{
+			Name:     "bulk string into bool",
+			In:       "$1\r\n1\r\n",
+			New:      func() interface{} { return new(bool) },
+			Expected: true,
+		},
+		{
+			Name:     "integer into uint16",
+			In:       ":65535\r\n",
+			New:      func() interface{} { return new(uint16) },
+			Expected: uint16(65535),
+		},
+		{
+			Name:     "integer into string",
+			In:       ":42\r\n",
+			New:      func() interface{} { return new(string) },
+			Expected: "42",
+		},
+		{
+			Name:     "double into float32",
+			In:       ",1.5\r\n",
+			New:      func() interface{} { return new(float32) },
+			Expected: float32(1.5),
+		},
+		{
+			Name:     "boolean into bool",
+			In:       "#t\r\n",
+			New:      func() interface{} { return new(bool) },
+			Expected: true,
+		},
+		{
+			Name:     "null bulk string into string",
+			In:       "$-1\r\n",
+			New:      func() interface{} { s := "x"; return &s },
+			Expected: "x",
+		},
+		{
+			Name:     "null into slice",
+			In:       "_\r\n",
+			New:      func() interface{} { s := []int{1}; return &s },
+			Expected: []int(nil),
+		},
+		{
+			Name:     "array into slice",
+			In:       "*3\r\n:1\r\n$1\r\n2\r\n:3\r\n",
+			New:      func() interface{} { return new([]int) },
+			Expected: []int{1, 2, 3},
+		},
+		{
+			Name:     "set into array",
+			In:       "~3\r\n:1\r\n:2\r\n:3\r\n",
+			New:      func() interface{} { return new([2]int) },
+			Expected: [2]int{1, 2},
+		},
+		{
+			Name:     "flat array into map",
+			In:       "*4\r\n$1\r\na\r\n$1\r\n1\r\n$1\r\nb\r\n$1\r\n2\r\n",
+			New:      func() interface{} { return new(map[string]int) },
+			Expected: map[string]int{"a": 1, "b": 2},
+		},
+		{
+			Name:     "map into map",
+			In:       "%2\r\n:1\r\n+a\r\n:2\r\n+b\r\n",
+			New:      func() interface{} { return new(map[int]string) },
+			Expected: map[int]string{1: "a", 2: "b"},
+		},
+		{
+			Name: "flat array into struct",
+			In: "*20\r\n" +
+				"$2\r\nid\r\n$1\r\n5\r\n" +
+				"$4\r\nname\r\n$4\r\nuser\r\n" +
+				"$5\r\nemail\r\n$16\r\nuser@example.com\r\n" +
+				"$5\r\nscore\r\n$3\r\n1.5\r\n" +
+				"$6\r\nactive\r\n$1\r\n1\r\n" +
+				"$4\r\ntags\r\n*2\r\n$1\r\na\r\n$1\r\nb\r\n" +
+				"$4\r\nmeta\r\n*2\r\n$1\r\nk\r\n$1\r\nv\r\n" +
+				"$7\r\nIgnored\r\n$1\r\nx\r\n" +
+				"$8\r\noptional\r\n$1\r\ny\r\n" +
+				"$7\r\nunknown\r\n*1\r\n:1\r\n",
+			New: func() interface{} { return new(decodeUser) },
+			Expected: decodeUser{
+				ID:       5,
+				Name:     "user",
+				Email:    &email,
+				Score:    1.5,
+				Active:   true,
+				Tags:     []string{"a", "b"},
+				Meta:     map[string]string{"k": "v"},
+				Optional: "y",
+			},
+		},
+		{
+			Name: "map into struct",
+			In:   "%3\r\n+id\r\n:5\r\n+active\r\n#t\r\n+score\r\n,2.5\r\n",
+			New:  func() interface{} { return new(decodeUser) },
+			Expected: decodeUser{
+				ID:     5,
+				Score:  2.5,
+				Active: true,
+			},
+		},
+		{
+			Name:     "array into interface",
+			In:       "*5\r\n$1\r\na\r\n:1\r\n,1.5\r\n#f\r\n%1\r\n+k\r\n_\r\n",
+			New:      func() interface{} { return new(interface{}) },
+			Expected: []interface{}{"a", int64(1), 1.5, false, map[string]interface{}{"k": nil}},
+		},
+		{
+			Name:     "attribute is skipped",
+			In:       "|1\r\n+key-popularity\r\n%1\r\n$1\r\na\r\n,0.1923\r\n:5\r\n",
+			New:      func() interface{} { return new(int) },
+			Expected: 5,
+		},
+		{
+			Name:     "unmarshaler",
+			In:       "*2\r\n+hello\r\n+world\r\n",
+			New:      func() interface{} { return new([]decodeUpper) },
+			Expected: []decodeUpper{"HELLO", "WORLD"},
+		},
+	} {
+		test := test
+
+		t.Run(test.Name, func(t *testing.T) {
+			v := test.New()
+			if err := resp.Unmarshal([]byte(test.In), v); err != nil {
+				t.Fatalf("failed to unmarshal: %s", err)
+			}
+			if got := reflect.ValueOf(v).Elem().Interface(); !reflect.DeepEqual(got, test.Expected) {
+				t.Errorf("got %#v, expected %#v", got, test.Expected)
+			}
+		})
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	var s string
+	if err := resp.Unmarshal([]byte("+OK\r\n"), s); err != resp.ErrInvalidDecodeTarget {
+		t.Errorf("got error %v, expected %v", err, resp.ErrInvalidDecodeTarget)
+	}
+
+	if err := resp.Unmarshal([]byte("+OK\r\n+OK\r\n"), &s); err != resp.ErrTrailingData {
+		t.Errorf("got error %v, expected %v", err, resp.ErrTrailingData)
+	}
+
+	var n int
+	if err := resp.Unmarshal([]byte("$3\r\nabc\r\n"), &n); err == nil {
+		t.Error("expected error when decoding non-numeric string into int")
+	} else if _, ok := err.(*resp.UnmarshalTypeError); !ok {
+		t.Errorf("got error %#v, expected *resp.UnmarshalTypeError", err)
+	}
+
+	if err := resp.Unmarshal([]byte(":300\r\n"), new(int8)); err == nil {
+		t.Error("expected error when decoding overflowing integer")
+	}
+}
+
+func TestReaderDecodeReplyError(t *testing.T) {
+	r := resp.NewReader(strings.NewReader("-WRONGTYPE wrong kind of value\r\n" +
+		"*3\r\n:1\r\n!9\r\nERR nope!\r\n:3\r\n" +
+		"+OK\r\n"))
+
+	var s string
+	err := r.Decode(&s)
+	if rerr, ok := err.(resp.ReplyError); !ok {
+		t.Fatalf("got error %#v, expected resp.ReplyError", err)
+	} else if rerr.Prefix() != "WRONGTYPE" {
+		t.Errorf("got prefix %q, expected %q", rerr.Prefix(), "WRONGTYPE")
+	}
+
+	var ns []int
+	if err := r.Decode(&ns); err != resp.ReplyError("ERR nope!") {
+		t.Errorf("got error %v, expected %q", err, "ERR nope!")
+	}
+	if !reflect.DeepEqual(ns, []int{1, 0, 3}) {
+		t.Errorf("got %v, expected %v", ns, []int{1, 0, 3})
+	}
+
+	// the stream must still be usable after errors
+	if err := r.Decode(&s); err != nil {
+		t.Fatalf("failed to decode: %s", err)
+	} else if s != "OK" {
+		t.Errorf("got %q, expected %q", s, "OK")
+	}
+}
+
+func TestMarshalUnmarshalRoundTrip(t *testing.T) {
+	email := "user@example.com"
+	in := decodeUser{
+		ID:     1,
+		Name:   "user",
+		Email:  &email,
+		Score:  0.5,
+		Active: true,
+		Tags:   []string{"a"},
+		Meta:   map[string]string{"k": "v"},
+	}
+
+	b, err := resp.Marshal(in)
+	if err != nil {
+		t.Fatalf("failed to marshal: %s", err)
+	}
+
+	var out decodeUser
+	if err := resp.Unmarshal(b, &out); err != nil {
+		t.Fatalf("failed to unmarshal: %s", err)
+	}
+
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("got %#v, expected %#v", out, in)
+	}
+}
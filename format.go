@@ -0,0 +1,243 @@
+package resp
+
+import (
+	"io"
+	"strconv"
+)
+
+// FormatOptions configures the output of Format.
+type FormatOptions struct {
+	// Raw formats values like redis-cli does when called with --raw or when the output is not a terminal.
+	//
+	// In raw mode strings are written without quotes or escaping, type information and indices are omitted and
+	// elements of aggregates are written on separate lines.
+	Raw bool
+}
+
+// Format writes a human-readable representation of the value v to w, in the same format used by redis-cli.
+//
+// By default values are formatted the way redis-cli formats replies for a terminal. For example strings are quoted,
+// integers are written as (integer) 5, null values as (nil) and elements of arrays are numbered and written on
+// separate lines, with nested aggregates indented below their index.
+//
+// The output always ends with a newline. If opts is nil, the default options are used.
+//
+// If the type of the value or of a nested value is unknown, ErrUnexpectedType is returned and nothing is written.
+func Format(w io.Writer, v Value, opts *FormatOptions) error {
+	var b []byte
+	var err error
+
+	if opts != nil && opts.Raw {
+		b, err = appendFormatRaw(nil, v)
+		b = append(b, '\n')
+	} else {
+		b, err = appendFormat(nil, v, "")
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(b)
+	return err
+}
+
+// appendQuoted appends s quoted and escaped like the sdscatrepr function used by redis-cli.
+func appendQuoted(dst []byte, s []byte) []byte {
+	const hex = "0123456789abcdef"
+
+	dst = append(dst, '"')
+	for _, c := range s {
+		switch c {
+		case '\\', '"':
+			dst = append(dst, '\\', c)
+		case '\n':
+			dst = append(dst, '\\', 'n')
+		case '\r':
+			dst = append(dst, '\\', 'r')
+		case '\t':
+			dst = append(dst, '\\', 't')
+		case '\a':
+			dst = append(dst, '\\', 'a')
+		case '\b':
+			dst = append(dst, '\\', 'b')
+		default:
+			if c < ' ' || c > '~' {
+				dst = append(dst, '\\', 'x', hex[c>>4], hex[c&0xf])
+			} else {
+				dst = append(dst, c)
+			}
+		}
+	}
+	return append(dst, '"')
+}
+
+// appendDoubleString appends the string representation of a double, preferring the original representation.
+func appendDoubleString(dst []byte, v Value) []byte {
+	if v.Str != nil {
+		return append(dst, v.Str...)
+	}
+	b := AppendDouble(nil, v.Float)
+	return append(dst, b[1:len(b)-2]...)
+}
+
+// verbatimText returns the content of a verbatim string without the format prefix.
+func verbatimText(v Value) []byte {
+	if len(v.Str) >= 4 && v.Str[3] == ':' {
+		return v.Str[4:]
+	}
+	return v.Str
+}
+
+// appendFormat appends v formatted like redis-cli does for terminals, using prefix for indenting nested lines.
+func appendFormat(dst []byte, v Value, prefix string) ([]byte, error) {
+	switch v.Type {
+	case TypeArray, TypeSet, TypePush, TypeMap, TypeAttribute:
+		if v.Null {
+			return append(dst, "(nil)\n"...), nil
+		}
+		if len(v.Elems) == 0 {
+			switch v.Type {
+			case TypeArray:
+				return append(dst, "(empty array)\n"...), nil
+			case TypeSet:
+				return append(dst, "(empty set)\n"...), nil
+			case TypePush:
+				return append(dst, "(empty push)\n"...), nil
+			default:
+				return append(dst, "(empty hash)\n"...), nil
+			}
+		}
+
+		pairs := v.Type == TypeMap || v.Type == TypeAttribute
+
+		n := len(v.Elems)
+		if pairs {
+			n /= 2
+		}
+		width := len(strconv.Itoa(n))
+
+		sep := byte(')')
+		switch {
+		case v.Type == TypeSet:
+			sep = '~'
+		case pairs:
+			sep = '#'
+		}
+
+		// nested lines are indented by the width of the index and the separator
+		nested := prefix
+		for i := 0; i < width+2; i++ {
+			nested += " "
+		}
+
+		for i := 0; i < n; i++ {
+			// the first index directly follows the index of the parent, if any
+			if i > 0 {
+				dst = append(dst, prefix...)
+			}
+			idx := strconv.Itoa(i + 1)
+			for j := len(idx); j < width; j++ {
+				dst = append(dst, ' ')
+			}
+			dst = append(dst, idx...)
+			dst = append(dst, sep, ' ')
+
+			var err error
+			if !pairs {
+				if dst, err = appendFormat(dst, v.Elems[i], nested); err != nil {
+					return dst, err
+				}
+				continue
+			}
+
+			if dst, err = appendFormat(dst, v.Elems[2*i], nested); err != nil {
+				return dst, err
+			}
+			dst = append(dst[:len(dst)-1], " => "...)
+			if dst, err = appendFormat(dst, v.Elems[2*i+1], nested); err != nil {
+				return dst, err
+			}
+		}
+		return dst, nil
+	case TypeBulkString:
+		if v.Null {
+			return append(dst, "(nil)\n"...), nil
+		}
+		dst = appendQuoted(dst, v.Str)
+	case TypeVerbatimString:
+		dst = append(dst, verbatimText(v)...)
+	case TypeSimpleString:
+		dst = append(dst, v.Str...)
+	case TypeError, TypeBlobError:
+		dst = append(dst, "(error) "...)
+		dst = append(dst, v.Str...)
+	case TypeBigNumber:
+		dst = append(dst, "(big number) "...)
+		dst = append(dst, v.Str...)
+	case TypeInteger:
+		dst = append(dst, "(integer) "...)
+		dst = strconv.AppendInt(dst, int64(v.Int), 10)
+	case TypeDouble:
+		dst = append(dst, "(double) "...)
+		dst = appendDoubleString(dst, v)
+	case TypeBoolean:
+		if v.Bool {
+			dst = append(dst, "(true)"...)
+		} else {
+			dst = append(dst, "(false)"...)
+		}
+	case TypeNull:
+		dst = append(dst, "(nil)"...)
+	default:
+		return dst, ErrUnexpectedType
+	}
+	return append(dst, '\n'), nil
+}
+
+// appendFormatRaw appends v formatted like redis-cli does in raw mode, without a trailing newline.
+func appendFormatRaw(dst []byte, v Value) ([]byte, error) {
+	switch v.Type {
+	case TypeArray, TypeSet, TypePush:
+		for i, e := range v.Elems {
+			if i > 0 {
+				dst = append(dst, '\n')
+			}
+			var err error
+			if dst, err = appendFormatRaw(dst, e); err != nil {
+				return dst, err
+			}
+		}
+	case TypeMap, TypeAttribute:
+		for i := 0; i+1 < len(v.Elems); i += 2 {
+			if i > 0 {
+				dst = append(dst, '\n')
+			}
+			var err error
+			if dst, err = appendFormatRaw(dst, v.Elems[i]); err != nil {
+				return dst, err
+			}
+			dst = append(dst, ' ')
+			if dst, err = appendFormatRaw(dst, v.Elems[i+1]); err != nil {
+				return dst, err
+			}
+		}
+	case TypeBulkString, TypeSimpleString, TypeError, TypeBlobError, TypeBigNumber:
+		dst = append(dst, v.Str...)
+	case TypeVerbatimString:
+		dst = append(dst, verbatimText(v)...)
+	case TypeInteger:
+		dst = strconv.AppendInt(dst, int64(v.Int), 10)
+	case TypeDouble:
+		dst = appendDoubleString(dst, v)
+	case TypeBoolean:
+		if v.Bool {
+			dst = append(dst, "(true)"...)
+		} else {
+			dst = append(dst, "(false)"...)
+		}
+	case TypeNull:
+	default:
+		return dst, ErrUnexpectedType
+	}
+	return dst, nil
+}
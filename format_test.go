@@ -0,0 +1,135 @@
+package resp_test
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/nussjustin/resp"
+)
+
+func TestFormat(t *testing.T) {
+	elems := make([]resp.Value, 10)
+	for i := range elems {
+		elems[i] = resp.Integer(i)
+	}
+
+	for _, test := range []struct {
+		Name     string
+		In       resp.Value
+		Expected string
+		Raw      string
+	}{
+		{Name: "simple string", In: resp.SimpleString("OK"), Expected: "OK\n", Raw: "OK\n"},
+		{Name: "error", In: resp.Error("ERR failed"), Expected: "(error) ERR failed\n", Raw: "ERR failed\n"},
+		{Name: "blob error", In: resp.BlobError("ERR failed"), Expected: "(error) ERR failed\n", Raw: "ERR failed\n"},
+		{Name: "integer", In: resp.Integer(-5), Expected: "(integer) -5\n", Raw: "-5\n"},
+		{Name: "bulk string", In: resp.BulkString("foo"), Expected: "\"foo\"\n", Raw: "foo\n"},
+		{
+			Name:     "bulk string escaping",
+			In:       resp.BulkString("a\"b\\c\r\n\t\a\b\x00\xff"),
+			Expected: `"a\"b\\c\r\n\t\a\b\x00\xff"` + "\n",
+			Raw:      "a\"b\\c\r\n\t\a\b\x00\xff\n",
+		},
+		{Name: "null bulk string", In: resp.NullBulkString(), Expected: "(nil)\n", Raw: "\n"},
+		{Name: "null array", In: resp.NullArray(), Expected: "(nil)\n", Raw: "\n"},
+		{Name: "null", In: resp.Null(), Expected: "(nil)\n", Raw: "\n"},
+		{Name: "boolean", In: resp.Boolean(true), Expected: "(true)\n", Raw: "(true)\n"},
+		{Name: "double", In: resp.Double(1.5), Expected: "(double) 1.5\n", Raw: "1.5\n"},
+		{Name: "double inf", In: resp.Double(math.Inf(-1)), Expected: "(double) -inf\n", Raw: "-inf\n"},
+		{
+			Name:     "double with representation",
+			In:       resp.Value{Type: resp.TypeDouble, Float: 10, Str: []byte("1e1")},
+			Expected: "(double) 1e1\n",
+			Raw:      "1e1\n",
+		},
+		{
+			Name:     "big number",
+			In:       resp.BigNumber("12345678901234567890"),
+			Expected: "(big number) 12345678901234567890\n",
+			Raw:      "12345678901234567890\n",
+		},
+		{
+			Name:     "verbatim string",
+			In:       resp.VerbatimString("txt", "hello\nworld"),
+			Expected: "hello\nworld\n",
+			Raw:      "hello\nworld\n",
+		},
+		{Name: "empty array", In: resp.Array(), Expected: "(empty array)\n", Raw: "\n"},
+		{Name: "empty set", In: resp.Set(), Expected: "(empty set)\n", Raw: "\n"},
+		{Name: "empty map", In: resp.Map(), Expected: "(empty hash)\n", Raw: "\n"},
+		{
+			Name:     "array",
+			In:       resp.Array(resp.BulkString("foo"), resp.Integer(5), resp.NullBulkString()),
+			Expected: "1) \"foo\"\n2) (integer) 5\n3) (nil)\n",
+			Raw:      "foo\n5\n\n",
+		},
+		{
+			Name: "nested array",
+			In: resp.Array(
+				resp.Array(resp.BulkString("a"), resp.Array(resp.BulkString("b"), resp.BulkString("c"))),
+				resp.Integer(1),
+			),
+			Expected: "1) 1) \"a\"\n   2) 1) \"b\"\n      2) \"c\"\n2) (integer) 1\n",
+			Raw:      "a\nb\nc\n1\n",
+		},
+		{
+			Name: "wide array",
+			In:   resp.Array(append(elems[:9:9], resp.Array(resp.SimpleString("a"), resp.SimpleString("b")))...),
+			Expected: " 1) (integer) 0\n 2) (integer) 1\n 3) (integer) 2\n 4) (integer) 3\n 5) (integer) 4\n" +
+				" 6) (integer) 5\n 7) (integer) 6\n 8) (integer) 7\n 9) (integer) 8\n10) 1) a\n    2) b\n",
+			Raw: "0\n1\n2\n3\n4\n5\n6\n7\n8\na\nb\n",
+		},
+		{
+			Name:     "set",
+			In:       resp.Set(resp.BulkString("a"), resp.BulkString("b")),
+			Expected: "1~ \"a\"\n2~ \"b\"\n",
+			Raw:      "a\nb\n",
+		},
+		{
+			Name: "map",
+			In: resp.Map(
+				resp.BulkString("key"), resp.Integer(1),
+				resp.SimpleString("list"), resp.Array(resp.Boolean(true), resp.Null()),
+			),
+			Expected: "1# \"key\" => (integer) 1\n2# list => 1) (true)\n   2) (nil)\n",
+			Raw:      "key 1\nlist (true)\n\n",
+		},
+		{
+			Name:     "push",
+			In:       resp.Push(resp.BulkString("message"), resp.BulkString("hello")),
+			Expected: "1) \"message\"\n2) \"hello\"\n",
+			Raw:      "message\nhello\n",
+		},
+	} {
+		test := test
+
+		t.Run(test.Name, func(t *testing.T) {
+			var sb strings.Builder
+			if err := resp.Format(&sb, test.In, nil); err != nil {
+				t.Fatalf("got error %v", err)
+			}
+			if got := sb.String(); got != test.Expected {
+				t.Errorf("got\n%s\nexpected\n%s", got, test.Expected)
+			}
+
+			sb.Reset()
+			if err := resp.Format(&sb, test.In, &resp.FormatOptions{Raw: true}); err != nil {
+				t.Fatalf("got error %v in raw mode", err)
+			}
+			if got := sb.String(); got != test.Raw {
+				t.Errorf("got %q in raw mode, expected %q", got, test.Raw)
+			}
+		})
+	}
+}
+
+func TestFormatInvalid(t *testing.T) {
+	var sb strings.Builder
+	if err := resp.Format(&sb, resp.Array(resp.Value{}), nil); err != resp.ErrUnexpectedType {
+		t.Errorf("got error %v, expected %v", err, resp.ErrUnexpectedType)
+	}
+	if sb.Len() != 0 {
+		t.Errorf("got output %q, expected no output", sb.String())
+	}
+}
@@ -0,0 +1,485 @@
+package resp_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math"
+	"os"
+	"reflect"
+	"runtime"
+	"testing"
+
+	"github.com/nussjustin/resp"
+)
+
+// fuzzSeeds contains RESP3 values that are not part of the RESP2 only seed file.
+var fuzzSeeds = []string{
+	"#t\r\n",
+	"#f\r\n",
+	",1.5\r\n",
+	",-inf\r\n",
+	",nan\r\n",
+	"_\r\n",
+	"(12345678901234567890\r\n",
+	"!9\r\nERR error\r\n",
+	"=9\r\ntxt:hello\r\n",
+	"%1\r\n+key\r\n:1\r\n",
+	"~2\r\n:1\r\n:2\r\n",
+	">2\r\n+message\r\n$5\r\nhello\r\n",
+	"|1\r\n+ttl\r\n:100\r\n$5\r\nhello\r\n",
+	"*3\r\n$3\r\nSET\r\n$3\r\nkey\r\n$5\r\nvalue\r\n",
+}
+
+// addFuzzSeeds adds the complete seed file, each value in the seed file and the RESP3 seeds to the corpus of f.
+func addFuzzSeeds(f *testing.F) {
+	data, err := os.ReadFile("testdata/TestReadWriter/simple.resp")
+	if err != nil {
+		f.Fatalf("failed to read seed file: %s", err)
+	}
+	f.Add(data)
+
+	r := resp.NewReader(bytes.NewReader(data))
+	for {
+		v, err := r.ReadValue()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			f.Fatalf("failed to read seed value: %s", err)
+		}
+
+		var buf bytes.Buffer
+		if _, err := resp.NewWriter(&buf).WriteValue(v); err != nil {
+			f.Fatalf("failed to write seed value: %s", err)
+		}
+		f.Add(buf.Bytes())
+	}
+
+	for _, s := range fuzzSeeds {
+		f.Add([]byte(s))
+	}
+}
+
+func totalAlloc() uint64 {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	return ms.TotalAlloc
+}
+
+// maxFuzzAlloc is the maximum number of bytes reading any input of size n may allocate.
+func maxFuzzAlloc(n int) uint64 {
+	return 1<<20 + 2048*uint64(n)
+}
+
+// fuzzReader checks that reading a value using fn does not panic, does not allocate an unbounded amount of memory
+// and that each value accepted by the Reader is written back in a form that decodes to the same value as the input.
+func fuzzReader(f *testing.F, fn func(r *resp.Reader, w *resp.Writer) error) {
+	addFuzzSeeds(f)
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		br := bytes.NewReader(data)
+		r := resp.NewReader(br)
+
+		var out bytes.Buffer
+		w := resp.NewWriter(&out)
+
+		before := totalAlloc()
+		err := fn(r, w)
+		if n := totalAlloc() - before; n > maxFuzzAlloc(len(data)) {
+			t.Fatalf("allocated %d bytes for input of %d bytes", n, len(data))
+		}
+		if err != nil {
+			return
+		}
+
+		// numbers can be written in different ways, for example with leading zeros, so compare the decoded input and
+		// output instead of the raw bytes
+		in := data[:len(data)-br.Len()-r.Buffered()]
+
+		inEvents, inErr := decodeEvents(in)
+		outEvents, outErr := decodeEvents(out.Bytes())
+		if inErr != outErr {
+			t.Fatalf("got error %v decoding output %q, expected %v from input %q", outErr, out.Bytes(), inErr, in)
+		}
+		if !equalEvents(inEvents, outEvents) {
+			t.Fatalf("got %#v from output %q, expected %#v from input %q", outEvents, out.Bytes(), inEvents, in)
+		}
+	})
+}
+
+// decodeEvents returns all events read from b using Reader.Next, with parts of blob strings joined into a single
+// event, and the error that stopped reading.
+func decodeEvents(b []byte) ([]resp.Event, error) {
+	r := resp.NewReader(bytes.NewReader(b))
+
+	var evs []resp.Event
+	more := false
+	for {
+		ev, err := r.Next()
+		if err == io.EOF {
+			return evs, nil
+		}
+		if err != nil {
+			return evs, err
+		}
+
+		ev.Str = append([]byte(nil), ev.Str...)
+		if more {
+			last := &evs[len(evs)-1]
+			last.Str = append(last.Str, ev.Str...)
+			last.More = ev.More
+		} else {
+			evs = append(evs, ev)
+		}
+		more = ev.Kind == resp.EventString && ev.More
+	}
+}
+
+// equalEvents returns true if a and b contain the same events, treating NaN doubles as equal.
+func equalEvents(a, b []resp.Event) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		ea, eb := a[i], b[i]
+		if math.IsNaN(ea.Float) && math.IsNaN(eb.Float) {
+			ea.Float, eb.Float = 0, 0
+		}
+		if !bytes.Equal(ea.Str, eb.Str) {
+			return false
+		}
+		ea.Str, eb.Str = nil, nil
+		if !reflect.DeepEqual(ea, eb) {
+			return false
+		}
+	}
+	return true
+}
+
+func fuzzHeader(f *testing.F, read func(*resp.Reader) (int, error), write func(*resp.Writer, int) (int, error)) {
+	fuzzReader(f, func(r *resp.Reader, w *resp.Writer) error {
+		n, err := read(r)
+		if err != nil {
+			return err
+		}
+		_, err = write(w, n)
+		return err
+	})
+}
+
+func fuzzBytes(f *testing.F, read func(*resp.Reader, []byte) ([]byte, error),
+	write func(*resp.Writer, []byte) (int, error)) {
+	fuzzReader(f, func(r *resp.Reader, w *resp.Writer) error {
+		b, err := read(r, nil)
+		if err != nil {
+			return err
+		}
+		_, err = write(w, b)
+		return err
+	})
+}
+
+func FuzzReaderReadArrayHeader(f *testing.F) {
+	fuzzHeader(f, (*resp.Reader).ReadArrayHeader, (*resp.Writer).WriteArrayHeader)
+}
+
+func FuzzReaderReadAttributeHeader(f *testing.F) {
+	fuzzHeader(f, (*resp.Reader).ReadAttributeHeader, (*resp.Writer).WriteAttributeHeader)
+}
+
+func FuzzReaderReadBulkStringHeader(f *testing.F) {
+	fuzzHeader(f, (*resp.Reader).ReadBulkStringHeader, (*resp.Writer).WriteBulkStringHeader)
+}
+
+func FuzzReaderReadInteger(f *testing.F) {
+	fuzzHeader(f, (*resp.Reader).ReadInteger, (*resp.Writer).WriteInteger)
+}
+
+func FuzzReaderReadMapHeader(f *testing.F) {
+	fuzzHeader(f, (*resp.Reader).ReadMapHeader, (*resp.Writer).WriteMapHeader)
+}
+
+func FuzzReaderReadPushHeader(f *testing.F) {
+	fuzzHeader(f, (*resp.Reader).ReadPushHeader, (*resp.Writer).WritePushHeader)
+}
+
+func FuzzReaderReadSetHeader(f *testing.F) {
+	fuzzHeader(f, (*resp.Reader).ReadSetHeader, (*resp.Writer).WriteSetHeader)
+}
+
+func FuzzReaderReadBigNumber(f *testing.F) {
+	fuzzBytes(f, (*resp.Reader).ReadBigNumber, func(w *resp.Writer, b []byte) (int, error) {
+		return w.WriteBigNumber(string(b))
+	})
+}
+
+func FuzzReaderReadBlobError(f *testing.F) {
+	fuzzBytes(f, (*resp.Reader).ReadBlobError, (*resp.Writer).WriteBlobErrorBytes)
+}
+
+func FuzzReaderReadBulkString(f *testing.F) {
+	fuzzBytes(f, (*resp.Reader).ReadBulkString, func(w *resp.Writer, b []byte) (int, error) {
+		if b == nil {
+			return w.WriteBulkStringHeader(-1)
+		}
+		return w.WriteBulkStringBytes(b)
+	})
+}
+
+func FuzzReaderReadError(f *testing.F) {
+	fuzzBytes(f, (*resp.Reader).ReadError, (*resp.Writer).WriteErrorBytes)
+}
+
+func FuzzReaderReadSimpleString(f *testing.F) {
+	fuzzBytes(f, (*resp.Reader).ReadSimpleString, (*resp.Writer).WriteSimpleStringBytes)
+}
+
+func FuzzReaderReadVerbatimString(f *testing.F) {
+	fuzzBytes(f, (*resp.Reader).ReadVerbatimString, func(w *resp.Writer, b []byte) (int, error) {
+		return w.WriteValue(resp.Value{Type: resp.TypeVerbatimString, Str: b})
+	})
+}
+
+func FuzzReaderReadBoolean(f *testing.F) {
+	fuzzReader(f, func(r *resp.Reader, w *resp.Writer) error {
+		b, err := r.ReadBoolean()
+		if err != nil {
+			return err
+		}
+		_, err = w.WriteBoolean(b)
+		return err
+	})
+}
+
+func FuzzReaderReadNull(f *testing.F) {
+	fuzzReader(f, func(r *resp.Reader, w *resp.Writer) error {
+		if err := r.ReadNull(); err != nil {
+			return err
+		}
+		_, err := w.WriteNull()
+		return err
+	})
+}
+
+func FuzzReaderReadCommand(f *testing.F) {
+	var cmd resp.Command
+
+	fuzzReader(f, func(r *resp.Reader, w *resp.Writer) error {
+		if err := r.ReadCommand(&cmd); err != nil {
+			return err
+		}
+		if _, err := w.WriteArrayHeader(len(cmd.Args)); err != nil {
+			return err
+		}
+		for _, arg := range cmd.Args {
+			if _, err := w.WriteBulkStringBytes(arg); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func FuzzReaderReadValue(f *testing.F) {
+	fuzzReader(f, func(r *resp.Reader, w *resp.Writer) error {
+		v, err := r.ReadValue()
+		if err != nil {
+			return err
+		}
+		_, err = w.WriteValue(v)
+		return err
+	})
+}
+
+func FuzzReaderReadRaw(f *testing.F) {
+	fuzzReader(f, func(r *resp.Reader, w *resp.Writer) error {
+		b, err := r.ReadRaw(nil)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(b)
+		return err
+	})
+}
+
+// FuzzReaderReadDouble checks that doubles survive a round trip. Doubles can have many representations, so unlike
+// the other targets the written value is not compared byte by byte.
+func FuzzReaderReadDouble(f *testing.F) {
+	addFuzzSeeds(f)
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		d, err := resp.NewReader(bytes.NewReader(data)).ReadDouble()
+		if err != nil {
+			return
+		}
+
+		var buf bytes.Buffer
+		if _, err := resp.NewWriter(&buf).WriteDouble(d); err != nil {
+			t.Fatalf("failed to write %v: %s", d, err)
+		}
+
+		got, err := resp.NewReader(&buf).ReadDouble()
+		if err != nil {
+			t.Fatalf("failed to read %q: %s", buf.Bytes(), err)
+		}
+		if math.Float64bits(got) != math.Float64bits(d) && !(math.IsNaN(got) && math.IsNaN(d)) {
+			t.Fatalf("got %v after round trip, expected %v", got, d)
+		}
+	})
+}
+
+// FuzzReaderDecode checks that decoding arbitrary input into the generic types does not panic or allocate an
+// unbounded amount of memory.
+func FuzzReaderDecode(f *testing.F) {
+	addFuzzSeeds(f)
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		for _, v := range []interface{}{
+			new(interface{}),
+			new([]string),
+			new(map[string]int),
+			new(struct {
+				A string `resp:"a"`
+				B []int  `resp:"b"`
+			}),
+		} {
+			before := totalAlloc()
+			_ = resp.NewReader(bytes.NewReader(data)).Decode(v)
+			if n := totalAlloc() - before; n > maxFuzzAlloc(len(data)) {
+				t.Fatalf("allocated %d bytes decoding input of %d bytes into %T", n, len(data), v)
+			}
+		}
+	})
+}
+
+// FuzzParse checks that Parse accepts exactly the values accepted by Reader.ReadValue and returns the same values.
+func FuzzParse(f *testing.F) {
+	addFuzzSeeds(f)
+	f.Add([]byte("$9223372036854775807\r\n"))
+	f.Add([]byte("*1\r\n$9223372036854775806\r\nab"))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		pv, rest, perr := resp.Parse(data)
+
+		br := bytes.NewReader(data)
+		r := resp.NewReader(br)
+		rv, rerr := r.ReadValue()
+
+		switch {
+		case perr == resp.ErrIncomplete:
+			if rerr == nil {
+				t.Fatalf("got %v from Parse, but Reader read value %#v", perr, rv)
+			}
+			return
+		case (perr == nil) != (rerr == nil):
+			t.Fatalf("got error %v from Parse and %v from Reader", perr, rerr)
+		case perr != nil:
+			return
+		}
+
+		pb, _ := resp.AppendValue(nil, pv)
+		rb, _ := resp.AppendValue(nil, rv)
+		if !bytes.Equal(pb, rb) {
+			t.Fatalf("got %q from Parse and %q from Reader", pb, rb)
+		}
+		if len(rest) != br.Len()+r.Buffered() {
+			t.Fatalf("got %d bytes rest from Parse, expected %d", len(rest), br.Len()+r.Buffered())
+		}
+	})
+}
+
+// FuzzParser checks that Parser accepts the same values as Parse when fed the input in chunks and emits events for
+// the same values.
+func FuzzParser(f *testing.F) {
+	addFuzzSeeds(f)
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var expected []resp.Value
+		var perr error
+		for rest := data; len(rest) > 0 && perr == nil; {
+			var v resp.Value
+			if v, rest, perr = resp.Parse(rest); perr == nil {
+				expected = append(expected, v)
+			}
+		}
+
+		var ev eventValues
+		p := resp.NewParser(ev.handle)
+
+		size := 1
+		if len(data) > 0 {
+			size += int(data[0] % 8)
+		}
+
+		var err error
+		for b := data; len(b) > 0 && err == nil; {
+			n := size
+			if n > len(b) {
+				n = len(b)
+			}
+			err, b = p.Feed(b[:n]), b[n:]
+		}
+
+		switch {
+		case perr == resp.ErrIncomplete:
+			// Parse needs the complete blob string before validating the EOL, so Parser may fail earlier.
+			if err == nil && !p.Partial() {
+				t.Fatalf("got no partial value from Parser, but Parse returned %v", perr)
+			}
+		case err != perr:
+			t.Fatalf("got error %v from Parser and %v from Parse", err, perr)
+		case err == nil && p.Partial():
+			t.Fatalf("got partial value from Parser at end of input")
+		}
+
+		// Blob strings are emitted before the EOL is validated, so on error Parser may report one additional value.
+		n := len(ev.values)
+		switch {
+		case perr == nil && n != len(expected):
+			t.Fatalf("got %d values from Parser, expected %d", n, len(expected))
+		case perr == resp.ErrIncomplete && n > len(expected)+1:
+			t.Fatalf("got %d values from Parser, expected at most %d", n, len(expected)+1)
+		case perr != nil && perr != resp.ErrIncomplete && n != len(expected) && n != len(expected)+1:
+			t.Fatalf("got %d values from Parser, expected %d", n, len(expected))
+		}
+		if n > len(expected) {
+			n = len(expected)
+		}
+		got, want := appendValues(t, ev.values[:n]), appendValues(t, expected[:n])
+		if !bytes.Equal(got, want) {
+			t.Fatalf("got %q from Parser and %q from Parse", got, want)
+		}
+	})
+}
+
+// FuzzJSON checks that values survive a round trip through the lossless JSON representation.
+func FuzzJSON(f *testing.F) {
+	addFuzzSeeds(f)
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		v, _, err := resp.Parse(data)
+		if err != nil {
+			return
+		}
+
+		b, err := resp.AppendJSON(nil, v, &resp.JSONOptions{Lossless: true})
+		if err != nil {
+			t.Fatalf("failed to convert %q: %s", data, err)
+		}
+
+		got, err := resp.ParseJSON(b, &resp.JSONOptions{Lossless: true})
+		if err != nil {
+			t.Fatalf("failed to parse %s: %s", b, err)
+		}
+
+		expected, _ := resp.AppendValue(nil, v)
+		if gb, _ := resp.AppendValue(nil, got); !bytes.Equal(gb, expected) {
+			t.Fatalf("got %q after round trip, expected %q", gb, expected)
+		}
+
+		if b, err := resp.AppendJSON(nil, v, nil); err != nil || !json.Valid(b) {
+			t.Fatalf("got invalid JSON %s with error %v", b, err)
+		}
+	})
+}
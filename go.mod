@@ -1 +1,3 @@
 module github.com/nussjustin/resp
+
+go 1.18
@@ -0,0 +1,136 @@
+// Package record implements the file format shared by resp-record and resp-replay.
+//
+// A recording is a sequence of RESP arrays, one for each recorded value. Each array contains the time the value was
+// seen as integer in nanoseconds since the Unix epoch, the ID of the connection, the direction as simple string
+// ("request" or "reply") and the value itself. This means recordings can be inspected using any tool that
+// understands RESP.
+package record
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/nussjustin/resp"
+)
+
+// ErrInvalidRecord is returned by Reader.Read when the input contains a value that is not a valid record.
+var ErrInvalidRecord = errors.New("record: invalid record")
+
+// Direction specifies if a value was sent by the client or by the server.
+type Direction uint8
+
+const (
+	// Request is the Direction of values sent by the client.
+	Request Direction = iota + 1
+
+	// Reply is the Direction of values sent by the server.
+	Reply
+)
+
+// String implements the fmt.Stringer interface.
+func (d Direction) String() string {
+	switch d {
+	case Request:
+		return "request"
+	case Reply:
+		return "reply"
+	default:
+		return "invalid"
+	}
+}
+
+// Record is a single value seen on a connection.
+type Record struct {
+	// Time is the time the value was read.
+	Time time.Time
+
+	// Conn is the ID of the connection on which the value was seen.
+	Conn int
+
+	// Dir is the direction in which the value was sent.
+	Dir Direction
+
+	// Value is the recorded value.
+	Value resp.Value
+}
+
+// Writer writes records to an io.Writer.
+type Writer struct {
+	mu  sync.Mutex
+	w   *resp.Writer
+	err error
+}
+
+// NewWriter returns a new Writer writing to w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: resp.NewWriter(w)}
+}
+
+// Write writes the record r.
+//
+// Each record is written using a single call to the underlying io.Writer. After the first error, all calls to Write
+// return the same error.
+//
+// It is safe to call Write from multiple goroutines.
+func (w *Writer) Write(r Record) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.err != nil {
+		return w.err
+	}
+
+	_, w.err = w.w.WriteValue(resp.Array(
+		resp.Integer(int(r.Time.UnixNano())),
+		resp.Integer(r.Conn),
+		resp.SimpleString(r.Dir.String()),
+		r.Value,
+	))
+	return w.err
+}
+
+// Reader reads records from an io.Reader.
+type Reader struct {
+	r *resp.Reader
+}
+
+// NewReader returns a new Reader reading from r.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: resp.NewReader(r)}
+}
+
+// Read reads the next record.
+//
+// At the end of the input, Read returns io.EOF.
+func (r *Reader) Read() (Record, error) {
+	v, err := r.r.ReadValue()
+	if err != nil {
+		return Record{}, err
+	}
+
+	if v.Type != resp.TypeArray || len(v.Elems) != 4 ||
+		v.Elems[0].Type != resp.TypeInteger ||
+		v.Elems[1].Type != resp.TypeInteger ||
+		v.Elems[2].Type != resp.TypeSimpleString {
+		return Record{}, ErrInvalidRecord
+	}
+
+	rec := Record{
+		Time:  time.Unix(0, int64(v.Elems[0].Int)),
+		Conn:  v.Elems[1].Int,
+		Value: v.Elems[3],
+	}
+
+	switch string(v.Elems[2].Str) {
+	case "request":
+		rec.Dir = Request
+	case "reply":
+		rec.Dir = Reply
+	default:
+		return Record{}, ErrInvalidRecord
+	}
+
+	return rec, nil
+}
@@ -0,0 +1,63 @@
+package record_test
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nussjustin/resp"
+	"github.com/nussjustin/resp/internal/record"
+)
+
+func TestReadWrite(t *testing.T) {
+	now := time.Unix(1700000000, 123456789)
+
+	records := []record.Record{
+		{Time: now, Conn: 1, Dir: record.Request, Value: resp.Array(resp.BulkString("GET"), resp.BulkString("key"))},
+		{Time: now.Add(time.Millisecond), Conn: 1, Dir: record.Reply, Value: resp.NullBulkString()},
+		{Time: now.Add(2 * time.Millisecond), Conn: 2, Dir: record.Reply, Value: resp.Error("ERR failed")},
+	}
+
+	var buf bytes.Buffer
+	w := record.NewWriter(&buf)
+	for _, rec := range records {
+		if err := w.Write(rec); err != nil {
+			t.Fatalf("failed to write record: %s", err)
+		}
+	}
+
+	r := record.NewReader(&buf)
+	for i, expected := range records {
+		got, err := r.Read()
+		if err != nil {
+			t.Fatalf("failed to read record %d: %s", i, err)
+		}
+		if !got.Time.Equal(expected.Time) {
+			t.Errorf("record %d: got time %s, expected %s", i, got.Time, expected.Time)
+		}
+		got.Time = expected.Time
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("record %d: got %#v, expected %#v", i, got, expected)
+		}
+	}
+
+	if _, err := r.Read(); err != io.EOF {
+		t.Errorf("got error %v at end of input, expected %v", err, io.EOF)
+	}
+}
+
+func TestReadInvalid(t *testing.T) {
+	for _, in := range []string{
+		"+OK\r\n",
+		"*3\r\n:1\r\n:1\r\n+request\r\n",
+		"*4\r\n:1\r\n:1\r\n+unknown\r\n+OK\r\n",
+		"*4\r\n+1\r\n:1\r\n+reply\r\n+OK\r\n",
+	} {
+		if _, err := record.NewReader(strings.NewReader(in)).Read(); err != record.ErrInvalidRecord {
+			t.Errorf("got error %v for %q, expected %v", err, in, record.ErrInvalidRecord)
+		}
+	}
+}
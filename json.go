@@ -0,0 +1,527 @@
+package resp
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"strconv"
+	"unicode/utf8"
+)
+
+// ErrInvalidJSON is returned by ParseJSON and Value.UnmarshalJSON for valid JSON that does not describe a value.
+var ErrInvalidJSON = errors.New("invalid JSON representation of value")
+
+var (
+	_ json.Marshaler   = Value{}
+	_ json.Unmarshaler = (*Value)(nil)
+)
+
+// JSONOptions configures the conversion of values to and from JSON.
+type JSONOptions struct {
+	// Lossless uses a representation that keeps all information about a value, including the RESP types.
+	//
+	// Each value is represented by an object with the type name in "t" and the value in "v". For example the bulk
+	// string "foo" is represented as {"t":"bulk","v":"foo"} and an array containing the integer 1 and a null bulk
+	// string as {"t":"array","v":[{"t":"int","v":1},{"t":"bulk","v":null}]}.
+	//
+	// The type names are simple, error, int, bulk, array, attribute, bignum, blob_error, bool, double, map, null,
+	// push, set and verbatim. Maps and attributes hold their alternating keys and values in a single array.
+	//
+	// Strings that are not valid UTF-8 are base64 encoded and the object has an additional "enc" key with the value
+	// "base64". Doubles and big numbers are represented as strings, using the RESP representation.
+	Lossless bool
+}
+
+var jsonTypeNames = map[Type]string{
+	TypeArray:          "array",
+	TypeBulkString:     "bulk",
+	TypeError:          "error",
+	TypeInteger:        "int",
+	TypeSimpleString:   "simple",
+	TypeAttribute:      "attribute",
+	TypeBigNumber:      "bignum",
+	TypeBlobError:      "blob_error",
+	TypeBoolean:        "bool",
+	TypeDouble:         "double",
+	TypeMap:            "map",
+	TypeNull:           "null",
+	TypePush:           "push",
+	TypeSet:            "set",
+	TypeVerbatimString: "verbatim",
+}
+
+var jsonTypes = func() map[string]Type {
+	m := make(map[string]Type, len(jsonTypeNames))
+	for t, name := range jsonTypeNames {
+		m[name] = t
+	}
+	return m
+}()
+
+// AppendJSON appends the JSON representation of v to dst and returns the extended slice.
+//
+// By default values are converted in a lossy way that is easy to consume:
+//
+// Simple strings, bulk strings and verbatim strings (without the format prefix) are represented as strings. Integers,
+// doubles and big numbers are represented as numbers, except for infinity and NaN, which are represented as the
+// strings "inf", "-inf" and "nan". Booleans are represented as booleans, all null values as null and errors as
+// objects with the error message in "error".
+//
+// Arrays, sets and push messages are represented as arrays. Maps and attributes are represented as objects, using
+// the raw format of the keys as returned by Format as object keys. The order of the keys is preserved.
+//
+// Invalid UTF-8 in strings is replaced by the Unicode replacement character.
+//
+// If opts is nil, the default options are used. See JSONOptions.Lossless for the lossless representation.
+//
+// If the type of the value or of a nested value is unknown, ErrUnexpectedType is returned and dst is not modified. If a
+// map or attribute has an odd number of elements, ErrInvalidMapLength or ErrInvalidAttributeLength is returned.
+func AppendJSON(dst []byte, v Value, opts *JSONOptions) ([]byte, error) {
+	var b []byte
+	var err error
+
+	if opts != nil && opts.Lossless {
+		b, err = appendJSONLossless(dst, v)
+	} else {
+		b, err = appendJSONLossy(dst, v)
+	}
+	if err != nil {
+		return dst, err
+	}
+	return b, nil
+}
+
+// MarshalJSON implements the json.Marshaler interface using the lossless representation.
+//
+// See JSONOptions.Lossless for details.
+func (v Value) MarshalJSON() ([]byte, error) {
+	return AppendJSON(nil, v, &JSONOptions{Lossless: true})
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface using the lossless representation.
+//
+// See JSONOptions.Lossless for details.
+func (v *Value) UnmarshalJSON(b []byte) error {
+	nv, err := ParseJSON(b, &JSONOptions{Lossless: true})
+	if err != nil {
+		return err
+	}
+	*v = nv
+	return nil
+}
+
+// appendJSONString appends s as JSON string, replacing invalid UTF-8 with the Unicode replacement character.
+//
+// U+2028 and U+2029 are escaped so that the output can be safely embedded in JavaScript.
+func appendJSONString(dst []byte, s []byte) []byte {
+	const hex = "0123456789abcdef"
+
+	dst = append(dst, '"')
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			switch {
+			case c == '"' || c == '\\':
+				dst = append(dst, '\\', c)
+			case c == '\n':
+				dst = append(dst, '\\', 'n')
+			case c == '\r':
+				dst = append(dst, '\\', 'r')
+			case c == '\t':
+				dst = append(dst, '\\', 't')
+			case c < ' ':
+				dst = append(dst, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xf])
+			default:
+				dst = append(dst, c)
+			}
+			i++
+			continue
+		}
+
+		r, size := utf8.DecodeRune(s[i:])
+		switch {
+		case r == utf8.RuneError && size == 1:
+			dst = append(dst, `\ufffd`...)
+		case r == '\u2028':
+			dst = append(dst, `\u2028`...)
+		case r == '\u2029':
+			dst = append(dst, `\u2029`...)
+		default:
+			dst = append(dst, s[i:i+size]...)
+		}
+		i += size
+	}
+	return append(dst, '"')
+}
+
+// isJSONNumber returns true if s is an optionally negative sequence of decimal digits, which is valid as both big
+// number and JSON number.
+func isJSONNumber(s []byte) bool {
+	if len(s) > 0 && s[0] == '-' {
+		s = s[1:]
+	}
+	if len(s) == 0 || (s[0] == '0' && len(s) > 1) {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func appendJSONLossy(dst []byte, v Value) ([]byte, error) {
+	switch v.Type {
+	case TypeArray, TypeSet, TypePush:
+		if v.Null {
+			return append(dst, "null"...), nil
+		}
+		dst = append(dst, '[')
+		for i, e := range v.Elems {
+			if i > 0 {
+				dst = append(dst, ',')
+			}
+			var err error
+			if dst, err = appendJSONLossy(dst, e); err != nil {
+				return dst, err
+			}
+		}
+		return append(dst, ']'), nil
+	case TypeMap, TypeAttribute:
+		if v.Null {
+			return append(dst, "null"...), nil
+		}
+		if err := checkPairs(v); err != nil {
+			return dst, err
+		}
+		dst = append(dst, '{')
+		var key []byte
+		for i := 0; i < len(v.Elems); i += 2 {
+			if i > 0 {
+				dst = append(dst, ',')
+			}
+			var err error
+			if key, err = appendFormatRaw(key[:0], v.Elems[i]); err != nil {
+				return dst, err
+			}
+			dst = appendJSONString(dst, key)
+			dst = append(dst, ':')
+			if dst, err = appendJSONLossy(dst, v.Elems[i+1]); err != nil {
+				return dst, err
+			}
+		}
+		return append(dst, '}'), nil
+	case TypeBulkString:
+		if v.Null {
+			return append(dst, "null"...), nil
+		}
+		return appendJSONString(dst, v.Str), nil
+	case TypeSimpleString:
+		return appendJSONString(dst, v.Str), nil
+	case TypeVerbatimString:
+		return appendJSONString(dst, verbatimText(v)), nil
+	case TypeError, TypeBlobError:
+		dst = append(dst, `{"error":`...)
+		dst = appendJSONString(dst, v.Str)
+		return append(dst, '}'), nil
+	case TypeInteger:
+		return strconv.AppendInt(dst, int64(v.Int), 10), nil
+	case TypeBigNumber:
+		if !isJSONNumber(v.Str) {
+			return appendJSONString(dst, v.Str), nil
+		}
+		return append(dst, v.Str...), nil
+	case TypeDouble:
+		switch {
+		case math.IsInf(v.Float, 1):
+			return append(dst, `"inf"`...), nil
+		case math.IsInf(v.Float, -1):
+			return append(dst, `"-inf"`...), nil
+		case math.IsNaN(v.Float):
+			return append(dst, `"nan"`...), nil
+		default:
+			return strconv.AppendFloat(dst, v.Float, 'g', -1, 64), nil
+		}
+	case TypeBoolean:
+		return strconv.AppendBool(dst, v.Bool), nil
+	case TypeNull:
+		return append(dst, "null"...), nil
+	default:
+		return dst, ErrUnexpectedType
+	}
+}
+
+// appendJSONBlob appends the "v" key for the string s and if needed the "enc" key.
+func appendJSONBlob(dst []byte, s []byte) []byte {
+	dst = append(dst, `,"v":`...)
+	if utf8.Valid(s) {
+		return appendJSONString(dst, s)
+	}
+	dst = append(dst, '"')
+	n, m := len(dst), base64.StdEncoding.EncodedLen(len(s))
+	dst = ensureSpace(dst, m)[:n+m]
+	base64.StdEncoding.Encode(dst[n:], s)
+	return append(dst, `","enc":"base64"`...)
+}
+
+// checkPairs returns ErrInvalidMapLength or ErrInvalidAttributeLength if v is a map or attribute with an odd number
+// of elements.
+func checkPairs(v Value) error {
+	switch {
+	case len(v.Elems)%2 == 0:
+		return nil
+	case v.Type == TypeMap:
+		return ErrInvalidMapLength
+	case v.Type == TypeAttribute:
+		return ErrInvalidAttributeLength
+	default:
+		return nil
+	}
+}
+
+func appendJSONLossless(dst []byte, v Value) ([]byte, error) {
+	name, ok := jsonTypeNames[v.Type]
+	if !ok {
+		return dst, ErrUnexpectedType
+	}
+
+	dst = append(dst, `{"t":"`...)
+	dst = append(dst, name...)
+	dst = append(dst, '"')
+
+	switch v.Type {
+	case TypeArray, TypeSet, TypePush, TypeMap, TypeAttribute:
+		if v.Null {
+			dst = append(dst, `,"v":null`...)
+			break
+		}
+		if err := checkPairs(v); err != nil {
+			return dst, err
+		}
+		dst = append(dst, `,"v":[`...)
+		for i, e := range v.Elems {
+			if i > 0 {
+				dst = append(dst, ',')
+			}
+			var err error
+			if dst, err = appendJSONLossless(dst, e); err != nil {
+				return dst, err
+			}
+		}
+		dst = append(dst, ']')
+	case TypeBulkString:
+		if v.Null {
+			dst = append(dst, `,"v":null`...)
+			break
+		}
+		dst = appendJSONBlob(dst, v.Str)
+	case TypeSimpleString, TypeError, TypeBlobError, TypeVerbatimString, TypeBigNumber:
+		dst = appendJSONBlob(dst, v.Str)
+	case TypeInteger:
+		dst = append(dst, `,"v":`...)
+		dst = strconv.AppendInt(dst, int64(v.Int), 10)
+	case TypeDouble:
+		dst = append(dst, `,"v":"`...)
+		dst = appendDoubleString(dst, v)
+		dst = append(dst, '"')
+	case TypeBoolean:
+		dst = append(dst, `,"v":`...)
+		dst = strconv.AppendBool(dst, v.Bool)
+	}
+
+	return append(dst, '}'), nil
+}
+
+// ParseJSON parses the JSON representation of a value as created by AppendJSON.
+//
+// By default the JSON is converted in a lossy way: Strings are converted to bulk strings, numbers to integers if
+// possible, to big numbers for integers that do not fit into an int and to doubles otherwise. Booleans are converted
+// to RESP3 booleans and null to a RESP3 null. Arrays are converted to arrays and objects to maps with bulk string
+// keys, preserving the order of the keys.
+//
+// If opts is nil, the default options are used. See JSONOptions.Lossless for the lossless representation.
+//
+// If b does not contain exactly one JSON value, an error is returned. In lossless mode, ErrInvalidJSON is returned if
+// the JSON does not describe a valid value.
+func ParseJSON(b []byte, opts *JSONOptions) (Value, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+
+	var v Value
+	var err error
+
+	if opts != nil && opts.Lossless {
+		var raw json.RawMessage
+		if err = dec.Decode(&raw); err == nil {
+			v, err = parseJSONLossless(raw)
+		}
+	} else {
+		v, err = parseJSONLossy(dec)
+	}
+	if err != nil {
+		return Value{}, err
+	}
+
+	if _, err := dec.Token(); err != io.EOF {
+		return Value{}, ErrInvalidJSON
+	}
+	return v, nil
+}
+
+func parseJSONLossy(dec *json.Decoder) (Value, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return Value{}, err
+	}
+
+	switch tok := tok.(type) {
+	case json.Delim:
+		elems := []Value{}
+		for dec.More() {
+			if tok == '{' {
+				key, err := dec.Token()
+				if err != nil {
+					return Value{}, err
+				}
+				elems = append(elems, BulkString(key.(string)))
+			}
+			e, err := parseJSONLossy(dec)
+			if err != nil {
+				return Value{}, err
+			}
+			elems = append(elems, e)
+		}
+		// consume the closing delimiter
+		if _, err := dec.Token(); err != nil {
+			return Value{}, err
+		}
+		if tok == '{' {
+			return Value{Type: TypeMap, Elems: elems}, nil
+		}
+		return Array(elems...), nil
+	case string:
+		return BulkString(tok), nil
+	case json.Number:
+		if i, err := strconv.Atoi(string(tok)); err == nil {
+			return Integer(i), nil
+		}
+		if isJSONNumber([]byte(tok)) {
+			return BigNumber(string(tok)), nil
+		}
+		f, err := strconv.ParseFloat(string(tok), 64)
+		if err != nil {
+			return Value{}, ErrInvalidDouble
+		}
+		return Double(f), nil
+	case bool:
+		return Boolean(tok), nil
+	default:
+		return Null(), nil
+	}
+}
+
+// jsonNode is the lossless representation of a single value.
+type jsonNode struct {
+	T   string          `json:"t"`
+	V   json.RawMessage `json:"v"`
+	Enc string          `json:"enc"`
+}
+
+func (n *jsonNode) isNull() bool {
+	return n.V == nil || string(n.V) == "null"
+}
+
+func (n *jsonNode) blob() ([]byte, error) {
+	var s string
+	if err := json.Unmarshal(n.V, &s); err != nil {
+		return nil, ErrInvalidJSON
+	}
+	switch n.Enc {
+	case "":
+		return []byte(s), nil
+	case "base64":
+		b, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return nil, ErrInvalidJSON
+		}
+		return b, nil
+	default:
+		return nil, ErrInvalidJSON
+	}
+}
+
+func parseJSONLossless(b []byte) (Value, error) {
+	var n jsonNode
+	if err := json.Unmarshal(b, &n); err != nil {
+		return Value{}, ErrInvalidJSON
+	}
+
+	t, ok := jsonTypes[n.T]
+	if !ok {
+		return Value{}, ErrInvalidJSON
+	}
+
+	v := Value{Type: t}
+
+	switch t {
+	case TypeArray, TypeSet, TypePush, TypeMap, TypeAttribute:
+		if n.isNull() {
+			v.Null = true
+			break
+		}
+		var raws []json.RawMessage
+		if err := json.Unmarshal(n.V, &raws); err != nil {
+			return Value{}, ErrInvalidJSON
+		}
+		if (t == TypeMap || t == TypeAttribute) && len(raws)%2 != 0 {
+			return Value{}, ErrInvalidJSON
+		}
+		v.Elems = make([]Value, len(raws))
+		for i, raw := range raws {
+			var err error
+			if v.Elems[i], err = parseJSONLossless(raw); err != nil {
+				return Value{}, err
+			}
+		}
+	case TypeBulkString:
+		if n.isNull() {
+			v.Null = true
+			break
+		}
+		fallthrough
+	case TypeSimpleString, TypeError, TypeBlobError, TypeVerbatimString, TypeBigNumber:
+		var err error
+		if v.Str, err = n.blob(); err != nil {
+			return Value{}, err
+		}
+	case TypeInteger:
+		if err := json.Unmarshal(n.V, &v.Int); err != nil {
+			return Value{}, ErrInvalidJSON
+		}
+	case TypeDouble:
+		var s string
+		if err := json.Unmarshal(n.V, &s); err != nil {
+			return Value{}, ErrInvalidJSON
+		}
+		f, err := parseDouble([]byte(s))
+		if err != nil {
+			return Value{}, err
+		}
+		v.Float, v.Str = f, []byte(s)
+	case TypeBoolean:
+		if err := json.Unmarshal(n.V, &v.Bool); err != nil {
+			return Value{}, ErrInvalidJSON
+		}
+	case TypeNull:
+		v.Null = true
+	}
+
+	return v, nil
+}
@@ -0,0 +1,250 @@
+package resp_test
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/nussjustin/resp"
+)
+
+func TestAppendJSON(t *testing.T) {
+	for _, test := range []struct {
+		Name     string
+		In       resp.Value
+		Lossy    string
+		Lossless string
+	}{
+		{Name: "simple string", In: resp.SimpleString("OK"), Lossy: `"OK"`, Lossless: `{"t":"simple","v":"OK"}`},
+		{
+			Name:     "error",
+			In:       resp.Error("ERR failed"),
+			Lossy:    `{"error":"ERR failed"}`,
+			Lossless: `{"t":"error","v":"ERR failed"}`,
+		},
+		{
+			Name:     "blob error",
+			In:       resp.BlobError("ERR failed"),
+			Lossy:    `{"error":"ERR failed"}`,
+			Lossless: `{"t":"blob_error","v":"ERR failed"}`,
+		},
+		{Name: "integer", In: resp.Integer(-5), Lossy: `-5`, Lossless: `{"t":"int","v":-5}`},
+		{Name: "bulk string", In: resp.BulkString("foo"), Lossy: `"foo"`, Lossless: `{"t":"bulk","v":"foo"}`},
+		{
+			Name:     "bulk string escaping",
+			In:       resp.BulkString("a\"b\\c\r\n\t\x00\u2028ä"),
+			Lossy:    `"a\"b\\c\r\n\t\u0000\u2028ä"`,
+			Lossless: `{"t":"bulk","v":"a\"b\\c\r\n\t\u0000\u2028ä"}`,
+		},
+		{
+			Name:     "bulk string invalid UTF-8",
+			In:       resp.BulkString("a\xffb"),
+			Lossy:    `"a\ufffdb"`,
+			Lossless: `{"t":"bulk","v":"Yf9i","enc":"base64"}`,
+		},
+		{Name: "null bulk string", In: resp.NullBulkString(), Lossy: `null`, Lossless: `{"t":"bulk","v":null}`},
+		{Name: "null array", In: resp.NullArray(), Lossy: `null`, Lossless: `{"t":"array","v":null}`},
+		{Name: "null", In: resp.Null(), Lossy: `null`, Lossless: `{"t":"null"}`},
+		{Name: "boolean", In: resp.Boolean(true), Lossy: `true`, Lossless: `{"t":"bool","v":true}`},
+		{Name: "double", In: resp.Double(1.5), Lossy: `1.5`, Lossless: `{"t":"double","v":"1.5"}`},
+		{Name: "double inf", In: resp.Double(math.Inf(-1)), Lossy: `"-inf"`, Lossless: `{"t":"double","v":"-inf"}`},
+		{
+			Name:     "double with representation",
+			In:       resp.Value{Type: resp.TypeDouble, Float: 10, Str: []byte("1e1")},
+			Lossy:    `10`,
+			Lossless: `{"t":"double","v":"1e1"}`,
+		},
+		{
+			Name:     "big number",
+			In:       resp.BigNumber("-12345678901234567890"),
+			Lossy:    `-12345678901234567890`,
+			Lossless: `{"t":"bignum","v":"-12345678901234567890"}`,
+		},
+		{Name: "invalid big number", In: resp.BigNumber("1.5"), Lossy: `"1.5"`, Lossless: `{"t":"bignum","v":"1.5"}`},
+		{
+			Name:     "verbatim string",
+			In:       resp.VerbatimString("txt", "hello"),
+			Lossy:    `"hello"`,
+			Lossless: `{"t":"verbatim","v":"txt:hello"}`,
+		},
+		{Name: "empty array", In: resp.Array(), Lossy: `[]`, Lossless: `{"t":"array","v":[]}`},
+		{
+			Name:     "array",
+			In:       resp.Array(resp.BulkString("foo"), resp.Integer(5), resp.NullBulkString()),
+			Lossy:    `["foo",5,null]`,
+			Lossless: `{"t":"array","v":[{"t":"bulk","v":"foo"},{"t":"int","v":5},{"t":"bulk","v":null}]}`,
+		},
+		{
+			Name:     "set",
+			In:       resp.Set(resp.SimpleString("a")),
+			Lossy:    `["a"]`,
+			Lossless: `{"t":"set","v":[{"t":"simple","v":"a"}]}`,
+		},
+		{
+			Name:     "push",
+			In:       resp.Push(resp.SimpleString("a")),
+			Lossy:    `["a"]`,
+			Lossless: `{"t":"push","v":[{"t":"simple","v":"a"}]}`,
+		},
+		{
+			Name:  "map",
+			In:    resp.Map(resp.BulkString("z"), resp.Integer(1), resp.Integer(2), resp.Array(resp.Boolean(false))),
+			Lossy: `{"z":1,"2":[false]}`,
+			Lossless: `{"t":"map","v":[{"t":"bulk","v":"z"},{"t":"int","v":1},` +
+				`{"t":"int","v":2},{"t":"array","v":[{"t":"bool","v":false}]}]}`,
+		},
+		{
+			Name:     "attribute",
+			In:       resp.Value{Type: resp.TypeAttribute, Elems: []resp.Value{resp.SimpleString("ttl"), resp.Integer(3)}},
+			Lossy:    `{"ttl":3}`,
+			Lossless: `{"t":"attribute","v":[{"t":"simple","v":"ttl"},{"t":"int","v":3}]}`,
+		},
+	} {
+		test := test
+
+		t.Run(test.Name, func(t *testing.T) {
+			b, err := resp.AppendJSON(nil, test.In, nil)
+			if err != nil {
+				t.Fatalf("got error %v", err)
+			}
+			if got := string(b); got != test.Lossy {
+				t.Errorf("got %s, expected %s", got, test.Lossy)
+			}
+			if !json.Valid(b) {
+				t.Errorf("got invalid JSON %s", b)
+			}
+
+			b, err = resp.AppendJSON(nil, test.In, &resp.JSONOptions{Lossless: true})
+			if err != nil {
+				t.Fatalf("got error %v in lossless mode", err)
+			}
+			if got := string(b); got != test.Lossless {
+				t.Errorf("got %s in lossless mode, expected %s", got, test.Lossless)
+			}
+
+			v, err := resp.ParseJSON(b, &resp.JSONOptions{Lossless: true})
+			if err != nil {
+				t.Fatalf("got error %v parsing lossless JSON", err)
+			}
+			assertValue(t, v, test.In)
+		})
+	}
+}
+
+func TestAppendJSONInvalid(t *testing.T) {
+	for _, opts := range []*resp.JSONOptions{nil, {Lossless: true}} {
+		b, err := resp.AppendJSON([]byte("x"), resp.Array(resp.Value{}), opts)
+		if err != resp.ErrUnexpectedType {
+			t.Errorf("got error %v, expected %v", err, resp.ErrUnexpectedType)
+		}
+		if string(b) != "x" {
+			t.Errorf("got %q, expected dst to be unmodified", b)
+		}
+
+		odd := resp.Value{
+			Type:  resp.TypeMap,
+			Elems: []resp.Value{resp.BulkString("a"), resp.Integer(1), resp.BulkString("b")},
+		}
+		b, err = resp.AppendJSON([]byte("x"), odd, opts)
+		if err != resp.ErrInvalidMapLength {
+			t.Errorf("got error %v, expected %v", err, resp.ErrInvalidMapLength)
+		}
+		if string(b) != "x" {
+			t.Errorf("got %q, expected dst to be unmodified", b)
+		}
+	}
+}
+
+func TestParseJSON(t *testing.T) {
+	for _, test := range []struct {
+		Name     string
+		In       string
+		Expected resp.Value
+	}{
+		{Name: "string", In: `"foo"`, Expected: resp.BulkString("foo")},
+		{Name: "integer", In: `-12`, Expected: resp.Integer(-12)},
+		{
+			Name:     "big integer",
+			In:       `123456789012345678901234567890`,
+			Expected: resp.BigNumber("123456789012345678901234567890"),
+		},
+		{Name: "double", In: `1.5e3`, Expected: resp.Double(1500)},
+		{Name: "boolean", In: `false`, Expected: resp.Boolean(false)},
+		{Name: "null", In: `null`, Expected: resp.Null()},
+		{Name: "array", In: ` [ "a", 1, [] ] `, Expected: resp.Array(resp.BulkString("a"), resp.Integer(1), resp.Array())},
+		{
+			Name: "object",
+			In:   `{"z": {"x": null}, "a": 1}`,
+			Expected: resp.Map(
+				resp.BulkString("z"), resp.Map(resp.BulkString("x"), resp.Null()),
+				resp.BulkString("a"), resp.Integer(1),
+			),
+		},
+	} {
+		test := test
+
+		t.Run(test.Name, func(t *testing.T) {
+			v, err := resp.ParseJSON([]byte(test.In), nil)
+			if err != nil {
+				t.Fatalf("got error %v", err)
+			}
+			assertValue(t, v, test.Expected)
+		})
+	}
+}
+
+func TestParseJSONInvalid(t *testing.T) {
+	for _, test := range []struct {
+		Name     string
+		In       string
+		Lossless bool
+	}{
+		{Name: "empty", In: ``},
+		{Name: "syntax", In: `[1,`},
+		{Name: "trailing data", In: `1 2`},
+		{Name: "lossless empty", In: ``, Lossless: true},
+		{Name: "lossless not an object", In: `"foo"`, Lossless: true},
+		{Name: "lossless unknown type", In: `{"t":"foo","v":"bar"}`, Lossless: true},
+		{Name: "lossless invalid value", In: `{"t":"int","v":"1"}`, Lossless: true},
+		{Name: "lossless invalid element", In: `{"t":"array","v":[1]}`, Lossless: true},
+		{Name: "lossless odd map", In: `{"t":"map","v":[{"t":"null"}]}`, Lossless: true},
+		{Name: "lossless invalid base64", In: `{"t":"bulk","v":"!","enc":"base64"}`, Lossless: true},
+		{Name: "lossless unknown encoding", In: `{"t":"bulk","v":"a","enc":"hex"}`, Lossless: true},
+		{Name: "lossless invalid double", In: `{"t":"double","v":"x"}`, Lossless: true},
+		{Name: "lossless trailing data", In: `{"t":"null"} {"t":"null"}`, Lossless: true},
+	} {
+		test := test
+
+		t.Run(test.Name, func(t *testing.T) {
+			if _, err := resp.ParseJSON([]byte(test.In), &resp.JSONOptions{Lossless: test.Lossless}); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestValueJSON(t *testing.T) {
+	in := struct {
+		Reply resp.Value
+	}{
+		Reply: resp.Array(resp.BulkString("a"), resp.Double(math.NaN()), resp.NullBulkString()),
+	}
+
+	b, err := json.Marshal(in)
+	if err != nil {
+		t.Fatalf("got error %v", err)
+	}
+
+	const expected = `{"Reply":{"t":"array","v":[{"t":"bulk","v":"a"},{"t":"double","v":"nan"},{"t":"bulk","v":null}]}}`
+	if string(b) != expected {
+		t.Errorf("got %s, expected %s", b, expected)
+	}
+
+	var out struct {
+		Reply resp.Value
+	}
+	if err := json.Unmarshal(b, &out); err != nil {
+		t.Fatalf("got error %v", err)
+	}
+	assertValue(t, out.Reply, in.Reply)
+}
@@ -0,0 +1,395 @@
+package resp
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Marshaler is implemented by types that can encode themselves into RESP.
+//
+// MarshalRESP must write exactly one value to the given Writer. The Writer's Protocol method can be used to decide
+// between RESP2 and RESP3 types.
+type Marshaler interface {
+	MarshalRESP(w *Writer) error
+}
+
+// UnsupportedTypeError is returned by Marshal and Writer.Encode when encountering a type that can not be encoded.
+type UnsupportedTypeError struct {
+	Type reflect.Type
+}
+
+// Error implements the error interface.
+func (e *UnsupportedTypeError) Error() string {
+	return "unsupported type: " + e.Type.String()
+}
+
+// UnsupportedValueError is returned by Marshal and Writer.Encode when encountering a value that can not be encoded.
+type UnsupportedValueError struct {
+	Value reflect.Value
+	Str   string
+}
+
+// Error implements the error interface.
+func (e *UnsupportedValueError) Error() string {
+	return "unsupported value: " + e.Str
+}
+
+// Marshal returns the RESP2 encoding of v.
+//
+// See Writer.Encode for details on how values are encoded. To encode values using RESP3 use a Writer with the
+// protocol set to RESP3.
+func Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	if err := w.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Encode writes the RESP encoding of v, using the protocol version set via SetProtocol.
+//
+// Values are encoded as follows:
+//
+//   - Types implementing Marshaler are encoded by calling their MarshalRESP method
+//   - Strings and byte slices are encoded as bulk strings
+//   - Integers are encoded as integers
+//   - Booleans are encoded as booleans under RESP3 and as the integers 1 and 0 under RESP2
+//   - Floats are encoded as doubles under RESP3 and as bulk strings under RESP2
+//   - Slices and arrays are encoded as arrays
+//   - Maps and structs are encoded as maps under RESP3 and as flat arrays of keys and values under RESP2
+//   - nil pointers, interfaces, maps and slices are encoded as null
+//
+// Map keys are sorted to make the output deterministic. Only maps with string or integer keys are supported, other
+// maps return an UnsupportedTypeError.
+//
+// Struct fields can be customized using the "resp" tag, which contains the name of the field, optionally followed by
+// a comma and the "omitempty" option. Fields with the tag "-" are ignored. Unexported fields are always ignored while
+// fields of embedded structs are treated as if they were part of the outer struct.
+func (rw *Writer) Encode(v interface{}) error {
+	return rw.encode(reflect.ValueOf(v))
+}
+
+var marshalerType = reflect.TypeOf((*Marshaler)(nil)).Elem()
+
+func (rw *Writer) encode(v reflect.Value) error {
+	if !v.IsValid() {
+		return rw.encodeNull(false)
+	}
+
+	t := v.Type()
+
+	if t.Implements(marshalerType) {
+		if (t.Kind() == reflect.Ptr || t.Kind() == reflect.Interface) && v.IsNil() {
+			return rw.encodeNull(false)
+		}
+		return v.Interface().(Marshaler).MarshalRESP(rw)
+	}
+
+	if t.Kind() != reflect.Ptr && v.CanAddr() && reflect.PtrTo(t).Implements(marshalerType) {
+		return v.Addr().Interface().(Marshaler).MarshalRESP(rw)
+	}
+
+	var err error
+
+	switch t.Kind() {
+	case reflect.Bool:
+		if rw.Protocol() == RESP3 {
+			_, err = rw.WriteBoolean(v.Bool())
+		} else if v.Bool() {
+			_, err = rw.write(appendNumber(rw.buf[:0], ':', 1))
+		} else {
+			_, err = rw.write(appendNumber(rw.buf[:0], ':', 0))
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		_, err = rw.write(appendNumber(rw.buf[:0], ':', v.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u := v.Uint()
+		if u > math.MaxInt64 {
+			return &UnsupportedValueError{Value: v, Str: strconv.FormatUint(u, 10)}
+		}
+		_, err = rw.write(appendNumber(rw.buf[:0], ':', int64(u)))
+	case reflect.Float32, reflect.Float64:
+		bits := 64
+		if t.Kind() == reflect.Float32 {
+			bits = 32
+		}
+		if rw.Protocol() == RESP3 {
+			_, err = rw.write(appendDouble(rw.buf[:0], v.Float(), bits))
+		} else {
+			_, err = rw.WriteBulkString(strconv.FormatFloat(v.Float(), 'g', -1, bits))
+		}
+	case reflect.String:
+		_, err = rw.WriteBulkString(v.String())
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 && !reflect.PtrTo(t.Elem()).Implements(marshalerType) {
+			if v.IsNil() {
+				return rw.encodeNull(false)
+			}
+			_, err = rw.WriteBulkStringBytes(v.Bytes())
+			break
+		}
+		if v.IsNil() {
+			return rw.encodeNull(true)
+		}
+		return rw.encodeArray(v)
+	case reflect.Array:
+		return rw.encodeArray(v)
+	case reflect.Map:
+		if v.IsNil() {
+			return rw.encodeNull(true)
+		}
+		return rw.encodeMap(v)
+	case reflect.Struct:
+		return rw.encodeStruct(v)
+	case reflect.Interface, reflect.Ptr:
+		if v.IsNil() {
+			return rw.encodeNull(isAggregateType(t.Elem()))
+		}
+		return rw.encode(v.Elem())
+	default:
+		return &UnsupportedTypeError{Type: t}
+	}
+
+	return err
+}
+
+func isAggregateType(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Array, reflect.Map, reflect.Struct:
+		return true
+	case reflect.Slice:
+		return t.Elem().Kind() != reflect.Uint8
+	default:
+		return false
+	}
+}
+
+func (rw *Writer) encodeNull(aggregate bool) error {
+	var err error
+	switch {
+	case rw.Protocol() == RESP3:
+		_, err = rw.WriteNull()
+	case aggregate:
+		_, err = rw.WriteArrayHeader(-1)
+	default:
+		_, err = rw.WriteBulkStringHeader(-1)
+	}
+	return err
+}
+
+func (rw *Writer) encodeMapHeader(n int) error {
+	var err error
+	if rw.Protocol() == RESP3 {
+		_, err = rw.WriteMapHeader(n)
+	} else {
+		_, err = rw.WriteArrayHeader(n * 2)
+	}
+	return err
+}
+
+func (rw *Writer) encodeArray(v reflect.Value) error {
+	n := v.Len()
+	if _, err := rw.WriteArrayHeader(n); err != nil {
+		return err
+	}
+	for i := 0; i < n; i++ {
+		if err := rw.encode(v.Index(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (rw *Writer) encodeMap(v reflect.Value) error {
+	switch v.Type().Key().Kind() {
+	case reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+	default:
+		return &UnsupportedTypeError{Type: v.Type()}
+	}
+
+	keys := v.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		return mapKeyString(keys[i]) < mapKeyString(keys[j])
+	})
+
+	if err := rw.encodeMapHeader(len(keys)); err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := rw.encode(key); err != nil {
+			return err
+		}
+		if err := rw.encode(v.MapIndex(key)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func mapKeyString(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	default: // unsigned integers, other key types are rejected by encodeMap
+		return strconv.FormatUint(v.Uint(), 10)
+	}
+}
+
+func (rw *Writer) encodeStruct(v reflect.Value) error {
+	fields := cachedFields(v.Type())
+
+	values := make([]reflect.Value, len(fields))
+	n := 0
+	for i := range fields {
+		fv, ok := fieldByIndex(v, fields[i].index)
+		if !ok || (fields[i].omitEmpty && isEmptyValue(fv)) {
+			continue
+		}
+		values[i] = fv
+		n++
+	}
+
+	if err := rw.encodeMapHeader(n); err != nil {
+		return err
+	}
+	for i := range fields {
+		if !values[i].IsValid() {
+			continue
+		}
+		if _, err := rw.WriteBulkString(fields[i].name); err != nil {
+			return err
+		}
+		if err := rw.encode(values[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	default:
+		return false
+	}
+}
+
+// field describes a single (possibly embedded) struct field that is encoded or decoded.
+type field struct {
+	name      string
+	index     []int
+	omitEmpty bool
+}
+
+var fieldCache sync.Map // map[reflect.Type][]field
+
+func cachedFields(t reflect.Type) []field {
+	if fs, ok := fieldCache.Load(t); ok {
+		return fs.([]field)
+	}
+	fs, _ := fieldCache.LoadOrStore(t, typeFields(t, nil, map[reflect.Type]bool{}))
+	return fs.([]field)
+}
+
+func typeFields(t reflect.Type, index []int, visited map[reflect.Type]bool) []field {
+	if visited[t] {
+		return nil
+	}
+	visited[t] = true
+
+	var fields, embedded []field
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+
+		tag := sf.Tag.Get("resp")
+		if tag == "-" {
+			continue
+		}
+
+		name, opts := tag, ""
+		if idx := strings.IndexByte(tag, ','); idx != -1 {
+			name, opts = tag[:idx], tag[idx+1:]
+		}
+
+		fieldIndex := make([]int, len(index)+1)
+		copy(fieldIndex, index)
+		fieldIndex[len(index)] = i
+
+		if sf.Anonymous && name == "" {
+			ft := sf.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				embedded = append(embedded, typeFields(ft, fieldIndex, visited)...)
+				continue
+			}
+		}
+
+		if sf.PkgPath != "" {
+			continue
+		}
+
+		if name == "" {
+			name = sf.Name
+		}
+
+		fields = append(fields, field{
+			name:      name,
+			index:     fieldIndex,
+			omitEmpty: opts == "omitempty",
+		})
+	}
+
+	// fields of the outer struct take precedence over fields of embedded structs with the same name
+	for _, ef := range embedded {
+		found := false
+		for _, f := range fields {
+			if f.name == ef.name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			fields = append(fields, ef)
+		}
+	}
+
+	return fields
+}
+
+// fieldByIndex returns the field at the given index, returning false if a nil embedded pointer was encountered.
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
@@ -0,0 +1,243 @@
+package resp_test
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/nussjustin/resp"
+)
+
+type marshalPoint struct {
+	X, Y int
+}
+
+func (p marshalPoint) MarshalRESP(w *resp.Writer) error {
+	_, err := w.WriteSimpleString("point")
+	return err
+}
+
+type marshalEmbedded struct {
+	ID   int    `resp:"id"`
+	Name string `resp:"name"`
+}
+
+type marshalStruct struct {
+	marshalEmbedded
+	Name     string  `resp:"name"`
+	Optional string  `resp:"optional,omitempty"`
+	Ignored  string  `resp:"-"`
+	Score    float64 `resp:"score"`
+	Tags     []string
+	private  int
+}
+
+var errMarshalFailed = errors.New("marshal failed")
+
+type failingMarshaler struct{}
+
+func (failingMarshaler) MarshalRESP(*resp.Writer) error {
+	return errMarshalFailed
+}
+
+func TestMarshal(t *testing.T) {
+	var nilString *string
+	var nilSlice []string
+	var nilMap map[string]int
+	var nilStruct *marshalStruct
+
+	for _, test := range []struct {
+		Name     string
+		In       interface{}
+		Expected string
+		RESP3    string
+	}{
+		{
+			Name:     "nil",
+			In:       nil,
+			Expected: "$-1\r\n",
+			RESP3:    "_\r\n",
+		},
+		{
+			Name:     "string",
+			In:       "hello",
+			Expected: "$5\r\nhello\r\n",
+		},
+		{
+			Name:     "bytes",
+			In:       []byte("hello"),
+			Expected: "$5\r\nhello\r\n",
+		},
+		{
+			Name:     "nil bytes",
+			In:       []byte(nil),
+			Expected: "$-1\r\n",
+			RESP3:    "_\r\n",
+		},
+		{
+			Name:     "int",
+			In:       -5,
+			Expected: ":-5\r\n",
+		},
+		{
+			Name:     "uint8",
+			In:       uint8(200),
+			Expected: ":200\r\n",
+		},
+		{
+			Name:     "bool",
+			In:       true,
+			Expected: ":1\r\n",
+			RESP3:    "#t\r\n",
+		},
+		{
+			Name:     "float",
+			In:       1.5,
+			Expected: "$3\r\n1.5\r\n",
+			RESP3:    ",1.5\r\n",
+		},
+		{
+			Name:     "float32",
+			In:       float32(0.1),
+			Expected: "$3\r\n0.1\r\n",
+			RESP3:    ",0.1\r\n",
+		},
+		{
+			Name:     "slice",
+			In:       []interface{}{"a", 1, []int{2}},
+			Expected: "*3\r\n$1\r\na\r\n:1\r\n*1\r\n:2\r\n",
+		},
+		{
+			Name:     "array",
+			In:       [2]string{"a", "b"},
+			Expected: "*2\r\n$1\r\na\r\n$1\r\nb\r\n",
+		},
+		{
+			Name:     "nil slice",
+			In:       nilSlice,
+			Expected: "*-1\r\n",
+			RESP3:    "_\r\n",
+		},
+		{
+			Name:     "map",
+			In:       map[string]int{"b": 2, "a": 1},
+			Expected: "*4\r\n$1\r\na\r\n:1\r\n$1\r\nb\r\n:2\r\n",
+			RESP3:    "%2\r\n$1\r\na\r\n:1\r\n$1\r\nb\r\n:2\r\n",
+		},
+		{
+			Name:     "nil map",
+			In:       nilMap,
+			Expected: "*-1\r\n",
+			RESP3:    "_\r\n",
+		},
+		{
+			Name: "struct",
+			In: marshalStruct{
+				marshalEmbedded: marshalEmbedded{ID: 1, Name: "shadowed"},
+				Name:            "test",
+				Ignored:         "ignored",
+				Score:           2,
+				Tags:            []string{"x"},
+			},
+			Expected: "*8\r\n" +
+				"$4\r\nname\r\n$4\r\ntest\r\n" +
+				"$5\r\nscore\r\n$1\r\n2\r\n" +
+				"$4\r\nTags\r\n*1\r\n$1\r\nx\r\n" +
+				"$2\r\nid\r\n:1\r\n",
+			RESP3: "%4\r\n" +
+				"$4\r\nname\r\n$4\r\ntest\r\n" +
+				"$5\r\nscore\r\n,2\r\n" +
+				"$4\r\nTags\r\n*1\r\n$1\r\nx\r\n" +
+				"$2\r\nid\r\n:1\r\n",
+		},
+		{
+			Name:     "nil pointer",
+			In:       nilString,
+			Expected: "$-1\r\n",
+			RESP3:    "_\r\n",
+		},
+		{
+			Name:     "nil struct pointer",
+			In:       nilStruct,
+			Expected: "*-1\r\n",
+			RESP3:    "_\r\n",
+		},
+		{
+			Name:     "pointer",
+			In:       &marshalEmbedded{ID: 2},
+			Expected: "*4\r\n$2\r\nid\r\n:2\r\n$4\r\nname\r\n$0\r\n\r\n",
+			RESP3:    "%2\r\n$2\r\nid\r\n:2\r\n$4\r\nname\r\n$0\r\n\r\n",
+		},
+		{
+			Name:     "marshaler",
+			In:       []marshalPoint{{1, 2}},
+			Expected: "*1\r\n+point\r\n",
+		},
+	} {
+		test := test
+
+		t.Run(test.Name, func(t *testing.T) {
+			got, err := resp.Marshal(test.In)
+			if err != nil {
+				t.Fatalf("failed to marshal %#v: %s", test.In, err)
+			}
+			assertBytes(t, got, test.Expected)
+
+			expected := test.RESP3
+			if expected == "" {
+				expected = test.Expected
+			}
+
+			var buf bytes.Buffer
+			w := resp.NewWriter(&buf)
+			w.SetProtocol(resp.RESP3)
+
+			if err := w.Encode(test.In); err != nil {
+				t.Fatalf("failed to encode %#v: %s", test.In, err)
+			}
+			assertBytes(t, buf.Bytes(), expected)
+		})
+	}
+}
+
+func TestMarshalErrors(t *testing.T) {
+	if _, err := resp.Marshal(make(chan int)); err == nil {
+		t.Error("expected error for channel")
+	} else if _, ok := err.(*resp.UnsupportedTypeError); !ok {
+		t.Errorf("got error %#v, expected *resp.UnsupportedTypeError", err)
+	}
+
+	if _, err := resp.Marshal(map[float64]int{1.5: 1}); err == nil {
+		t.Error("expected error for map with float keys")
+	} else if _, ok := err.(*resp.UnsupportedTypeError); !ok {
+		t.Errorf("got error %#v, expected *resp.UnsupportedTypeError", err)
+	}
+
+	if _, err := resp.Marshal(uint64(math.MaxUint64)); err == nil {
+		t.Error("expected error for overflowing uint64")
+	} else if _, ok := err.(*resp.UnsupportedValueError); !ok {
+		t.Errorf("got error %#v, expected *resp.UnsupportedValueError", err)
+	}
+
+	if _, err := resp.Marshal([]interface{}{failingMarshaler{}}); err != errMarshalFailed {
+		t.Errorf("got error %v, expected %v", err, errMarshalFailed)
+	}
+}
+
+func BenchmarkMarshal(b *testing.B) {
+	v := marshalStruct{
+		marshalEmbedded: marshalEmbedded{ID: 1},
+		Name:            "test",
+		Score:           2,
+		Tags:            []string{"x", "y", "z"},
+	}
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := resp.Marshal(v); err != nil {
+			b.Fatalf("marshal failed: %s", err)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package resp
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"sync"
+)
+
+// ErrUnexpectedReply is returned by Multiplexer when the server sent a reply without a matching command.
+var ErrUnexpectedReply = errors.New("received reply without matching command")
+
+// Multiplexer sends commands from multiple goroutines over a single connection.
+//
+// Commands are written by a single goroutine that writes all commands that are submitted while the previous batch of
+// commands is written, before flushing them together. Replies are read by another goroutine and matched to the
+// commands in the order the commands were written.
+//
+// Because all commands share one connection, commands that block the connection (for example BLPOP) or change its
+// state (for example SELECT, SUBSCRIBE or MULTI) must not be used with a Multiplexer. RESP3 push messages and
+// attributes are ignored.
+//
+// Once the connection fails, all pending and future commands fail with the same error and the Multiplexer must be
+// closed.
+type Multiplexer struct {
+	conn net.Conn
+	bw   *bufio.Writer
+	rw   ReadWriter
+
+	reqs chan *muxRequest
+	done chan struct{}
+	wg   sync.WaitGroup
+
+	mu      sync.Mutex
+	err     error
+	pending []*muxRequest
+}
+
+type muxRequest struct {
+	cmd   []byte
+	reply chan muxReply
+}
+
+type muxReply struct {
+	v   Value
+	err error
+}
+
+// NewMultiplexer returns a new Multiplexer using the given connection.
+//
+// The Multiplexer starts two goroutines which run until Close is called or the connection fails.
+func NewMultiplexer(conn net.Conn) *Multiplexer {
+	m := &Multiplexer{
+		conn: conn,
+		bw:   bufio.NewWriter(conn),
+		reqs: make(chan *muxRequest),
+		done: make(chan struct{}),
+	}
+	m.rw.Reader.Reset(conn)
+	m.rw.Writer.Reset(m.bw)
+
+	m.wg.Add(2)
+	go m.readLoop()
+	go m.writeLoop()
+
+	return m
+}
+
+// Close closes the underlying connection and waits for all goroutines started by the Multiplexer to exit.
+//
+// All pending commands fail with net.ErrClosed.
+func (m *Multiplexer) Close() error {
+	err := m.fail(net.ErrClosed)
+	m.wg.Wait()
+	return err
+}
+
+// Err returns the error that caused the Multiplexer to fail, or nil if the Multiplexer is usable.
+func (m *Multiplexer) Err() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.err
+}
+
+// fail stops the Multiplexer, closes the connection and fails all pending commands with err.
+//
+// Only the first call has an effect. The error returned is the result of closing the connection.
+func (m *Multiplexer) fail(err error) error {
+	m.mu.Lock()
+	if m.err != nil {
+		m.mu.Unlock()
+		return nil
+	}
+	m.err = err
+	pending := m.pending
+	m.pending = nil
+	close(m.done)
+	m.mu.Unlock()
+
+	for _, req := range pending {
+		req.reply <- muxReply{err: err}
+	}
+	return m.conn.Close()
+}
+
+// Do sends a single command, consisting of the given arguments, and returns the reply.
+//
+// See Conn.Do for the supported argument types. If the reply is an error, the reply is returned together with the
+// error as ReplyError.
+//
+// If ctx is done before the reply was received, Do returns the context error. The command may still be executed by
+// the server and its reply is discarded.
+func (m *Multiplexer) Do(ctx context.Context, args ...interface{}) (Value, error) {
+	cmd, err := appendCommand(nil, args)
+	if err != nil {
+		return Value{}, err
+	}
+
+	req := &muxRequest{cmd: cmd, reply: make(chan muxReply, 1)}
+
+	select {
+	case m.reqs <- req:
+	case <-m.done:
+		return Value{}, m.Err()
+	case <-ctx.Done():
+		return Value{}, ctx.Err()
+	}
+
+	select {
+	case r := <-req.reply:
+		return r.v, r.err
+	case <-ctx.Done():
+		return Value{}, ctx.Err()
+	}
+}
+
+func (m *Multiplexer) writeLoop() {
+	defer m.wg.Done()
+
+	for {
+		var req *muxRequest
+
+		select {
+		case req = <-m.reqs:
+		case <-m.done:
+			return
+		}
+
+		for req != nil {
+			if !m.push(req) {
+				return
+			}
+
+			if _, err := m.rw.Writer.Write(req.cmd); err != nil {
+				_ = m.fail(err)
+				return
+			}
+
+			select {
+			case req = <-m.reqs:
+			default:
+				req = nil
+			}
+		}
+
+		if err := m.bw.Flush(); err != nil {
+			_ = m.fail(err)
+			return
+		}
+	}
+}
+
+// push adds req to the list of requests waiting for a reply. If the Multiplexer failed, req fails immediately.
+func (m *Multiplexer) push(req *muxRequest) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.err != nil {
+		req.reply <- muxReply{err: m.err}
+		return false
+	}
+
+	m.pending = append(m.pending, req)
+	return true
+}
+
+// pop removes the oldest request waiting for a reply.
+func (m *Multiplexer) pop() *muxRequest {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.pending) == 0 {
+		return nil
+	}
+
+	req := m.pending[0]
+	m.pending[0] = nil
+	m.pending = m.pending[1:]
+	return req
+}
+
+func (m *Multiplexer) readLoop() {
+	defer m.wg.Done()
+
+	for {
+		v, err := readReply(&m.rw.Reader)
+		if err != nil {
+			_ = m.fail(err)
+			return
+		}
+
+		req := m.pop()
+		if req == nil {
+			_ = m.fail(ErrUnexpectedReply)
+			return
+		}
+		req.reply <- muxReply{v: v, err: v.Err()}
+	}
+}
@@ -0,0 +1,148 @@
+package resp_test
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nussjustin/resp"
+)
+
+func newTestMultiplexer(tb testing.TB, fn func(req resp.Value) resp.Value) (*resp.Multiplexer, net.Conn) {
+	client, server := net.Pipe()
+	go serveConn(tb, server, fn)
+
+	m := resp.NewMultiplexer(client)
+	tb.Cleanup(func() {
+		_ = m.Close()
+		_ = server.Close()
+	})
+	return m, server
+}
+
+func TestMultiplexerDo(t *testing.T) {
+	m, _ := newTestMultiplexer(t, echoHandler)
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			v, err := m.Do(ctx, "ECHO", i)
+			if err != nil {
+				t.Errorf("do failed: %s", err)
+				return
+			}
+			if got, expected := string(v.Str), "ECHO "+strconv.Itoa(i); got != expected {
+				t.Errorf("got %q, expected %q", got, expected)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if _, err := m.Do(ctx, "ERR", "x"); err != resp.ReplyError("ERR x") {
+		t.Errorf("got error %v, expected %v", err, resp.ReplyError("ERR x"))
+	}
+	if _, err := m.Do(ctx, struct{}{}); err == nil {
+		t.Error("expected error for unsupported argument")
+	}
+	if err := m.Err(); err != nil {
+		t.Errorf("got error %v, expected multiplexer to be usable", err)
+	}
+}
+
+func TestMultiplexerAttributesAndPush(t *testing.T) {
+	client, server := net.Pipe()
+	t.Cleanup(func() { _ = server.Close() })
+
+	go func() {
+		rw := resp.NewReadWriter(server)
+		for {
+			req, err := rw.ReadValue()
+			if err != nil {
+				return
+			}
+			_, _ = server.Write([]byte(">2\r\n$10\r\ninvalidate\r\n*1\r\n$1\r\nk\r\n|1\r\n+ttl\r\n:100\r\n"))
+			_, _ = rw.WriteValue(echoHandler(req))
+		}
+	}()
+
+	m := resp.NewMultiplexer(client)
+	t.Cleanup(func() { _ = m.Close() })
+
+	for _, arg := range []string{"a", "b"} {
+		v, err := m.Do(context.Background(), "ECHO", arg)
+		if err != nil {
+			t.Fatalf("do failed: %s", err)
+		}
+		if got, expected := string(v.Str), "ECHO "+arg; got != expected {
+			t.Errorf("got %q, expected %q", got, expected)
+		}
+	}
+}
+
+func TestMultiplexerCancel(t *testing.T) {
+	release := make(chan struct{})
+	m, _ := newTestMultiplexer(t, func(req resp.Value) resp.Value {
+		if string(req.Elems[0].Str) == "SLOW" {
+			<-release
+		}
+		return echoHandler(req)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, err := m.Do(ctx, "SLOW"); err != context.DeadlineExceeded {
+		t.Fatalf("got error %v, expected %v", err, context.DeadlineExceeded)
+	}
+	close(release)
+
+	// the reply to the canceled command must be skipped
+	v, err := m.Do(context.Background(), "ECHO", "fast")
+	if err != nil {
+		t.Fatalf("do failed: %s", err)
+	}
+	if got := string(v.Str); got != "ECHO fast" {
+		t.Errorf("got %q, expected %q", got, "ECHO fast")
+	}
+}
+
+func TestMultiplexerConnError(t *testing.T) {
+	received, release := make(chan struct{}), make(chan struct{})
+	defer close(release)
+
+	m, server := newTestMultiplexer(t, func(req resp.Value) resp.Value {
+		close(received)
+		<-release
+		return resp.SimpleString("PONG")
+	})
+
+	errc := make(chan error, 1)
+	go func() {
+		_, err := m.Do(context.Background(), "PING")
+		errc <- err
+	}()
+
+	<-received
+	_ = server.Close()
+
+	if err := <-errc; err == nil {
+		t.Fatal("expected error after connection was closed")
+	}
+	if m.Err() == nil {
+		t.Error("expected multiplexer to be broken")
+	}
+	if _, err := m.Do(context.Background(), "PING"); err == nil {
+		t.Error("expected error when using broken multiplexer")
+	}
+
+	if err := m.Close(); err != nil {
+		t.Errorf("failed to close: %s", err)
+	}
+}
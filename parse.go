@@ -0,0 +1,280 @@
+package resp
+
+import (
+	"errors"
+	"math"
+)
+
+// ErrIncomplete is returned by Parse and ParseCommand when the given data ends before the end of the value.
+var ErrIncomplete = errors.New("incomplete value")
+
+// Parse parses the first value in b, including all nested values, and returns the value together with the data
+// following it.
+//
+// If b only contains part of a value, ErrIncomplete is returned and the caller should call Parse again once more data
+// is available. On error, rest is always b.
+//
+// Values are parsed using the same rules as used by Reader.ReadValue. Unlike ReadValue, the returned value references
+// the memory of b instead of copying it, so b must not be modified while the value is in use.
+func Parse(b []byte) (v Value, rest []byte, err error) {
+	p := sliceParser{b: b}
+	if v, err = p.value(); err != nil {
+		return Value{}, b, err
+	}
+	return v, b[p.pos:], nil
+}
+
+// ParseCommand parses the first command in b into cmd and returns the data following it.
+//
+// If b only contains part of a command, ErrIncomplete is returned and the caller should call ParseCommand again once
+// more data is available. On error, rest is always b.
+//
+// Commands are parsed using the same rules as used by Reader.ReadCommand. The slice cmd.Args is reused if possible,
+// but unlike ReadCommand the arguments reference the memory of b instead of being copied, so b must not be modified
+// while cmd is in use.
+func ParseCommand(b []byte, cmd *Command) (rest []byte, err error) {
+	p := sliceParser{b: b}
+
+	n, err := p.header(TypeArray, -1, 1, ErrInvalidArrayLength)
+	if err != nil {
+		return b, err
+	}
+	if n < 0 {
+		return b, ErrInvalidCommand
+	}
+
+	args := cmd.Args[:0]
+	for i := 0; i < n; i++ {
+		if p.pos >= len(p.b) {
+			return b, ErrIncomplete
+		}
+		if Type(p.b[p.pos]) != TypeBulkString {
+			return b, ErrInvalidCommand
+		}
+
+		arg, err := p.blob(TypeBulkString, -1, ErrInvalidBulkStringLength)
+		if err != nil {
+			return b, err
+		}
+		if arg == nil {
+			return b, ErrInvalidCommand
+		}
+		args = append(args, arg)
+	}
+
+	cmd.Args = args
+	return b[p.pos:], nil
+}
+
+// sliceParser implements Parse and ParseCommand.
+type sliceParser struct {
+	b   []byte
+	pos int
+}
+
+// slice returns b[i:j] with the capacity limited to the length, so that appending to the result never modifies b.
+func (p *sliceParser) slice(i, j int) []byte {
+	return p.b[i:j:j]
+}
+
+// number parses a number line at the current position, using the same rules as Reader.readNumberLine.
+func (p *sliceParser) number() (int, error) {
+	var n uint
+	var neg bool
+
+	// the magnitude of the smallest negative number is one larger than the largest positive number
+	limit := uint(math.MaxInt)
+
+	for i := 0; ; i++ {
+		if p.pos+i >= len(p.b) {
+			return 0, ErrIncomplete
+		}
+
+		switch b := p.b[p.pos+i]; {
+		case b == '-' && i == 0:
+			neg = true
+			limit++
+		case b >= '0' && b <= '9':
+			if n > (limit-uint(b-'0'))/10 {
+				return 0, ErrInvalidInteger
+			}
+			n *= 10
+			n += uint(b - '0')
+		case b == '\r':
+			if p.pos+i+1 >= len(p.b) {
+				return 0, ErrIncomplete
+			}
+			if p.b[p.pos+i+1] != '\n' {
+				return 0, ErrUnexpectedEOL
+			}
+			p.pos += i + 2
+			if neg {
+				return -int(n), nil
+			}
+			return int(n), nil
+		case b == '\n':
+			return 0, ErrUnexpectedEOL
+		default:
+			return 0, ErrInvalidInteger
+		}
+	}
+}
+
+// line parses a line at the current position, excluding the EOL marker.
+func (p *sliceParser) line() ([]byte, error) {
+	for i := p.pos; i < len(p.b); i++ {
+		if p.b[i] != '\n' {
+			continue
+		}
+		if i == p.pos || p.b[i-1] != '\r' {
+			return nil, ErrUnexpectedEOL
+		}
+		line := p.slice(p.pos, i-1)
+		p.pos = i + 1
+		return line, nil
+	}
+	return nil, ErrIncomplete
+}
+
+// expect skips the type byte of the value at the current position, which must be of type t.
+func (p *sliceParser) expect(t Type) error {
+	if p.pos >= len(p.b) {
+		return ErrIncomplete
+	}
+	if types[p.b[p.pos]] != t {
+		return ErrUnexpectedType
+	}
+	p.pos++
+	return nil
+}
+
+// header parses an aggregate or blob header of type t with entries of size elems, returning invalidErr if the
+// length is less than min.
+func (p *sliceParser) header(t Type, min, elems int, invalidErr error) (int, error) {
+	if err := p.expect(t); err != nil {
+		return 0, err
+	}
+	n, err := p.number()
+	if err == ErrInvalidInteger || (err == nil && (n < min || n > math.MaxInt/elems)) {
+		return 0, invalidErr
+	}
+	return n, err
+}
+
+// blob parses a blob string of type t, returning nil for null values.
+func (p *sliceParser) blob(t Type, min int, invalidErr error) ([]byte, error) {
+	n, err := p.header(t, min, 1, invalidErr)
+	if err != nil || n == -1 {
+		return nil, err
+	}
+	if n > len(p.b)-p.pos-2 {
+		return nil, ErrIncomplete
+	}
+	if p.b[p.pos+n] != '\r' || p.b[p.pos+n+1] != '\n' {
+		return nil, ErrUnexpectedEOL
+	}
+	s := p.slice(p.pos, p.pos+n)
+	p.pos += n + 2
+	return s, nil
+}
+
+// simple parses a single line value of type t.
+func (p *sliceParser) simple(t Type) ([]byte, error) {
+	if err := p.expect(t); err != nil {
+		return nil, err
+	}
+	return p.line()
+}
+
+func (p *sliceParser) value() (Value, error) {
+	if p.pos >= len(p.b) {
+		return Value{}, ErrIncomplete
+	}
+
+	t := types[p.b[p.pos]]
+	v := Value{Type: t}
+
+	var err error
+
+	switch t {
+	case TypeArray, TypeSet, TypePush, TypeMap, TypeAttribute:
+		var n int
+		switch t {
+		case TypeArray:
+			n, err = p.header(t, -1, 1, ErrInvalidArrayLength)
+		case TypeSet:
+			n, err = p.header(t, 0, 1, ErrInvalidSetLength)
+		case TypePush:
+			n, err = p.header(t, 0, 1, ErrInvalidPushLength)
+		case TypeMap:
+			n, err = p.header(t, 0, 2, ErrInvalidMapLength)
+		case TypeAttribute:
+			n, err = p.header(t, 0, 2, ErrInvalidAttributeLength)
+		}
+		if err != nil {
+			return Value{}, err
+		}
+		if n == -1 {
+			v.Null = true
+			return v, nil
+		}
+		if t == TypeMap || t == TypeAttribute {
+			n *= 2
+		}
+		v.Elems = make([]Value, 0, preallocSize(n, maxPreallocElems))
+		for i := 0; i < n; i++ {
+			e, err := p.value()
+			if err != nil {
+				return Value{}, err
+			}
+			v.Elems = append(v.Elems, e)
+		}
+	case TypeBulkString:
+		v.Str, err = p.blob(t, -1, ErrInvalidBulkStringLength)
+		v.Null = err == nil && v.Str == nil
+	case TypeBlobError:
+		v.Str, err = p.blob(t, 0, ErrInvalidBlobErrorLength)
+	case TypeVerbatimString:
+		v.Str, err = p.blob(t, 4, ErrInvalidVerbatimStringLength)
+	case TypeSimpleString, TypeError, TypeBigNumber:
+		v.Str, err = p.simple(t)
+	case TypeInteger:
+		p.pos++
+		v.Int, err = p.number()
+	case TypeDouble:
+		if v.Str, err = p.simple(t); err == nil {
+			v.Float, err = parseDouble(v.Str)
+		}
+	case TypeBoolean:
+		var line []byte
+		if line, err = p.simple(t); err == nil {
+			switch {
+			case len(line) == 1 && line[0] == 't':
+				v.Bool = true
+			case len(line) == 1 && line[0] == 'f':
+			default:
+				err = ErrInvalidBoolean
+			}
+		}
+	case TypeNull:
+		p.pos++
+		if len(p.b)-p.pos < 2 {
+			if len(p.b) == p.pos || p.b[p.pos] == '\r' {
+				return Value{}, ErrIncomplete
+			}
+			return Value{}, ErrUnexpectedEOL
+		}
+		if p.b[p.pos] != '\r' || p.b[p.pos+1] != '\n' {
+			return Value{}, ErrUnexpectedEOL
+		}
+		p.pos += 2
+		v.Null = true
+	default:
+		return Value{}, ErrUnexpectedType
+	}
+
+	if err != nil {
+		return Value{}, err
+	}
+	return v, nil
+}
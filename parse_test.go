@@ -0,0 +1,165 @@
+package resp_test
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/nussjustin/resp"
+)
+
+func TestParse(t *testing.T) {
+	for _, test := range []struct {
+		Name     string
+		In       string
+		Expected resp.Value
+		Err      error
+	}{
+		{Name: "simple string", In: "+OK\r\n", Expected: resp.SimpleString("OK")},
+		{Name: "error", In: "-ERR failed\r\n", Expected: resp.Error("ERR failed")},
+		{Name: "integer", In: ":-100\r\n", Expected: resp.Integer(-100)},
+		{Name: "bulk string", In: "$5\r\nhello\r\n", Expected: resp.BulkString("hello")},
+		{Name: "empty bulk string", In: "$0\r\n\r\n", Expected: resp.BulkString("")},
+		{Name: "null bulk string", In: "$-1\r\n", Expected: resp.NullBulkString()},
+		{Name: "null array", In: "*-1\r\n", Expected: resp.NullArray()},
+		{
+			Name:     "array",
+			In:       "*2\r\n$3\r\nkey\r\n:1\r\n",
+			Expected: resp.Array(resp.BulkString("key"), resp.Integer(1)),
+		},
+		{Name: "boolean", In: "#t\r\n", Expected: resp.Boolean(true)},
+		{Name: "double", In: ",1.5\r\n", Expected: resp.Value{Type: resp.TypeDouble, Float: 1.5, Str: []byte("1.5")}},
+		{Name: "null", In: "_\r\n", Expected: resp.Null()},
+		{
+			Name:     "map",
+			In:       "%1\r\n+key\r\n~1\r\n#f\r\n",
+			Expected: resp.Map(resp.SimpleString("key"), resp.Set(resp.Boolean(false))),
+		},
+		{Name: "verbatim string", In: "=9\r\ntxt:hello\r\n", Expected: resp.VerbatimString("txt", "hello")},
+
+		{Name: "empty", In: "", Err: resp.ErrIncomplete},
+		{Name: "incomplete line", In: "+OK\r", Err: resp.ErrIncomplete},
+		{Name: "incomplete number", In: ":12", Err: resp.ErrIncomplete},
+		{Name: "incomplete bulk string", In: "$5\r\nhel", Err: resp.ErrIncomplete},
+		{Name: "incomplete array", In: "*2\r\n:1\r\n", Err: resp.ErrIncomplete},
+		{Name: "incomplete null", In: "_\r", Err: resp.ErrIncomplete},
+		{Name: "invalid type", In: "A\r\n", Err: resp.ErrUnexpectedType},
+		{Name: "invalid integer", In: ":1a\r\n", Err: resp.ErrInvalidInteger},
+		{Name: "integer overflow", In: ":99999999999999999999\r\n", Err: resp.ErrInvalidInteger},
+		{Name: "length overflow", In: "$99999999999999999999\r\n", Err: resp.ErrInvalidBulkStringLength},
+		{Name: "invalid array length", In: "*-2\r\n", Err: resp.ErrInvalidArrayLength},
+		{Name: "invalid bulk string length", In: "$a\r\n", Err: resp.ErrInvalidBulkStringLength},
+		{Name: "invalid map length", In: "%-1\r\n", Err: resp.ErrInvalidMapLength},
+		{Name: "missing \\r", In: "+OK\n", Err: resp.ErrUnexpectedEOL},
+		{Name: "bulk string too long", In: "$2\r\nabc\r\n", Err: resp.ErrUnexpectedEOL},
+		{Name: "invalid boolean", In: "#x\r\n", Err: resp.ErrInvalidBoolean},
+		{Name: "invalid double", In: ",x\r\n", Err: resp.ErrInvalidDouble},
+	} {
+		test := test
+
+		t.Run(test.Name, func(t *testing.T) {
+			in := []byte(test.In)
+			if test.Err == nil {
+				in = append(in, "rest"...)
+			}
+
+			v, rest, err := resp.Parse(in)
+			if err != test.Err {
+				t.Fatalf("got error %v, expected %v", err, test.Err)
+			}
+			if err != nil {
+				if !bytes.Equal(rest, in) {
+					t.Errorf("got rest %q, expected %q", rest, in)
+				}
+				return
+			}
+			if !reflect.DeepEqual(v, test.Expected) {
+				t.Errorf("got %#v, expected %#v", v, test.Expected)
+			}
+			if string(rest) != "rest" {
+				t.Errorf("got rest %q, expected %q", rest, "rest")
+			}
+		})
+	}
+}
+
+func TestParseIncomplete(t *testing.T) {
+	in := []byte("*3\r\n$3\r\nSET\r\n%1\r\n+key\r\n,1.5\r\n~2\r\n#t\r\n_\r\n")
+
+	for i := 0; i < len(in); i++ {
+		if _, _, err := resp.Parse(in[:i]); err != resp.ErrIncomplete {
+			t.Errorf("got error %v for %q, expected %v", err, in[:i], resp.ErrIncomplete)
+		}
+	}
+
+	if _, rest, err := resp.Parse(in); err != nil || len(rest) != 0 {
+		t.Errorf("got rest %q and error %v, expected no rest and no error", rest, err)
+	}
+
+	for _, in := range []string{"$9223372036854775807\r\n", "$9223372036854775806\r\nab", "=9223372036854775807\r\n"} {
+		if _, _, err := resp.Parse([]byte(in)); err != resp.ErrIncomplete {
+			t.Errorf("got error %v for %q, expected %v", err, in, resp.ErrIncomplete)
+		}
+	}
+}
+
+func TestParseDoesNotShareCapacity(t *testing.T) {
+	in := []byte("$5\r\nhello\r\n:1\r\n")
+
+	v, _, err := resp.Parse(in)
+	if err != nil {
+		t.Fatalf("got error %v", err)
+	}
+
+	_ = append(v.Str, '!')
+
+	if string(in) != "$5\r\nhello\r\n:1\r\n" {
+		t.Errorf("input modified to %q", in)
+	}
+}
+
+func TestParseCommand(t *testing.T) {
+	in := []byte("*2\r\n$3\r\nGET\r\n$3\r\nkey\r\n*1\r\n$4\r\nPING\r\n")
+
+	var cmd resp.Command
+
+	rest, err := resp.ParseCommand(in, &cmd)
+	if err != nil {
+		t.Fatalf("got error %v", err)
+	}
+	if got, expected := cmd.Args, [][]byte{[]byte("GET"), []byte("key")}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("got %q, expected %q", got, expected)
+	}
+
+	rest, err = resp.ParseCommand(rest, &cmd)
+	if err != nil {
+		t.Fatalf("got error %v", err)
+	}
+	if got, expected := cmd.Args, [][]byte{[]byte("PING")}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("got %q, expected %q", got, expected)
+	}
+	if len(rest) != 0 {
+		t.Errorf("got rest %q, expected no rest", rest)
+	}
+
+	for _, test := range []struct {
+		In  string
+		Err error
+	}{
+		{"", resp.ErrIncomplete},
+		{"*2\r\n$3\r\nGET\r\n", resp.ErrIncomplete},
+		{"*2\r\n$3\r\nGET\r\n$3\r\nke", resp.ErrIncomplete},
+		{"*1\r\n$9223372036854775806\r\nab", resp.ErrIncomplete},
+		{"*-1\r\n", resp.ErrInvalidCommand},
+		{"*1\r\n:1\r\n", resp.ErrInvalidCommand},
+		{"*1\r\n$-1\r\n", resp.ErrInvalidCommand},
+		{"+PING\r\n", resp.ErrUnexpectedType},
+	} {
+		in := []byte(test.In)
+		if rest, err := resp.ParseCommand(in, &cmd); err != test.Err {
+			t.Errorf("got error %v for %q, expected %v", err, test.In, test.Err)
+		} else if !bytes.Equal(rest, in) {
+			t.Errorf("got rest %q for %q, expected input", rest, test.In)
+		}
+	}
+}
@@ -0,0 +1,442 @@
+package resp
+
+import (
+	"bytes"
+	"math"
+)
+
+// EventKind is the kind of an Event emitted by a Parser.
+type EventKind uint8
+
+const (
+	// EventValue is emitted for a complete value that is neither an aggregate nor a blob string, as well as for null
+	// arrays and null bulk strings.
+	EventValue EventKind = iota + 1
+
+	// EventString is emitted for each part of a blob string (bulk string, blob error or verbatim string).
+	//
+	// Event.More reports whether more parts of the same string will follow.
+	EventString
+
+	// EventStart is emitted at the start of an aggregate (array, attribute, map, push or set).
+	EventStart
+
+	// EventEnd is emitted after the last element of an aggregate.
+	EventEnd
+)
+
+// String implements the fmt.Stringer interface.
+func (k EventKind) String() string {
+	switch k {
+	case EventValue:
+		return "Value"
+	case EventString:
+		return "String"
+	case EventStart:
+		return "Start"
+	case EventEnd:
+		return "End"
+	default:
+		return "Invalid"
+	}
+}
+
+// Event is a single event emitted by a Parser.
+type Event struct {
+	// Kind is the kind of the event.
+	Kind EventKind
+
+	// Type is the type of the value the event belongs to.
+	Type Type
+
+	// Depth is the number of aggregates containing the value, with 0 being a top-level value.
+	Depth int
+
+	// Len is the number of elements for EventStart, where maps and attributes count key-value pairs, and the total
+	// length of the string for EventString.
+	Len int
+
+	// Str is set to the content of line based values and to the current part of blob strings.
+	//
+	// Str references the data passed to Parser.Feed and is only valid until the callback returns.
+	Str []byte
+
+	// Int is set for integers.
+	Int int
+
+	// Float is set for doubles.
+	Float float64
+
+	// Bool is set for booleans.
+	Bool bool
+
+	// Null is set for null values, including null arrays and null bulk strings.
+	Null bool
+
+	// More is set for EventString if more parts of the same string will follow.
+	More bool
+}
+
+type parserState uint8
+
+const (
+	parserStateType parserState = iota
+	parserStateLine
+	parserStateBlob
+	parserStateBlobEOL
+)
+
+type parserFrame struct {
+	typ       Type
+	remaining int
+}
+
+// Parser is an incremental, push-style parser for RESP values.
+//
+// Unlike Reader, a Parser never blocks. Data is passed to Feed as it becomes available, in chunks of arbitrary size,
+// and the Parser calls a user provided function for each parsed event. Values can be split at any position, in which
+// case parsing resumes with the next call to Feed.
+//
+// Blob strings are not buffered but emitted in parts as data arrives, so that the memory used by a Parser does not
+// depend on the size of the parsed values. Only the lines of simple values and headers are buffered when split.
+//
+// Values are parsed using the same rules as used by Reader.ReadValue.
+type Parser struct {
+	fn  func(Event) error
+	err error
+
+	state parserState
+	typ   Type
+	line  []byte
+
+	blobLen   int
+	remaining int
+
+	stack []parserFrame
+}
+
+// NewParser returns a new Parser that calls fn for each parsed event.
+//
+// If fn returns an error, parsing stops and the error is returned by Feed.
+func NewParser(fn func(Event) error) *Parser {
+	return &Parser{fn: fn}
+}
+
+// Reset resets the internal state of the Parser, discarding any partially parsed value and error.
+func (p *Parser) Reset() {
+	p.err = nil
+	p.state = parserStateType
+	p.line = p.line[:0]
+	p.blobLen, p.remaining = 0, 0
+	p.stack = p.stack[:0]
+}
+
+// Err returns the error that broke the Parser, if any.
+func (p *Parser) Err() error {
+	return p.err
+}
+
+// Partial reports whether the Parser is in the middle of a value.
+func (p *Parser) Partial() bool {
+	return p.state != parserStateType || len(p.stack) > 0
+}
+
+// Feed parses the data in b and calls the event function for each event.
+//
+// b may end at any position, including in the middle of a value. The Parser does not retain b after Feed returns.
+//
+// If b contains invalid data or the event function returns an error, the Parser is broken and Feed returns the error.
+// Further calls to Feed return the same error until Reset is called. Events for a blob string may be emitted before
+// an error in the EOL following the string is detected.
+func (p *Parser) Feed(b []byte) error {
+	if p.err != nil {
+		return p.err
+	}
+
+	for len(b) > 0 {
+		var err error
+
+		switch p.state {
+		case parserStateType:
+			b, err = p.feedType(b)
+		case parserStateLine:
+			b, err = p.feedLine(b)
+		case parserStateBlob:
+			b, err = p.feedBlob(b)
+		case parserStateBlobEOL:
+			b, err = p.feedBlobEOL(b)
+		}
+
+		if err != nil {
+			p.err = err
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *Parser) emit(ev Event) error {
+	ev.Depth = len(p.stack)
+	return p.fn(ev)
+}
+
+// done is called after a complete value was parsed and emits EventEnd for all aggregates completed by the value.
+func (p *Parser) done() error {
+	p.state = parserStateType
+
+	for len(p.stack) > 0 {
+		top := &p.stack[len(p.stack)-1]
+		if top.remaining--; top.remaining > 0 {
+			return nil
+		}
+
+		t := top.typ
+		p.stack = p.stack[:len(p.stack)-1]
+
+		if err := p.emit(Event{Kind: EventEnd, Type: t}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *Parser) feedType(b []byte) ([]byte, error) {
+	t := types[b[0]]
+	if t == TypeInvalid {
+		return b, ErrUnexpectedType
+	}
+
+	p.typ = t
+	p.state = parserStateLine
+	return b[1:], nil
+}
+
+func (p *Parser) feedLine(b []byte) ([]byte, error) {
+	i := bytes.IndexByte(b, '\n')
+	if i == -1 {
+		p.line = append(p.line, b...)
+
+		if p.typ == TypeNull && !bytes.HasPrefix([]byte("\r\n"), p.line) {
+			return b, ErrUnexpectedEOL
+		}
+
+		if p.isNumberLine() {
+			// Check what we have so far, so that invalid numbers are detected early and the buffer stays small.
+			sp := sliceParser{b: p.line}
+			if _, err := sp.number(); err != ErrIncomplete {
+				return b, p.numberErr(err)
+			}
+		}
+
+		return nil, nil
+	}
+
+	line, rest := b[:i+1], b[i+1:]
+	if len(p.line) > 0 {
+		p.line = append(p.line, line...)
+		line = p.line
+	}
+
+	err := p.finishLine(line)
+	p.line = p.line[:0]
+	return rest, err
+}
+
+func (p *Parser) isNumberLine() bool {
+	switch p.typ {
+	case TypeArray, TypeAttribute, TypeMap, TypePush, TypeSet,
+		TypeBulkString, TypeBlobError, TypeVerbatimString, TypeInteger:
+		return true
+	default:
+		return false
+	}
+}
+
+// numberErr converts errors from parsing a header to the type specific error.
+func (p *Parser) numberErr(err error) error {
+	if err != ErrInvalidInteger {
+		return err
+	}
+
+	switch p.typ {
+	case TypeArray:
+		return ErrInvalidArrayLength
+	case TypeAttribute:
+		return ErrInvalidAttributeLength
+	case TypeMap:
+		return ErrInvalidMapLength
+	case TypePush:
+		return ErrInvalidPushLength
+	case TypeSet:
+		return ErrInvalidSetLength
+	case TypeBulkString:
+		return ErrInvalidBulkStringLength
+	case TypeBlobError:
+		return ErrInvalidBlobErrorLength
+	case TypeVerbatimString:
+		return ErrInvalidVerbatimStringLength
+	default:
+		return err
+	}
+}
+
+// finishLine handles a complete line, including the EOL marker, for the current type.
+func (p *Parser) finishLine(line []byte) error {
+	t := p.typ
+
+	if p.isNumberLine() {
+		sp := sliceParser{b: line}
+		n, err := sp.number()
+		if err != nil {
+			return p.numberErr(err)
+		}
+
+		switch t {
+		case TypeInteger:
+			if err := p.emit(Event{Kind: EventValue, Type: t, Int: n}); err != nil {
+				return err
+			}
+			return p.done()
+		case TypeArray, TypeSet, TypePush:
+			return p.start(t, n, 1)
+		case TypeMap, TypeAttribute:
+			return p.start(t, n, 2)
+		default:
+			return p.blob(t, n)
+		}
+	}
+
+	if len(line) < 2 || line[len(line)-2] != '\r' {
+		return ErrUnexpectedEOL
+	}
+	s := line[: len(line)-2 : len(line)-2]
+
+	ev := Event{Kind: EventValue, Type: t, Str: s}
+
+	switch t {
+	case TypeDouble:
+		f, err := parseDouble(s)
+		if err != nil {
+			return err
+		}
+		ev.Float = f
+	case TypeBoolean:
+		switch {
+		case len(s) == 1 && s[0] == 't':
+			ev.Bool = true
+		case len(s) == 1 && s[0] == 'f':
+		default:
+			return ErrInvalidBoolean
+		}
+		ev.Str = nil
+	case TypeNull:
+		if len(s) != 0 {
+			return ErrUnexpectedEOL
+		}
+		ev.Str = nil
+		ev.Null = true
+	}
+
+	if err := p.emit(ev); err != nil {
+		return err
+	}
+	return p.done()
+}
+
+// start handles the header of an aggregate of type t with n entries of size elems.
+func (p *Parser) start(t Type, n, elems int) error {
+	min := 0
+	if t == TypeArray {
+		min = -1
+	}
+	if n < min || n > math.MaxInt/elems {
+		return p.numberErr(ErrInvalidInteger)
+	}
+
+	if n == -1 {
+		if err := p.emit(Event{Kind: EventValue, Type: t, Null: true}); err != nil {
+			return err
+		}
+		return p.done()
+	}
+
+	if err := p.emit(Event{Kind: EventStart, Type: t, Len: n}); err != nil {
+		return err
+	}
+
+	if n == 0 {
+		if err := p.emit(Event{Kind: EventEnd, Type: t}); err != nil {
+			return err
+		}
+		return p.done()
+	}
+
+	p.stack = append(p.stack, parserFrame{typ: t, remaining: n * elems})
+	p.state = parserStateType
+	return nil
+}
+
+// blob handles the header of a blob string of type t with length n.
+func (p *Parser) blob(t Type, n int) error {
+	var min int
+	switch t {
+	case TypeBulkString:
+		min = -1
+	case TypeVerbatimString:
+		min = 4
+	}
+	if n < min {
+		return p.numberErr(ErrInvalidInteger)
+	}
+
+	if n == -1 {
+		if err := p.emit(Event{Kind: EventValue, Type: t, Null: true}); err != nil {
+			return err
+		}
+		return p.done()
+	}
+
+	p.blobLen, p.remaining = n, n
+
+	if n == 0 {
+		if err := p.emit(Event{Kind: EventString, Type: t, Str: []byte{}}); err != nil {
+			return err
+		}
+		p.state, p.remaining = parserStateBlobEOL, 2
+		return nil
+	}
+
+	p.state = parserStateBlob
+	return nil
+}
+
+func (p *Parser) feedBlob(b []byte) ([]byte, error) {
+	n := p.remaining
+	if n > len(b) {
+		n = len(b)
+	}
+	p.remaining -= n
+
+	ev := Event{Kind: EventString, Type: p.typ, Len: p.blobLen, Str: b[:n:n], More: p.remaining > 0}
+	if err := p.emit(ev); err != nil {
+		return b, err
+	}
+
+	if p.remaining == 0 {
+		p.state, p.remaining = parserStateBlobEOL, 2
+	}
+	return b[n:], nil
+}
+
+func (p *Parser) feedBlobEOL(b []byte) ([]byte, error) {
+	if b[0] != "\r\n"[2-p.remaining] {
+		return b, ErrUnexpectedEOL
+	}
+
+	if p.remaining--; p.remaining > 0 {
+		return b[1:], nil
+	}
+	return b[1:], p.done()
+}
@@ -0,0 +1,219 @@
+package resp_test
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/nussjustin/resp"
+)
+
+// eventValues builds values from the events emitted by a Parser.
+type eventValues struct {
+	values []resp.Value
+	stack  []resp.Value
+	str    []byte
+}
+
+func (ev *eventValues) add(v resp.Value) {
+	if len(ev.stack) == 0 {
+		ev.values = append(ev.values, v)
+		return
+	}
+	top := &ev.stack[len(ev.stack)-1]
+	top.Elems = append(top.Elems, v)
+}
+
+func (ev *eventValues) handle(e resp.Event) error {
+	depth := len(ev.stack)
+	if e.Kind == resp.EventEnd {
+		depth--
+	}
+	if e.Depth != depth {
+		return errors.New("depth mismatch")
+	}
+
+	switch e.Kind {
+	case resp.EventStart:
+		ev.stack = append(ev.stack, resp.Value{Type: e.Type})
+	case resp.EventEnd:
+		v := ev.stack[len(ev.stack)-1]
+		ev.stack = ev.stack[:len(ev.stack)-1]
+		ev.add(v)
+	case resp.EventString:
+		ev.str = append(ev.str, e.Str...)
+		if e.More {
+			return nil
+		}
+		ev.add(resp.Value{Type: e.Type, Str: ev.str})
+		ev.str = nil
+	case resp.EventValue:
+		var str []byte
+		if e.Str != nil {
+			str = append([]byte{}, e.Str...)
+		}
+		ev.add(resp.Value{Type: e.Type, Str: str, Int: e.Int, Float: e.Float, Bool: e.Bool, Null: e.Null})
+	}
+	return nil
+}
+
+func appendValues(t *testing.T, vs []resp.Value) []byte {
+	t.Helper()
+
+	var b []byte
+	for _, v := range vs {
+		var err error
+		if b, err = resp.AppendValue(b, v); err != nil {
+			t.Fatalf("failed to append value %#v: %s", v, err)
+		}
+	}
+	return b
+}
+
+func TestParser(t *testing.T) {
+	in := []byte("+OK\r\n-ERR failed\r\n:-100\r\n$5\r\nhello\r\n$0\r\n\r\n$-1\r\n*-1\r\n*0\r\n" +
+		"*2\r\n$3\r\nkey\r\n:1\r\n#t\r\n,1.5\r\n_\r\n(12345678901234567890\r\n!9\r\nERR error\r\n" +
+		"=9\r\ntxt:hello\r\n%1\r\n+key\r\n~2\r\n#f\r\n>1\r\n%0\r\n|1\r\n+ttl\r\n:100\r\n$4\r\n\r\n\r\n\r\n")
+
+	var expected []resp.Value
+	for rest := in; len(rest) > 0; {
+		v, r, err := resp.Parse(rest)
+		if err != nil {
+			t.Fatalf("failed to parse %q: %s", rest, err)
+		}
+		expected, rest = append(expected, v), r
+	}
+
+	for size := 1; size <= len(in); size++ {
+		var ev eventValues
+		p := resp.NewParser(ev.handle)
+
+		for b := in; len(b) > 0; {
+			n := size
+			if n > len(b) {
+				n = len(b)
+			}
+			if err := p.Feed(b[:n]); err != nil {
+				t.Fatalf("chunk size %d: got error %v", size, err)
+			}
+			b = b[n:]
+		}
+
+		if p.Partial() {
+			t.Errorf("chunk size %d: got partial value at end of input", size)
+		}
+		if got, want := appendValues(t, ev.values), appendValues(t, expected); !bytes.Equal(got, want) {
+			t.Errorf("chunk size %d: got %q, expected %q", size, got, want)
+		}
+	}
+}
+
+func TestParserEvents(t *testing.T) {
+	var got []resp.Event
+	p := resp.NewParser(func(e resp.Event) error {
+		got = append(got, e)
+		return nil
+	})
+
+	for i, chunk := range []string{"*2\r\n$5\r\nhel", "lo\r", "\n%0\r\n"} {
+		if err := p.Feed([]byte(chunk)); err != nil {
+			t.Fatalf("got error %v", err)
+		}
+		if partial := i < 2; p.Partial() != partial {
+			t.Errorf("got Partial() = %v after chunk %d, expected %v", p.Partial(), i, partial)
+		}
+	}
+
+	expected := []resp.Event{
+		{Kind: resp.EventStart, Type: resp.TypeArray, Len: 2},
+		{Kind: resp.EventString, Type: resp.TypeBulkString, Depth: 1, Len: 5, Str: []byte("hel"), More: true},
+		{Kind: resp.EventString, Type: resp.TypeBulkString, Depth: 1, Len: 5, Str: []byte("lo")},
+		{Kind: resp.EventStart, Type: resp.TypeMap, Depth: 1},
+		{Kind: resp.EventEnd, Type: resp.TypeMap, Depth: 1},
+		{Kind: resp.EventEnd, Type: resp.TypeArray},
+	}
+
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("got events %+v, expected %+v", got, expected)
+	}
+}
+
+func TestParserErrors(t *testing.T) {
+	for _, test := range []struct {
+		Name string
+		In   string
+		Err  error
+	}{
+		{Name: "invalid type", In: "A\r\n", Err: resp.ErrUnexpectedType},
+		{Name: "invalid integer", In: ":1a\r\n", Err: resp.ErrInvalidInteger},
+		{Name: "integer overflow", In: ":99999999999999999999\r\n", Err: resp.ErrInvalidInteger},
+		{Name: "length overflow", In: "$99999999999999999999\r\n", Err: resp.ErrInvalidBulkStringLength},
+		{Name: "invalid array length", In: "*-2\r\n", Err: resp.ErrInvalidArrayLength},
+		{Name: "invalid bulk string length", In: "$a\r\n", Err: resp.ErrInvalidBulkStringLength},
+		{Name: "invalid map length", In: "%-1\r\n", Err: resp.ErrInvalidMapLength},
+		{Name: "invalid verbatim string length", In: "=3\r\ntxt\r\n", Err: resp.ErrInvalidVerbatimStringLength},
+		{Name: "missing \\r", In: "+OK\n", Err: resp.ErrUnexpectedEOL},
+		{Name: "missing \\n", In: ":1\ra", Err: resp.ErrUnexpectedEOL},
+		{Name: "bulk string too long", In: "$2\r\nabc\r\n", Err: resp.ErrUnexpectedEOL},
+		{Name: "invalid boolean", In: "#x\r\n", Err: resp.ErrInvalidBoolean},
+		{Name: "invalid double", In: ",x\r\n", Err: resp.ErrInvalidDouble},
+		{Name: "invalid null", In: "_a\r\n", Err: resp.ErrUnexpectedEOL},
+		{Name: "invalid nested value", In: "*2\r\n:1\r\n:x\r\n", Err: resp.ErrInvalidInteger},
+	} {
+		test := test
+
+		t.Run(test.Name, func(t *testing.T) {
+			for _, size := range []int{1, len(test.In)} {
+				p := resp.NewParser(func(resp.Event) error { return nil })
+
+				var err error
+				for b := []byte(test.In); len(b) > 0 && err == nil; {
+					n := size
+					if n > len(b) {
+						n = len(b)
+					}
+					err, b = p.Feed(b[:n]), b[n:]
+				}
+
+				if err != test.Err {
+					t.Fatalf("chunk size %d: got error %v, expected %v", size, err, test.Err)
+				}
+				if p.Err() != test.Err {
+					t.Fatalf("chunk size %d: got Err() = %v, expected %v", size, p.Err(), test.Err)
+				}
+				if err := p.Feed([]byte("+OK\r\n")); err != test.Err {
+					t.Fatalf("chunk size %d: got error %v from broken Parser, expected %v", size, err, test.Err)
+				}
+
+				p.Reset()
+
+				if err := p.Feed([]byte("+OK\r\n")); err != nil {
+					t.Fatalf("chunk size %d: got error %v after Reset", size, err)
+				}
+			}
+		})
+	}
+}
+
+func TestParserHandlerError(t *testing.T) {
+	errHandler := errors.New("handler error")
+
+	var n int
+	p := resp.NewParser(func(resp.Event) error {
+		if n++; n == 2 {
+			return errHandler
+		}
+		return nil
+	})
+
+	if err := p.Feed([]byte("+a\r\n+b\r\n+c\r\n")); err != errHandler {
+		t.Fatalf("got error %v, expected %v", err, errHandler)
+	}
+	if n != 2 {
+		t.Errorf("got %d events, expected 2", n)
+	}
+	if err := p.Feed([]byte("+d\r\n")); err != errHandler {
+		t.Errorf("got error %v from broken Parser, expected %v", err, errHandler)
+	}
+}
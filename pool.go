@@ -0,0 +1,266 @@
+package resp
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrPoolClosed is returned by Pool.Get after the Pool was closed.
+var ErrPoolClosed = errors.New("pool is closed")
+
+// DefaultMaxIdle is the maximum number of idle connections kept by a Pool if Pool.MaxIdle is 0.
+const DefaultMaxIdle = 2
+
+// PoolStats contains statistics about a Pool.
+type PoolStats struct {
+	// Idle is the number of idle connections.
+	Idle int
+
+	// InUse is the number of connections returned by Get that were not yet put back.
+	InUse int
+}
+
+// Pool is a pool of connections.
+//
+// Connections are returned by Get and must be given back to the Pool using Put once they are not used anymore.
+// Connections that are broken, have unread or unflushed data, or have sent more commands using Conn.Send than values
+// were read using Conn.Receive when given back are closed instead of being reused.
+//
+// A Pool must not be copied after first use. All methods are safe for concurrent use.
+type Pool struct {
+	// Dial is called to create new connections. It must not be nil.
+	Dial func(ctx context.Context) (*Conn, error)
+
+	// MaxIdle is the maximum number of idle connections kept by the Pool.
+	//
+	// If MaxIdle is 0, DefaultMaxIdle is used. If MaxIdle is negative, no idle connections are kept.
+	MaxIdle int
+
+	// MaxActive is the maximum number of connections that can be in use at the same time. If the limit is reached,
+	// Get waits until a connection is put back or the context passed to Get is done.
+	//
+	// If MaxActive is <= 0, the number of connections is not limited.
+	MaxActive int
+
+	// IdleTimeout is the maximum duration a connection can stay idle before it is closed.
+	//
+	// If IdleTimeout is <= 0, idle connections are not closed because of their age.
+	IdleTimeout time.Duration
+
+	// PingInterval is the duration after which idle connections are checked using PING before being returned by Get.
+	// Connections that fail the check are closed.
+	//
+	// If PingInterval is 0, all idle connections are checked, so every Get that reuses a connection sends a PING and
+	// waits for the reply. If PingInterval is negative no checks are done.
+	PingInterval time.Duration
+
+	mu     sync.Mutex
+	closed bool
+	idle   []idleConn
+	inUse  int
+	active map[*Conn]struct{}
+	sem    chan struct{}
+}
+
+type idleConn struct {
+	c     *Conn
+	since time.Time
+}
+
+func (p *Pool) maxIdle() int {
+	switch {
+	case p.MaxIdle == 0:
+		return DefaultMaxIdle
+	case p.MaxIdle < 0:
+		return 0
+	default:
+		return p.MaxIdle
+	}
+}
+
+func (p *Pool) acquire(ctx context.Context) error {
+	if p.MaxActive <= 0 {
+		return nil
+	}
+
+	p.mu.Lock()
+	if p.sem == nil {
+		p.sem = make(chan struct{}, p.MaxActive)
+	}
+	sem := p.sem
+	p.mu.Unlock()
+
+	select {
+	case sem <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *Pool) release() {
+	p.mu.Lock()
+	p.inUse--
+	sem := p.sem
+	p.mu.Unlock()
+
+	if sem != nil {
+		<-sem
+	}
+}
+
+// Get returns an idle connection or creates a new connection using Dial.
+func (p *Pool) Get(ctx context.Context) (*Conn, error) {
+	if err := p.acquire(ctx); err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	p.inUse++
+	p.mu.Unlock()
+
+	for {
+		c, since, err := p.popIdle()
+		if err != nil {
+			p.release()
+			return nil, err
+		}
+		if c == nil {
+			break
+		}
+		if p.PingInterval >= 0 && time.Since(since) >= p.PingInterval {
+			if _, err := c.Do(ctx, "PING"); err != nil {
+				_ = c.Close()
+				if cerr := ctx.Err(); cerr != nil {
+					p.release()
+					return nil, cerr
+				}
+				continue
+			}
+		}
+		p.track(c)
+		return c, nil
+	}
+
+	c, err := p.Dial(ctx)
+	if err != nil {
+		p.release()
+		return nil, err
+	}
+	p.track(c)
+	return c, nil
+}
+
+// track marks c as returned by Get.
+func (p *Pool) track(c *Conn) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.active == nil {
+		p.active = make(map[*Conn]struct{})
+	}
+	p.active[c] = struct{}{}
+}
+
+// popIdle returns the most recently used idle connection, closing all connections that exceeded the IdleTimeout.
+func (p *Pool) popIdle() (*Conn, time.Time, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return nil, time.Time{}, ErrPoolClosed
+	}
+
+	p.closeStaleLocked()
+
+	n := len(p.idle)
+	if n == 0 {
+		return nil, time.Time{}, nil
+	}
+
+	ic := p.idle[n-1]
+	p.idle[n-1] = idleConn{}
+	p.idle = p.idle[:n-1]
+	return ic.c, ic.since, nil
+}
+
+// closeStaleLocked closes all idle connections that exceeded the IdleTimeout. p.mu must be held.
+func (p *Pool) closeStaleLocked() {
+	if p.IdleTimeout <= 0 {
+		return
+	}
+
+	// p.idle is ordered from least to most recently used
+	var n int
+	for n < len(p.idle) && time.Since(p.idle[n].since) >= p.IdleTimeout {
+		_ = p.idle[n].c.Close()
+		n++
+	}
+	if n > 0 {
+		copy(p.idle, p.idle[n:])
+		for i := len(p.idle) - n; i < len(p.idle); i++ {
+			p.idle[i] = idleConn{}
+		}
+		p.idle = p.idle[:len(p.idle)-n]
+	}
+}
+
+// Put gives the connection c, which must have been returned by Get, back to the Pool.
+//
+// If the connection can not be reused (see Pool), or if the Pool already has MaxIdle idle connections, the connection
+// is closed.
+//
+// If c was not returned by Get or was already put back, Put panics.
+func (p *Pool) Put(c *Conn) {
+	p.mu.Lock()
+	if _, ok := p.active[c]; !ok {
+		p.mu.Unlock()
+		panic("resp: Put called with a connection that is not in use")
+	}
+	delete(p.active, c)
+	p.mu.Unlock()
+
+	defer p.release()
+
+	if c.Err() != nil || c.pending > 0 || c.rw.Reader.Buffered() > 0 || c.bw.Buffered() > 0 {
+		_ = c.Close()
+		return
+	}
+
+	p.mu.Lock()
+	p.closeStaleLocked()
+	if p.closed || len(p.idle) >= p.maxIdle() {
+		p.mu.Unlock()
+		_ = c.Close()
+		return
+	}
+	p.idle = append(p.idle, idleConn{c: c, since: time.Now()})
+	p.mu.Unlock()
+}
+
+// Stats returns statistics about the Pool.
+func (p *Pool) Stats() PoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return PoolStats{Idle: len(p.idle), InUse: p.inUse}
+}
+
+// Close closes all idle connections and marks the Pool as closed.
+//
+// Connections that are in use are closed when they are put back.
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	idle := p.idle
+	p.idle, p.closed = nil, true
+	p.mu.Unlock()
+
+	var err error
+	for _, ic := range idle {
+		if cerr := ic.c.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
@@ -0,0 +1,246 @@
+package resp_test
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nussjustin/resp"
+)
+
+func newTestPool(tb testing.TB, fn func(req resp.Value) resp.Value) (*resp.Pool, *int32) {
+	var dials int32
+	p := &resp.Pool{
+		Dial: func(ctx context.Context) (*resp.Conn, error) {
+			atomic.AddInt32(&dials, 1)
+			return newTestConn(tb, fn), nil
+		},
+	}
+	tb.Cleanup(func() { _ = p.Close() })
+	return p, &dials
+}
+
+func pingHandler(req resp.Value) resp.Value {
+	if string(req.Elems[0].Str) == "PING" {
+		return resp.SimpleString("PONG")
+	}
+	return echoHandler(req)
+}
+
+func TestPoolGetPut(t *testing.T) {
+	p, dials := newTestPool(t, pingHandler)
+	ctx := context.Background()
+
+	c1, err := p.Get(ctx)
+	if err != nil {
+		t.Fatalf("get failed: %s", err)
+	}
+	c2, err := p.Get(ctx)
+	if err != nil {
+		t.Fatalf("get failed: %s", err)
+	}
+
+	if stats := p.Stats(); stats != (resp.PoolStats{InUse: 2}) {
+		t.Errorf("got stats %+v, expected 2 connections in use", stats)
+	}
+
+	p.Put(c1)
+	p.Put(c2)
+
+	if stats := p.Stats(); stats != (resp.PoolStats{Idle: 2}) {
+		t.Errorf("got stats %+v, expected 2 idle connections", stats)
+	}
+
+	c3, err := p.Get(ctx)
+	if err != nil {
+		t.Fatalf("get failed: %s", err)
+	}
+	if c3 != c2 {
+		t.Error("expected most recently used connection to be reused")
+	}
+	if _, err := c3.Do(ctx, "ECHO", "hello"); err != nil {
+		t.Errorf("do failed: %s", err)
+	}
+	p.Put(c3)
+
+	if n := atomic.LoadInt32(dials); n != 2 {
+		t.Errorf("got %d dials, expected 2", n)
+	}
+}
+
+func TestPoolMaxIdle(t *testing.T) {
+	p, _ := newTestPool(t, pingHandler)
+	p.MaxIdle = 1
+	ctx := context.Background()
+
+	c1, _ := p.Get(ctx)
+	c2, _ := p.Get(ctx)
+	p.Put(c1)
+	p.Put(c2)
+
+	if stats := p.Stats(); stats.Idle != 1 {
+		t.Errorf("got %d idle connections, expected 1", stats.Idle)
+	}
+	if c2.Err() == nil {
+		t.Error("expected connection exceeding MaxIdle to be closed")
+	}
+}
+
+func TestPoolDiscardBroken(t *testing.T) {
+	p, dials := newTestPool(t, pingHandler)
+	ctx := context.Background()
+
+	c, _ := p.Get(ctx)
+	_ = c.NetConn().Close()
+	if _, err := c.Do(ctx, "ECHO", "x"); err == nil {
+		t.Fatal("expected error from closed connection")
+	}
+	p.Put(c)
+
+	if stats := p.Stats(); stats.Idle != 0 {
+		t.Errorf("got %d idle connections, expected 0", stats.Idle)
+	}
+
+	c, _ = p.Get(ctx)
+	if err := c.Send(ctx, "ECHO", "unread"); err != nil {
+		t.Fatalf("send failed: %s", err)
+	}
+	p.Put(c)
+
+	if stats := p.Stats(); stats.Idle != 0 {
+		t.Errorf("got %d idle connections, expected connection with pending reply to be discarded", stats.Idle)
+	}
+
+	if n := atomic.LoadInt32(dials); n != 2 {
+		t.Errorf("got %d dials, expected 2", n)
+	}
+}
+
+func TestPoolPing(t *testing.T) {
+	var closeServer func()
+
+	p := &resp.Pool{
+		Dial: func(ctx context.Context) (*resp.Conn, error) {
+			client, server := net.Pipe()
+			closeServer = func() { _ = server.Close() }
+			go serveConn(t, server, pingHandler)
+			return resp.NewConn(client), nil
+		},
+	}
+	defer p.Close()
+
+	ctx := context.Background()
+
+	c1, _ := p.Get(ctx)
+	p.Put(c1)
+
+	closeServer()
+
+	c2, err := p.Get(ctx)
+	if err != nil {
+		t.Fatalf("get failed: %s", err)
+	}
+	if c1 == c2 {
+		t.Fatal("expected connection failing the health check to be replaced")
+	}
+	if _, err := c2.Do(ctx, "PING"); err != nil {
+		t.Errorf("do failed: %s", err)
+	}
+	p.Put(c2)
+}
+
+func TestPoolIdleTimeout(t *testing.T) {
+	p, dials := newTestPool(t, pingHandler)
+	p.IdleTimeout = 10 * time.Millisecond
+	ctx := context.Background()
+
+	c, _ := p.Get(ctx)
+	p.Put(c)
+
+	time.Sleep(20 * time.Millisecond)
+
+	c, _ = p.Get(ctx)
+	p.Put(c)
+
+	if n := atomic.LoadInt32(dials); n != 2 {
+		t.Errorf("got %d dials, expected 2", n)
+	}
+}
+
+func TestPoolMaxActive(t *testing.T) {
+	p, _ := newTestPool(t, pingHandler)
+	p.MaxActive = 1
+
+	c, err := p.Get(context.Background())
+	if err != nil {
+		t.Fatalf("get failed: %s", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if _, err := p.Get(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("got error %v, expected %v", err, context.DeadlineExceeded)
+	}
+
+	time.AfterFunc(10*time.Millisecond, func() { p.Put(c) })
+
+	c, err = p.Get(context.Background())
+	if err != nil {
+		t.Fatalf("get failed: %s", err)
+	}
+	p.Put(c)
+}
+
+func TestPoolClose(t *testing.T) {
+	p, _ := newTestPool(t, pingHandler)
+	ctx := context.Background()
+
+	c1, _ := p.Get(ctx)
+	c2, _ := p.Get(ctx)
+	p.Put(c1)
+
+	if err := p.Close(); err != nil {
+		t.Fatalf("close failed: %s", err)
+	}
+	if c1.Err() == nil {
+		t.Error("expected idle connection to be closed")
+	}
+
+	p.Put(c2)
+	if c2.Err() == nil {
+		t.Error("expected connection put back after close to be closed")
+	}
+
+	if _, err := p.Get(ctx); err != resp.ErrPoolClosed {
+		t.Errorf("got error %v, expected %v", err, resp.ErrPoolClosed)
+	}
+}
+
+func TestPoolPutNotInUse(t *testing.T) {
+	p, _ := newTestPool(t, pingHandler)
+	p.MaxActive = 1
+
+	c, err := p.Get(context.Background())
+	if err != nil {
+		t.Fatalf("get failed: %s", err)
+	}
+	p.Put(c)
+
+	for _, c := range []*resp.Conn{c, newTestConn(t, pingHandler)} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Error("expected panic")
+				}
+			}()
+			p.Put(c)
+		}()
+	}
+
+	if stats := p.Stats(); stats != (resp.PoolStats{Idle: 1}) {
+		t.Errorf("got stats %+v, expected 1 idle connection", stats)
+	}
+}
@@ -0,0 +1,166 @@
+package resp
+
+import (
+	"context"
+	"net"
+)
+
+// Proxy is a Handler that forwards commands to an upstream server and copies the replies back to the client.
+//
+// Each client connection uses its own upstream connection, which is created when the first command is forwarded and
+// closed together with the client connection. This way per-connection state like the selected database,
+// authentication or transactions works as if the client were connected to the upstream server directly.
+//
+// Replies are forwarded using Reader.ReadRaw without decoding them, unless ModifyReply is set. RESP3 attributes are
+// forwarded together with the reply they describe, while push frames received before a reply are not treated as reply
+// and instead forwarded to the client as they arrive.
+//
+// Because each forwarded command must result in exactly one reply, commands that put the connection into a mode
+// with a different number of replies, like SUBSCRIBE or MONITOR, are not supported and should be rejected using
+// Intercept.
+type Proxy struct {
+	// Dial creates a new connection to the upstream server. It must not be nil.
+	//
+	// The given context is canceled when the client connection is closed.
+	Dial func(ctx context.Context) (net.Conn, error)
+
+	// Intercept, if not nil, is called for each command before it is forwarded.
+	//
+	// Intercept can modify cmd.Args, for example to add a prefix to all keys. To reject a command or to answer it
+	// locally, Intercept writes a reply to w and returns true, in which case the command is not forwarded.
+	Intercept func(w ReplyWriter, cmd *Command) (handled bool)
+
+	// ModifyReply, if not nil, is called with the reply for each forwarded command. The returned value is written
+	// to the client instead of the original reply.
+	ModifyReply func(cmd *Command, v Value) Value
+}
+
+var _ Handler = (*Proxy)(nil)
+
+// proxyConnKey is the key used for storing the upstream connection of a Proxy on a ServerConn.
+type proxyConnKey struct {
+	p *Proxy
+}
+
+// proxyConn is an upstream connection used by a Proxy.
+type proxyConn struct {
+	conn net.Conn
+	rr   Reader
+	buf  []byte
+	push []byte
+}
+
+// ServeRESP implements the Handler interface.
+func (p *Proxy) ServeRESP(w ReplyWriter, cmd *Command) {
+	if p.Intercept != nil && p.Intercept(w, cmd) {
+		return
+	}
+
+	pc, err := p.upstream(cmd)
+	if err != nil {
+		p.fail(w, cmd, err)
+		return
+	}
+	if cmd.Conn() == nil {
+		defer pc.conn.Close()
+	}
+
+	pc.buf = AppendArrayHeader(pc.buf[:0], len(cmd.Args))
+	for _, arg := range cmd.Args {
+		pc.buf = appendBlobBytes(pc.buf, '$', arg)
+	}
+	if _, err := pc.conn.Write(pc.buf); err != nil {
+		p.fail(w, cmd, err)
+		return
+	}
+
+	// read attributes preceding the reply, so that they are written together with the reply
+	pc.buf = pc.buf[:0]
+	for {
+		t, err := pc.rr.Peek()
+		if err != nil {
+			p.fail(w, cmd, err)
+			return
+		}
+		if t == TypePush {
+			if pc.push, err = pc.rr.ReadRaw(pc.push[:0]); err != nil {
+				p.fail(w, cmd, err)
+				return
+			}
+			_, _ = w.Write(pc.push)
+			continue
+		}
+		if t != TypeAttribute {
+			break
+		}
+		if pc.buf, err = pc.rr.ReadRaw(pc.buf); err != nil {
+			p.fail(w, cmd, err)
+			return
+		}
+	}
+
+	if p.ModifyReply != nil {
+		v, err := pc.rr.ReadValue()
+		if err != nil {
+			p.fail(w, cmd, err)
+			return
+		}
+		_, _ = w.Write(pc.buf)
+		_, _ = w.WriteValue(p.ModifyReply(cmd, v))
+		return
+	}
+
+	if pc.buf, err = pc.rr.ReadRaw(pc.buf); err != nil {
+		p.fail(w, cmd, err)
+		return
+	}
+	_, _ = w.Write(pc.buf)
+}
+
+// upstream returns the upstream connection for the client connection of cmd, creating it if necessary.
+//
+// For commands that were not read by a Server, a new connection is returned each time.
+func (p *Proxy) upstream(cmd *Command) (*proxyConn, error) {
+	c := cmd.Conn()
+	if c != nil {
+		if pc, ok := c.Value(proxyConnKey{p}).(*proxyConn); ok {
+			return pc, nil
+		}
+	}
+
+	ctx := cmd.Context()
+
+	conn, err := p.Dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	pc := &proxyConn{conn: conn}
+	pc.rr.Reset(conn)
+
+	// closing the connection also unblocks a handler waiting for a reply when the client connection is closed
+	if done := ctx.Done(); done != nil {
+		go func() {
+			<-done
+			_ = conn.Close()
+		}()
+	}
+
+	if c != nil {
+		c.SetValue(proxyConnKey{p}, pc)
+	}
+	return pc, nil
+}
+
+// fail replies with an error and closes the client connection, since the state of the upstream connection is unknown.
+func (p *Proxy) fail(w ReplyWriter, cmd *Command, err error) {
+	_, _ = w.WriteError("ERR proxy: " + err.Error())
+
+	if c := cmd.Conn(); c != nil {
+		if pc, ok := c.Value(proxyConnKey{p}).(*proxyConn); ok {
+			_ = pc.conn.Close()
+			c.SetValue(proxyConnKey{p}, nil)
+		}
+		c.Close()
+	}
+}
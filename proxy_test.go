@@ -0,0 +1,179 @@
+package resp_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/nussjustin/resp"
+	"github.com/nussjustin/resp/resptest"
+)
+
+func newTestProxy(tb testing.TB, p *resp.Proxy) (*resptest.Server, *resp.Conn) {
+	upstream := resptest.NewServer()
+	tb.Cleanup(upstream.Close)
+
+	if p.Dial == nil {
+		p.Dial = func(ctx context.Context) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "tcp", upstream.Addr)
+		}
+	}
+
+	_, addr := newTestServer(tb, p)
+	return upstream, dialTestServer(tb, addr)
+}
+
+func TestProxy(t *testing.T) {
+	_, c := newTestProxy(t, &resp.Proxy{})
+
+	ctx := context.Background()
+
+	for _, test := range []struct {
+		Args     []interface{}
+		Expected resp.Value
+	}{
+		{Args: []interface{}{"SET", "key", "value"}, Expected: resp.SimpleString("OK")},
+		{Args: []interface{}{"GET", "key"}, Expected: resp.BulkString("value")},
+		{Args: []interface{}{"MULTI"}, Expected: resp.SimpleString("OK")},
+		{Args: []interface{}{"GET", "key"}, Expected: resp.SimpleString("QUEUED")},
+		{Args: []interface{}{"EXEC"}, Expected: resp.Array(resp.BulkString("value"))},
+		{Args: []interface{}{"UNKNOWN"}, Expected: resp.Error("ERR unknown command 'UNKNOWN', with args beginning with: ")},
+	} {
+		v, err := c.Do(ctx, test.Args...)
+		if err != nil && err != v.Err() {
+			t.Fatalf("%v: got error %v", test.Args, err)
+		}
+		assertValue(t, v, test.Expected)
+	}
+}
+
+func TestProxyIntercept(t *testing.T) {
+	upstream, c := newTestProxy(t, &resp.Proxy{
+		Intercept: func(w resp.ReplyWriter, cmd *resp.Command) bool {
+			switch {
+			case cmd.Is("FLUSHALL"):
+				_, _ = w.WriteError("ERR FLUSHALL is not allowed")
+				return true
+			case cmd.Is("PING"):
+				_, _ = w.WriteSimpleString("PONG from proxy")
+				return true
+			case len(cmd.Args) > 1:
+				cmd.Args[1] = append([]byte("tenant:"), cmd.Args[1]...)
+			}
+			return false
+		},
+	})
+
+	ctx := context.Background()
+
+	if _, err := c.Do(ctx, "SET", "key", "value"); err != nil {
+		t.Fatalf("got error %v", err)
+	}
+	if v, _ := c.Do(ctx, "FLUSHALL"); v.Err() == nil {
+		t.Fatalf("got %#v, expected error reply", v)
+	}
+	if v, err := c.Do(ctx, "PING"); err != nil || string(v.Str) != "PONG from proxy" {
+		t.Fatalf("got %#v (error %v), expected local reply", v, err)
+	}
+
+	uc, err := upstream.Dial(ctx)
+	if err != nil {
+		t.Fatalf("failed to connect to upstream: %s", err)
+	}
+	defer uc.Close()
+
+	v, err := uc.Do(ctx, "GET", "tenant:key")
+	if err != nil {
+		t.Fatalf("got error %v", err)
+	}
+	assertValue(t, v, resp.BulkString("value"))
+}
+
+func TestProxyModifyReply(t *testing.T) {
+	_, c := newTestProxy(t, &resp.Proxy{
+		ModifyReply: func(cmd *resp.Command, v resp.Value) resp.Value {
+			if cmd.Is("GET") && v.IsNull() {
+				return resp.BulkString("default")
+			}
+			return v
+		},
+	})
+
+	v, err := c.Do(context.Background(), "GET", "missing")
+	if err != nil {
+		t.Fatalf("got error %v", err)
+	}
+	assertValue(t, v, resp.BulkString("default"))
+}
+
+func TestProxyAttributesAndPush(t *testing.T) {
+	push := resp.Push(resp.BulkString("invalidate"), resp.Array(resp.BulkString("key")))
+	attr := resp.Value{Type: resp.TypeAttribute, Elems: []resp.Value{resp.SimpleString("ttl"), resp.Integer(10)}}
+
+	_, c := newTestProxy(t, &resp.Proxy{
+		Dial: func(ctx context.Context) (net.Conn, error) {
+			client, server := net.Pipe()
+			go func() {
+				defer server.Close()
+
+				rw := resp.NewReadWriter(server)
+				for {
+					if _, err := rw.ReadValue(); err != nil {
+						return
+					}
+					_, _ = rw.WriteValue(push)
+					_, _ = rw.WriteValue(attr)
+					_, _ = rw.WriteValue(resp.BulkString("value"))
+				}
+			}()
+			return client, nil
+		},
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for i := 0; i < 2; i++ {
+		if err := c.Send(ctx, "GET", "key"); err != nil {
+			t.Fatalf("send failed: %s", err)
+		}
+		for _, expected := range []resp.Value{push, attr, resp.BulkString("value")} {
+			v, err := c.Receive(ctx)
+			if err != nil {
+				t.Fatalf("receive failed: %s", err)
+			}
+			assertValue(t, v, expected)
+		}
+	}
+}
+
+func TestProxyDialError(t *testing.T) {
+	errDial := errors.New("dial failed")
+
+	_, c := newTestProxy(t, &resp.Proxy{
+		Dial: func(ctx context.Context) (net.Conn, error) {
+			return nil, errDial
+		},
+	})
+
+	v, _ := c.Do(context.Background(), "GET", "key")
+	assertValue(t, v, resp.Error("ERR proxy: dial failed"))
+
+	if _, err := c.Do(context.Background(), "GET", "key"); err == nil || errors.As(err, new(resp.ReplyError)) {
+		t.Fatalf("got error %v after failed command, expected connection to be closed", err)
+	}
+}
+
+func assertValue(tb testing.TB, got, expected resp.Value) {
+	tb.Helper()
+
+	gotb, _ := resp.AppendValue(nil, got)
+	expectedb, _ := resp.AppendValue(nil, expected)
+	if !bytes.Equal(gotb, expectedb) {
+		tb.Errorf("got %q, expected %q", gotb, expectedb)
+	}
+}
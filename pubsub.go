@@ -0,0 +1,249 @@
+package resp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+)
+
+// ErrInvalidMessage is returned by PubSub when a received value is not a valid Pub/Sub message.
+var ErrInvalidMessage = errors.New("invalid pub/sub message")
+
+// Message kinds as sent by the server.
+const (
+	MessageKindMessage      = "message"
+	MessageKindPMessage     = "pmessage"
+	MessageKindSMessage     = "smessage"
+	MessageKindSubscribe    = "subscribe"
+	MessageKindPSubscribe   = "psubscribe"
+	MessageKindSSubscribe   = "ssubscribe"
+	MessageKindUnsubscribe  = "unsubscribe"
+	MessageKindPUnsubscribe = "punsubscribe"
+	MessageKindSUnsubscribe = "sunsubscribe"
+	MessageKindPong         = "pong"
+)
+
+// Message is a single message received by a PubSub.
+type Message struct {
+	// Kind is the kind of the message, for example MessageKindMessage or MessageKindSubscribe.
+	//
+	// Push messages of unknown kinds are returned with only Kind set.
+	Kind string
+
+	// Channel is the channel the message was published to or the channel that was (un)subscribed.
+	//
+	// Channel is empty for pong messages and for unsubscribe messages sent when there were no subscriptions.
+	Channel string
+
+	// Pattern is the pattern that matched the channel for pmessage messages or the pattern that was (un)subscribed
+	// for psubscribe and punsubscribe messages.
+	Pattern string
+
+	// Payload is the published message for message, pmessage and smessage messages or the argument to PING for pong
+	// messages.
+	Payload []byte
+
+	// Count is the number of active subscriptions for subscribe and unsubscribe messages.
+	Count int
+}
+
+// PubSub subscribes to channels on a Conn and receives the published messages.
+//
+// Both RESP2 arrays and RESP3 push messages are supported.
+//
+// After subscribing, the Conn must not be used for other commands until all subscriptions are removed and must not be
+// given back to a Pool.
+//
+// A PubSub must not be used concurrently from multiple goroutines.
+type PubSub struct {
+	c *Conn
+
+	// subs is the number of channel and pattern subscriptions and ssubs the number of shard channel subscriptions, as
+	// reported by the last (un)subscribe message of each kind.
+	subs, ssubs int
+
+	// pings is the number of sent pings for which no pong was received yet.
+	pings int
+}
+
+// NewPubSub returns a new PubSub using the given Conn.
+func NewPubSub(c *Conn) *PubSub {
+	return &PubSub{c: c}
+}
+
+// Conn returns the Conn used by the PubSub.
+func (ps *PubSub) Conn() *Conn {
+	return ps.c
+}
+
+// Subscribe subscribes to the given channels.
+//
+// Subscribe does not wait for the subscriptions to be confirmed. Confirmations are returned by Receive.
+func (ps *PubSub) Subscribe(ctx context.Context, channels ...string) error {
+	return ps.send(ctx, "SUBSCRIBE", channels)
+}
+
+// PSubscribe subscribes to the given patterns.
+func (ps *PubSub) PSubscribe(ctx context.Context, patterns ...string) error {
+	return ps.send(ctx, "PSUBSCRIBE", patterns)
+}
+
+// SSubscribe subscribes to the given shard channels.
+func (ps *PubSub) SSubscribe(ctx context.Context, channels ...string) error {
+	return ps.send(ctx, "SSUBSCRIBE", channels)
+}
+
+// Unsubscribe unsubscribes from the given channels or from all channels if no channel is given.
+func (ps *PubSub) Unsubscribe(ctx context.Context, channels ...string) error {
+	return ps.send(ctx, "UNSUBSCRIBE", channels)
+}
+
+// PUnsubscribe unsubscribes from the given patterns or from all patterns if no pattern is given.
+func (ps *PubSub) PUnsubscribe(ctx context.Context, patterns ...string) error {
+	return ps.send(ctx, "PUNSUBSCRIBE", patterns)
+}
+
+// SUnsubscribe unsubscribes from the given shard channels or from all shard channels if no channel is given.
+func (ps *PubSub) SUnsubscribe(ctx context.Context, channels ...string) error {
+	return ps.send(ctx, "SUNSUBSCRIBE", channels)
+}
+
+// Ping sends a PING with an optional payload. The reply is returned by Receive as message of kind MessageKindPong.
+func (ps *PubSub) Ping(ctx context.Context, payload string) error {
+	var err error
+	if payload == "" {
+		err = ps.send(ctx, "PING", nil)
+	} else {
+		err = ps.send(ctx, "PING", []string{payload})
+	}
+	if err == nil {
+		ps.pings++
+	}
+	return err
+}
+
+func (ps *PubSub) send(ctx context.Context, cmd string, names []string) error {
+	args := make([]interface{}, 0, 1+len(names))
+	args = append(args, cmd)
+	for _, name := range names {
+		args = append(args, name)
+	}
+	return ps.c.Send(ctx, args...)
+}
+
+// Receive reads the next message.
+//
+// If the server replied with an error, the error is returned as ReplyError. Like Conn.Receive, Receive does not break
+// the connection if ctx is done before a message was received.
+func (ps *PubSub) Receive(ctx context.Context) (Message, error) {
+	v, err := ps.c.Receive(ctx)
+	if err != nil {
+		return Message{}, err
+	}
+	if err := v.Err(); err != nil {
+		return Message{}, err
+	}
+
+	m, err := parseMessage(v, ps.pings > 0)
+	if err != nil {
+		return Message{}, err
+	}
+
+	switch m.Kind {
+	case MessageKindPong:
+		if ps.pings > 0 {
+			ps.pings--
+		}
+	case MessageKindSubscribe, MessageKindPSubscribe, MessageKindUnsubscribe, MessageKindPUnsubscribe:
+		ps.subs = m.Count
+	case MessageKindSSubscribe, MessageKindSUnsubscribe:
+		ps.ssubs = m.Count
+	}
+	return m, nil
+}
+
+// Run calls fn for each received message until the number of subscriptions drops to zero, ctx is done, fn returns an
+// error or receiving a message fails.
+//
+// Channel and pattern subscriptions are counted separately from shard channel subscriptions, so Run only stops once
+// both kinds of subscriptions were removed.
+//
+// Run returns nil if all subscriptions were removed, otherwise the error that stopped it.
+func (ps *PubSub) Run(ctx context.Context, fn func(Message) error) error {
+	for {
+		m, err := ps.Receive(ctx)
+		if err != nil {
+			return err
+		}
+		if err := fn(m); err != nil {
+			return err
+		}
+		switch m.Kind {
+		case MessageKindUnsubscribe, MessageKindPUnsubscribe, MessageKindSUnsubscribe:
+			if ps.subs == 0 && ps.ssubs == 0 {
+				return nil
+			}
+		}
+	}
+}
+
+// parseMessage converts a RESP2 array or RESP3 push message into a Message.
+//
+// If pingPending is true, a simple string PONG or a bulk string is accepted as the reply to a PING.
+func parseMessage(v Value, pingPending bool) (Message, error) {
+	// RESP3 servers reply to PING with a normal reply, even when subscribed
+	switch {
+	case !pingPending:
+	case v.Type == TypeSimpleString && bytes.EqualFold(v.Str, []byte("PONG")):
+		return Message{Kind: MessageKindPong}, nil
+	case v.Type == TypeBulkString && !v.Null:
+		return Message{Kind: MessageKindPong, Payload: v.Str}, nil
+	}
+
+	if (v.Type != TypeArray && v.Type != TypePush) || len(v.Elems) == 0 || !isStringValue(v.Elems[0]) {
+		return Message{}, ErrInvalidMessage
+	}
+
+	elems := v.Elems
+	m := Message{Kind: string(bytes.ToLower(elems[0].Str))}
+
+	switch m.Kind {
+	case MessageKindMessage, MessageKindSMessage:
+		if len(elems) != 3 || !isStringValue(elems[1]) || !isStringValue(elems[2]) {
+			return Message{}, ErrInvalidMessage
+		}
+		m.Channel, m.Payload = string(elems[1].Str), elems[2].Str
+	case MessageKindPMessage:
+		if len(elems) != 4 || !isStringValue(elems[1]) || !isStringValue(elems[2]) || !isStringValue(elems[3]) {
+			return Message{}, ErrInvalidMessage
+		}
+		m.Pattern, m.Channel, m.Payload = string(elems[1].Str), string(elems[2].Str), elems[3].Str
+	case MessageKindSubscribe, MessageKindSSubscribe, MessageKindUnsubscribe, MessageKindSUnsubscribe,
+		MessageKindPSubscribe, MessageKindPUnsubscribe:
+		if len(elems) != 3 || elems[2].Type != TypeInteger || !(isStringValue(elems[1]) || elems[1].IsNull()) {
+			return Message{}, ErrInvalidMessage
+		}
+		if m.Kind == MessageKindPSubscribe || m.Kind == MessageKindPUnsubscribe {
+			m.Pattern = string(elems[1].Str)
+		} else {
+			m.Channel = string(elems[1].Str)
+		}
+		m.Count = elems[2].Int
+	case MessageKindPong:
+		if len(elems) != 2 || !isStringValue(elems[1]) {
+			return Message{}, ErrInvalidMessage
+		}
+		m.Payload = elems[1].Str
+	}
+
+	return m, nil
+}
+
+func isStringValue(v Value) bool {
+	switch v.Type {
+	case TypeBulkString, TypeSimpleString, TypeVerbatimString:
+		return !v.Null
+	default:
+		return false
+	}
+}
@@ -0,0 +1,230 @@
+package resp_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/nussjustin/resp"
+)
+
+// newTestPubSub returns a PubSub whose server replies to each request with all values returned by fn.
+func newTestPubSub(tb testing.TB, fn func(req resp.Value) []resp.Value) *resp.PubSub {
+	client, server := net.Pipe()
+	rw := resp.NewReadWriter(server)
+
+	// replies are written from a separate goroutine, since net.Pipe is unbuffered and the client may send multiple
+	// commands before reading any reply
+	replies := make(chan resp.Value, 64)
+	go func() {
+		defer close(replies)
+		for {
+			req, err := rw.ReadValue()
+			if err != nil {
+				return
+			}
+			for _, v := range fn(req) {
+				replies <- v
+			}
+		}
+	}()
+	go func() {
+		for v := range replies {
+			if _, err := rw.WriteValue(v); err != nil {
+				return
+			}
+		}
+	}()
+
+	c := resp.NewConn(client)
+	tb.Cleanup(func() {
+		_ = c.Close()
+		_ = server.Close()
+	})
+	return resp.NewPubSub(c)
+}
+
+func TestPubSub(t *testing.T) {
+	for _, test := range []struct {
+		Name string
+		Wrap func(elems ...resp.Value) resp.Value
+	}{
+		{Name: "RESP2", Wrap: resp.Array},
+		{Name: "RESP3", Wrap: resp.Push},
+	} {
+		test := test
+
+		t.Run(test.Name, func(t *testing.T) {
+			ps := newTestPubSub(t, func(req resp.Value) []resp.Value {
+				switch string(req.Elems[0].Str) {
+				case "SUBSCRIBE":
+					return []resp.Value{
+						test.Wrap(resp.BulkString("subscribe"), resp.BulkString("a"), resp.Integer(1)),
+						test.Wrap(resp.BulkString("message"), resp.BulkString("a"), resp.BulkString("hello")),
+					}
+				case "PSUBSCRIBE":
+					return []resp.Value{
+						test.Wrap(resp.BulkString("psubscribe"), resp.BulkString("b*"), resp.Integer(2)),
+						test.Wrap(resp.BulkString("pmessage"), resp.BulkString("b*"), resp.BulkString("bc"), resp.BulkString("world")),
+					}
+				case "PING":
+					return []resp.Value{test.Wrap(resp.BulkString("pong"), resp.BulkString(""))}
+				case "UNSUBSCRIBE":
+					return []resp.Value{
+						test.Wrap(resp.BulkString("unsubscribe"), resp.BulkString("a"), resp.Integer(1)),
+						test.Wrap(resp.BulkString("punsubscribe"), resp.BulkString("b*"), resp.Integer(0)),
+					}
+				default:
+					return []resp.Value{resp.Error("ERR unknown command")}
+				}
+			})
+			ctx := context.Background()
+
+			if err := ps.Subscribe(ctx, "a"); err != nil {
+				t.Fatalf("failed to subscribe: %s", err)
+			}
+			if err := ps.PSubscribe(ctx, "b*"); err != nil {
+				t.Fatalf("failed to subscribe: %s", err)
+			}
+			if err := ps.Ping(ctx, ""); err != nil {
+				t.Fatalf("failed to ping: %s", err)
+			}
+			if err := ps.Unsubscribe(ctx); err != nil {
+				t.Fatalf("failed to unsubscribe: %s", err)
+			}
+
+			var got []resp.Message
+			if err := ps.Run(ctx, func(m resp.Message) error {
+				got = append(got, m)
+				return nil
+			}); err != nil {
+				t.Fatalf("run failed: %s", err)
+			}
+
+			expected := []resp.Message{
+				{Kind: resp.MessageKindSubscribe, Channel: "a", Count: 1},
+				{Kind: resp.MessageKindMessage, Channel: "a", Payload: []byte("hello")},
+				{Kind: resp.MessageKindPSubscribe, Pattern: "b*", Count: 2},
+				{Kind: resp.MessageKindPMessage, Channel: "bc", Pattern: "b*", Payload: []byte("world")},
+				{Kind: resp.MessageKindPong, Payload: []byte{}},
+				{Kind: resp.MessageKindUnsubscribe, Channel: "a", Count: 1},
+				{Kind: resp.MessageKindPUnsubscribe, Pattern: "b*", Count: 0},
+			}
+			if !reflect.DeepEqual(got, expected) {
+				t.Errorf("got %#v, expected %#v", got, expected)
+			}
+		})
+	}
+}
+
+func TestPubSubErrors(t *testing.T) {
+	ps := newTestPubSub(t, func(req resp.Value) []resp.Value {
+		switch string(req.Elems[0].Str) {
+		case "SUBSCRIBE":
+			return []resp.Value{resp.Array(resp.BulkString("message"), resp.BulkString("a"))}
+		default:
+			return []resp.Value{resp.Error("ERR only (P|S)SUBSCRIBE / (P|S)UNSUBSCRIBE / PING / QUIT / RESET are allowed")}
+		}
+	})
+	ctx := context.Background()
+
+	if err := ps.Conn().Send(ctx, "GET", "a"); err != nil {
+		t.Fatalf("failed to send: %s", err)
+	}
+	if _, err := ps.Receive(ctx); !errors.As(err, new(resp.ReplyError)) {
+		t.Errorf("got error %v, expected resp.ReplyError", err)
+	}
+
+	if err := ps.Subscribe(ctx, "a"); err != nil {
+		t.Fatalf("failed to subscribe: %s", err)
+	}
+	if _, err := ps.Receive(ctx); err != resp.ErrInvalidMessage {
+		t.Errorf("got error %v, expected %v", err, resp.ErrInvalidMessage)
+	}
+}
+
+func TestPubSubRunShardSubscriptions(t *testing.T) {
+	ps := newTestPubSub(t, func(req resp.Value) []resp.Value {
+		switch string(req.Elems[0].Str) {
+		case "SUBSCRIBE":
+			return []resp.Value{resp.Push(resp.BulkString("subscribe"), resp.BulkString("a"), resp.Integer(1))}
+		case "SSUBSCRIBE":
+			return []resp.Value{resp.Push(resp.BulkString("ssubscribe"), resp.BulkString("s"), resp.Integer(1))}
+		case "UNSUBSCRIBE":
+			return []resp.Value{
+				resp.Push(resp.BulkString("unsubscribe"), resp.BulkString("a"), resp.Integer(0)),
+				resp.Push(resp.BulkString("smessage"), resp.BulkString("s"), resp.BulkString("hello")),
+			}
+		case "SUNSUBSCRIBE":
+			return []resp.Value{resp.Push(resp.BulkString("sunsubscribe"), resp.BulkString("s"), resp.Integer(0))}
+		default:
+			return []resp.Value{resp.Error("ERR unknown command")}
+		}
+	})
+	ctx := context.Background()
+
+	if err := ps.Subscribe(ctx, "a"); err != nil {
+		t.Fatalf("failed to subscribe: %s", err)
+	}
+	if err := ps.SSubscribe(ctx, "s"); err != nil {
+		t.Fatalf("failed to subscribe: %s", err)
+	}
+	if err := ps.Unsubscribe(ctx); err != nil {
+		t.Fatalf("failed to unsubscribe: %s", err)
+	}
+	if err := ps.SUnsubscribe(ctx); err != nil {
+		t.Fatalf("failed to unsubscribe: %s", err)
+	}
+
+	var kinds []string
+	if err := ps.Run(ctx, func(m resp.Message) error {
+		kinds = append(kinds, m.Kind)
+		return nil
+	}); err != nil {
+		t.Fatalf("run failed: %s", err)
+	}
+
+	expected := []string{
+		resp.MessageKindSubscribe,
+		resp.MessageKindSSubscribe,
+		resp.MessageKindUnsubscribe,
+		resp.MessageKindSMessage,
+		resp.MessageKindSUnsubscribe,
+	}
+	if !reflect.DeepEqual(kinds, expected) {
+		t.Errorf("got %q, expected %q", kinds, expected)
+	}
+}
+
+func TestPubSubPong(t *testing.T) {
+	ps := newTestPubSub(t, func(req resp.Value) []resp.Value {
+		switch string(req.Elems[0].Str) {
+		case "PING":
+			return []resp.Value{resp.BulkString("payload")}
+		default:
+			return []resp.Value{resp.BulkString("value")}
+		}
+	})
+	ctx := context.Background()
+
+	if err := ps.Conn().Send(ctx, "GET", "a"); err != nil {
+		t.Fatalf("failed to send: %s", err)
+	}
+	if _, err := ps.Receive(ctx); err != resp.ErrInvalidMessage {
+		t.Errorf("got error %v, expected %v", err, resp.ErrInvalidMessage)
+	}
+
+	if err := ps.Ping(ctx, "payload"); err != nil {
+		t.Fatalf("failed to ping: %s", err)
+	}
+	m, err := ps.Receive(ctx)
+	if err != nil {
+		t.Fatalf("receive failed: %s", err)
+	}
+	expected := resp.Message{Kind: resp.MessageKindPong, Payload: []byte("payload")}
+	if !reflect.DeepEqual(m, expected) {
+		t.Errorf("got %#v, expected %#v", m, expected)
+	}
+}
@@ -3,6 +3,8 @@ package resp
 import (
 	"bufio"
 	"io"
+	"math"
+	"strconv"
 )
 
 // Reader wraps an io.Reader and provides methods for reading the RESP protocol.
@@ -12,6 +14,15 @@ type Reader struct {
 	// ownbr holds a *bufio.Reader that is reused when calling Reset. This is used in cases the io.Reader given to
 	// Reset is already a *bufio.Reader to avoid reusing the user given *bufio.Reader when calling Reset.
 	ownbr *bufio.Reader
+
+	// err is set when a read fails in the middle of a value, after which the Reader can not be used until Reset.
+	err error
+
+	// buf is used as scratch space when parsing values that are not returned as byte slice (e.g. doubles).
+	buf []byte
+
+	// stack holds the aggregates entered using Next together with the number of elements left in each aggregate.
+	stack []parserFrame
 }
 
 // NewReader returns a *Reader that uses the given io.Reader for reads.
@@ -29,6 +40,9 @@ var _ io.Reader = (*Reader)(nil)
 //
 // If the given io.Reader is an *bufio.Reader it is used directly without additional buffering.
 func (rr *Reader) Reset(r io.Reader) {
+	rr.err = nil
+	rr.stack = rr.stack[:0]
+
 	if br, ok := r.(*bufio.Reader); ok {
 		rr.br = br
 		return
@@ -43,8 +57,38 @@ func (rr *Reader) Reset(r io.Reader) {
 	rr.br = rr.ownbr
 }
 
+// Err returns the error that broke the Reader, or nil if the Reader is usable.
+//
+// A Reader breaks when reading fails in the middle of a value, for example because of a protocol error or because a
+// read deadline was exceeded, leaving the underlying stream in an unknown position. Once broken, all methods that
+// read data return ErrReaderBroken until Reset is called.
+//
+// Errors that occur before the first byte of a value was consumed, for example a timeout while waiting for the next
+// value, do not break the Reader.
+func (rr *Reader) Err() error {
+	return rr.err
+}
+
+// broken marks the Reader as broken if err is not nil.
+func (rr *Reader) broken(err error) error {
+	if err != nil && rr.err == nil {
+		rr.err = err
+	}
+	return err
+}
+
+// Buffered returns the number of bytes that can be read from the current buffer without reading from the
+// underlying io.Reader.
+func (rr *Reader) Buffered() int {
+	return rr.br.Buffered()
+}
+
 // Peek looks at the next byte in the underlying reader and returns the Type of the response.
 func (rr *Reader) Peek() (Type, error) {
+	if rr.err != nil {
+		return TypeInvalid, ErrReaderBroken
+	}
+
 	b, err := rr.br.Peek(1)
 	if err != nil {
 		return TypeInvalid, err
@@ -66,9 +110,27 @@ func (rr *Reader) expect(t Type) error {
 }
 
 func (rr *Reader) readNumberLine() (int, error) {
-	var n int
+	n, err := rr.scanNumberLine()
+	return n, rr.broken(err)
+}
+
+// readLength reads a length line of an aggregate or blob with entries of size elems, returning invalidErr if the
+// line is not a number or the length is less than min or too large. Both cases break the Reader with invalidErr.
+func (rr *Reader) readLength(min, elems int, invalidErr error) (int, error) {
+	n, err := rr.scanNumberLine()
+	if err == ErrInvalidInteger || (err == nil && (n < min || n > math.MaxInt/elems)) {
+		n, err = 0, invalidErr
+	}
+	return n, rr.broken(err)
+}
+
+func (rr *Reader) scanNumberLine() (int, error) {
+	var n uint
 	var neg bool
 
+	// the magnitude of the smallest negative number is one larger than the largest positive number
+	limit := uint(math.MaxInt)
+
 loop:
 	for i := 0; ; i++ {
 		b, err := rr.br.ReadByte()
@@ -79,9 +141,13 @@ loop:
 		switch {
 		case b == '-' && i == 0:
 			neg = true
+			limit++
 		case b >= '0' && b <= '9':
+			if n > (limit-uint(b-'0'))/10 {
+				return 0, ErrInvalidInteger
+			}
 			n *= 10
-			n += int(b - '0')
+			n += uint(b - '0')
 		case b == '\r':
 			b1, err := rr.br.ReadByte()
 			if err == io.EOF {
@@ -108,13 +174,18 @@ loop:
 	}
 
 	if neg {
-		n *= -1
+		return -int(n), nil
 	}
 
-	return n, nil
+	return int(n), nil
 }
 
 func (rr *Reader) readLine(dst []byte) ([]byte, error) {
+	b, err := rr.scanLine(dst)
+	return b, rr.broken(err)
+}
+
+func (rr *Reader) scanLine(dst []byte) ([]byte, error) {
 	for {
 		line, err := rr.br.ReadSlice('\n')
 		if err != nil && err != bufio.ErrBufferFull {
@@ -132,10 +203,20 @@ func (rr *Reader) readLine(dst []byte) ([]byte, error) {
 }
 
 func (rr *Reader) readLineN(dst []byte, n int) ([]byte, error) {
+	b, err := rr.scanLineN(dst, n)
+	return b, rr.broken(err)
+}
+
+func (rr *Reader) scanLineN(dst []byte, n int) ([]byte, error) {
 	n += len("\r\n")
-	dst = ensureSpace(dst, n)
+	dst = ensureSpace(dst, preallocSize(n, maxPreallocBytes))
 	for n > 0 {
-		line, err := rr.br.Peek(n)
+		// Peek does not fill the buffer if n is larger than the buffer, so never ask for more than fits
+		m := n
+		if size := rr.br.Size(); m > size {
+			m = size
+		}
+		line, err := rr.br.Peek(m)
 		if err != nil && err != bufio.ErrBufferFull {
 			if err == io.EOF {
 				err = ErrUnexpectedEOL
@@ -160,6 +241,25 @@ func ensureSpace(b []byte, n int) []byte {
 	return b
 }
 
+const (
+	// maxPreallocBytes is the maximum number of bytes allocated up front based on a length read from the input.
+	maxPreallocBytes = 64 << 10
+
+	// maxPreallocElems is the maximum number of elements allocated up front based on a length read from the input.
+	maxPreallocElems = 16
+)
+
+// preallocSize returns n limited to max.
+//
+// Lengths are read from the input and can not be trusted, so buffers are only preallocated up to a limit and grow
+// as the data is actually read. This prevents small inputs from causing huge allocations.
+func preallocSize(n, max int) int {
+	if n > max {
+		return max
+	}
+	return n
+}
+
 func removeEOLMarker(b []byte) ([]byte, error) {
 	if len(b) < 2 || b[len(b)-2] != '\r' || b[len(b)-1] != '\n' {
 		return nil, ErrUnexpectedEOL
@@ -171,6 +271,9 @@ func removeEOLMarker(b []byte) ([]byte, error) {
 //
 // It implements the io.Reader interface.
 func (rr *Reader) Read(dst []byte) (n int, err error) {
+	if rr.err != nil {
+		return 0, ErrReaderBroken
+	}
 	return rr.br.Read(dst)
 }
 
@@ -181,11 +284,7 @@ func (rr *Reader) ReadArrayHeader() (int, error) {
 	if err := rr.expect(TypeArray); err != nil {
 		return 0, err
 	}
-	n, err := rr.readNumberLine()
-	if n < -1 || err == ErrInvalidInteger {
-		n, err = 0, ErrInvalidArrayLength
-	}
-	return n, err
+	return rr.readLength(-1, 1, ErrInvalidArrayLength)
 }
 
 // ReadBulkStringHeader reads a bulk string header, returning the length, without reading the bulk string itself.
@@ -195,11 +294,7 @@ func (rr *Reader) ReadBulkStringHeader() (int, error) {
 	if err := rr.expect(TypeBulkString); err != nil {
 		return 0, err
 	}
-	n, err := rr.readNumberLine()
-	if n < -1 || err == ErrInvalidInteger {
-		n, err = 0, ErrInvalidBulkStringLength
-	}
-	return n, err
+	return rr.readLength(-1, 1, ErrInvalidBulkStringLength)
 }
 
 // ReadBulkString reads a bulk string into the byte slice dst and returns the modified slice.
@@ -245,3 +340,385 @@ func (rr *Reader) ReadSimpleString(dst []byte) ([]byte, error) {
 	}
 	return rr.readLine(dst)
 }
+
+// readAggregateHeader reads the header of an aggregate of type t with n entries of size elems each.
+func (rr *Reader) readAggregateHeader(t Type, elems int, invalidErr error) (int, error) {
+	if err := rr.expect(t); err != nil {
+		return 0, err
+	}
+	return rr.readLength(0, elems, invalidErr)
+}
+
+// ReadAttributeHeader reads a RESP3 attribute header, returning the number of key-value pairs in the attribute.
+//
+// If the next type in the response is not an attribute, ErrUnexpectedType is returned.
+func (rr *Reader) ReadAttributeHeader() (int, error) {
+	return rr.readAggregateHeader(TypeAttribute, 2, ErrInvalidAttributeLength)
+}
+
+// ReadBigNumber reads a RESP3 big number into the byte slice dst and returns the modified slice.
+//
+// The number is not validated.
+//
+// If the next type in the response is not a big number, ErrUnexpectedType is returned.
+func (rr *Reader) ReadBigNumber(dst []byte) ([]byte, error) {
+	if err := rr.expect(TypeBigNumber); err != nil {
+		return nil, err
+	}
+	return rr.readLine(dst)
+}
+
+// ReadBlobError reads a RESP3 blob error into the byte slice dst and returns the modified slice.
+//
+// If the next type in the response is not a blob error, ErrUnexpectedType is returned.
+func (rr *Reader) ReadBlobError(dst []byte) ([]byte, error) {
+	if err := rr.expect(TypeBlobError); err != nil {
+		return nil, err
+	}
+	n, err := rr.readLength(0, 1, ErrInvalidBlobErrorLength)
+	if err != nil {
+		return nil, err
+	}
+	return rr.readLineN(dst, n)
+}
+
+// ReadBoolean reads a single RESP3 boolean.
+//
+// If the next type in the response is not a boolean, ErrUnexpectedType is returned.
+func (rr *Reader) ReadBoolean() (bool, error) {
+	if err := rr.expect(TypeBoolean); err != nil {
+		return false, err
+	}
+	line, err := rr.readLine(rr.buf[:0])
+	if err != nil {
+		return false, err
+	}
+	rr.buf = line
+	switch {
+	case len(line) == 1 && line[0] == 't':
+		return true, nil
+	case len(line) == 1 && line[0] == 'f':
+		return false, nil
+	default:
+		return false, ErrInvalidBoolean
+	}
+}
+
+// ReadDouble reads a single RESP3 double.
+//
+// The special values inf, -inf and nan are returned as the corresponding float64 values.
+//
+// If the next type in the response is not a double, ErrUnexpectedType is returned.
+func (rr *Reader) ReadDouble() (float64, error) {
+	if err := rr.expect(TypeDouble); err != nil {
+		return 0, err
+	}
+	line, err := rr.readLine(rr.buf[:0])
+	if err != nil {
+		return 0, err
+	}
+	rr.buf = line
+	return parseDouble(line)
+}
+
+func parseDouble(b []byte) (float64, error) {
+	switch string(b) {
+	case "inf", "+inf":
+		return math.Inf(1), nil
+	case "-inf":
+		return math.Inf(-1), nil
+	case "nan":
+		return math.NaN(), nil
+	}
+	f, err := strconv.ParseFloat(string(b), 64)
+	if err != nil {
+		return 0, ErrInvalidDouble
+	}
+	return f, nil
+}
+
+// ReadMapHeader reads a RESP3 map header, returning the number of key-value pairs in the map.
+//
+// If the next type in the response is not a map, ErrUnexpectedType is returned.
+func (rr *Reader) ReadMapHeader() (int, error) {
+	return rr.readAggregateHeader(TypeMap, 2, ErrInvalidMapLength)
+}
+
+// ReadNull reads a single RESP3 null.
+//
+// If the next type in the response is not a null, ErrUnexpectedType is returned.
+func (rr *Reader) ReadNull() error {
+	if err := rr.expect(TypeNull); err != nil {
+		return err
+	}
+	_, err := rr.readLineN(rr.buf[:0], 0)
+	return err
+}
+
+// ReadPushHeader reads a RESP3 push header, returning the number of elements in the push message.
+//
+// If the next type in the response is not a push message, ErrUnexpectedType is returned.
+func (rr *Reader) ReadPushHeader() (int, error) {
+	return rr.readAggregateHeader(TypePush, 1, ErrInvalidPushLength)
+}
+
+// ReadSetHeader reads a RESP3 set header, returning the number of elements in the set.
+//
+// If the next type in the response is not a set, ErrUnexpectedType is returned.
+func (rr *Reader) ReadSetHeader() (int, error) {
+	return rr.readAggregateHeader(TypeSet, 1, ErrInvalidSetLength)
+}
+
+// ReadVerbatimString reads a RESP3 verbatim string into the byte slice dst and returns the modified slice.
+//
+// The returned slice includes the 3 character format and the following colon (for example "txt:Some string").
+//
+// If the next type in the response is not a verbatim string, ErrUnexpectedType is returned.
+func (rr *Reader) ReadVerbatimString(dst []byte) ([]byte, error) {
+	if err := rr.expect(TypeVerbatimString); err != nil {
+		return nil, err
+	}
+	n, err := rr.readLength(4, 1, ErrInvalidVerbatimStringLength)
+	if err != nil {
+		return nil, err
+	}
+	return rr.readLineN(dst, n)
+}
+
+// Next reads the next token of the response and returns it as an Event, similar to the Token method of
+// encoding/json.Decoder.
+//
+// At the start of an aggregate an EventStart is returned, containing the number of elements (or key-value pairs for
+// maps and attributes) in Event.Len. Once all elements of the aggregate were read, Next returns a matching EventEnd.
+// Blob strings are returned as a single EventString and all other values, including null arrays and null bulk strings,
+// as EventValue. Event.Depth is set to the nesting depth of the token.
+//
+// Event.Str is only valid until the next call to Next.
+//
+// Next tracks the aggregates it has entered, so other read methods must not be used while Next is in the middle of an
+// aggregate. An error inside an aggregate breaks the Reader.
+func (rr *Reader) Next() (Event, error) {
+	if rr.err != nil {
+		return Event{}, ErrReaderBroken
+	}
+
+	if n := len(rr.stack); n > 0 && rr.stack[n-1].remaining == 0 {
+		t := rr.stack[n-1].typ
+		rr.stack = rr.stack[:n-1]
+		rr.nextDone()
+		return Event{Kind: EventEnd, Type: t, Depth: n - 1}, nil
+	}
+
+	t, err := rr.Peek()
+	if err != nil {
+		return Event{}, rr.nextErr(err)
+	}
+
+	ev := Event{Kind: EventValue, Type: t, Depth: len(rr.stack)}
+
+	var s []byte
+
+	switch t {
+	case TypeArray, TypeSet, TypePush, TypeMap, TypeAttribute:
+		elems := 1
+		switch t {
+		case TypeArray:
+			ev.Len, err = rr.ReadArrayHeader()
+		case TypeSet:
+			ev.Len, err = rr.ReadSetHeader()
+		case TypePush:
+			ev.Len, err = rr.ReadPushHeader()
+		case TypeMap:
+			ev.Len, err = rr.ReadMapHeader()
+			elems = 2
+		case TypeAttribute:
+			ev.Len, err = rr.ReadAttributeHeader()
+			elems = 2
+		}
+		if err != nil {
+			return Event{}, rr.nextErr(err)
+		}
+		if ev.Len == -1 {
+			ev.Len, ev.Null = 0, true
+			break
+		}
+		ev.Kind = EventStart
+		rr.stack = append(rr.stack, parserFrame{typ: t, remaining: ev.Len * elems})
+		return ev, nil
+	case TypeBulkString:
+		if s, err = rr.ReadBulkString(rr.buf[:0]); err == nil && s == nil {
+			ev.Null = true
+		}
+	case TypeBlobError:
+		s, err = rr.ReadBlobError(rr.buf[:0])
+	case TypeVerbatimString:
+		s, err = rr.ReadVerbatimString(rr.buf[:0])
+	case TypeSimpleString:
+		s, err = rr.ReadSimpleString(rr.buf[:0])
+	case TypeError:
+		s, err = rr.ReadError(rr.buf[:0])
+	case TypeBigNumber:
+		s, err = rr.ReadBigNumber(rr.buf[:0])
+	case TypeInteger:
+		ev.Int, err = rr.ReadInteger()
+	case TypeDouble:
+		if ev.Float, err = rr.ReadDouble(); err == nil {
+			ev.Str = rr.buf
+		}
+	case TypeBoolean:
+		ev.Bool, err = rr.ReadBoolean()
+	case TypeNull:
+		err = rr.ReadNull()
+		ev.Null = true
+	default:
+		return Event{}, rr.nextErr(ErrUnexpectedType)
+	}
+
+	if err != nil {
+		return Event{}, rr.nextErr(err)
+	}
+
+	if s != nil {
+		rr.buf, ev.Str = s, s
+	}
+
+	switch t {
+	case TypeBulkString, TypeBlobError, TypeVerbatimString:
+		if !ev.Null {
+			ev.Kind, ev.Len = EventString, len(s)
+		}
+	}
+
+	rr.nextDone()
+	return ev, nil
+}
+
+// nextDone is called by Next after reading a complete value and counts the value as element of the current aggregate.
+func (rr *Reader) nextDone() {
+	if n := len(rr.stack); n > 0 {
+		rr.stack[n-1].remaining--
+	}
+}
+
+// nextErr breaks the Reader if err occurred inside an aggregate entered by Next.
+func (rr *Reader) nextErr(err error) error {
+	if len(rr.stack) > 0 {
+		return rr.broken(err)
+	}
+	return err
+}
+
+// ReadRaw reads the next value, including all nested values, and appends it in its encoded form to dst, returning
+// the modified slice.
+//
+// Values are validated but not decoded into a Value. Numbers and lengths are appended in their canonical form, so
+// the appended data can differ from the data read, for example when a length had leading zeros. ReadRaw is useful
+// for forwarding values, for example in a proxy.
+//
+// If an error occurs after the first byte of the value was consumed, the Reader is broken.
+func (rr *Reader) ReadRaw(dst []byte) ([]byte, error) {
+	t, err := rr.Peek()
+	if err != nil {
+		return nil, err
+	}
+
+	switch t {
+	case TypeArray, TypeSet, TypePush, TypeMap, TypeAttribute:
+		var n int
+		switch t {
+		case TypeArray:
+			if n, err = rr.ReadArrayHeader(); err == nil {
+				dst = AppendArrayHeader(dst, n)
+			}
+		case TypeSet:
+			if n, err = rr.ReadSetHeader(); err == nil {
+				dst = AppendSetHeader(dst, n)
+			}
+		case TypePush:
+			if n, err = rr.ReadPushHeader(); err == nil {
+				dst = AppendPushHeader(dst, n)
+			}
+		case TypeMap:
+			if n, err = rr.ReadMapHeader(); err == nil {
+				dst = AppendMapHeader(dst, n)
+			}
+			n *= 2
+		case TypeAttribute:
+			if n, err = rr.ReadAttributeHeader(); err == nil {
+				dst = AppendAttributeHeader(dst, n)
+			}
+			n *= 2
+		}
+		if err != nil {
+			return nil, err
+		}
+		for i := 0; i < n; i++ {
+			if dst, err = rr.ReadRaw(dst); err != nil {
+				return nil, rr.broken(err)
+			}
+		}
+		return dst, nil
+	case TypeBulkString:
+		var n int
+		if n, err = rr.ReadBulkStringHeader(); err != nil {
+			return nil, err
+		}
+		dst = AppendBulkStringHeader(dst, n)
+		if n == -1 {
+			return dst, nil
+		}
+		if dst, err = rr.readLineN(dst, n); err != nil {
+			return nil, err
+		}
+		return append(dst, '\r', '\n'), nil
+	case TypeInteger:
+		var n int
+		if n, err = rr.ReadInteger(); err != nil {
+			return nil, err
+		}
+		return AppendInteger(dst, n), nil
+	case TypeBoolean:
+		var b bool
+		if b, err = rr.ReadBoolean(); err != nil {
+			return nil, err
+		}
+		return AppendBoolean(dst, b), nil
+	case TypeNull:
+		if err = rr.ReadNull(); err != nil {
+			return nil, err
+		}
+		return AppendNull(dst), nil
+	}
+
+	// the remaining types are read into the scratch buffer first, since the header depends on the content
+	var s []byte
+	switch t {
+	case TypeBlobError:
+		s, err = rr.ReadBlobError(rr.buf[:0])
+	case TypeVerbatimString:
+		s, err = rr.ReadVerbatimString(rr.buf[:0])
+	case TypeSimpleString:
+		s, err = rr.ReadSimpleString(rr.buf[:0])
+	case TypeError:
+		s, err = rr.ReadError(rr.buf[:0])
+	case TypeBigNumber:
+		s, err = rr.ReadBigNumber(rr.buf[:0])
+	case TypeDouble:
+		if _, err = rr.ReadDouble(); err == nil {
+			s = rr.buf
+		}
+	default:
+		return nil, ErrUnexpectedType
+	}
+	if err != nil {
+		return nil, err
+	}
+	rr.buf = s
+
+	switch t {
+	case TypeBlobError, TypeVerbatimString:
+		return appendBlobBytes(dst, byte(t), s), nil
+	default:
+		return appendLineBytes(dst, byte(t), s), nil
+	}
+}
@@ -3,8 +3,11 @@ package resp_test
 import (
 	"bufio"
 	"bytes"
+	"errors"
 	"io"
 	"io/ioutil"
+	"math"
+	"reflect"
 	"strings"
 	"testing"
 	"testing/iotest"
@@ -86,7 +89,7 @@ func TestReaderRead(t *testing.T) {
 			if gerr != err {
 				t.Errorf("got error %v, expected %v", gerr, err)
 			} else if !bytes.Equal(got.Bytes(), expected.Bytes()) {
-				t.Errorf("got %q (len %d), expected %q (len %d)", got, got.Len(), expected, expected.Len())
+				t.Errorf("got %q (len %d), expected %q (len %d)", &got, got.Len(), &expected, expected.Len())
 			}
 		})
 	}
@@ -364,6 +367,19 @@ func TestReaderReadBulkString(t *testing.T) {
 	}
 }
 
+func TestReaderReadBulkStringLargerThanBuffer(t *testing.T) {
+	s := strings.Repeat("0123456789", 10)
+
+	// use OneByteReader so that the buffer is never filled by a single read
+	r := resp.NewReader(bufio.NewReaderSize(iotest.OneByteReader(strings.NewReader("$100\r\n"+s+"\r\n")), 16))
+
+	got, err := r.ReadBulkString(nil)
+	if err != nil {
+		t.Fatalf("read failed: %s", err)
+	}
+	assertBytes(t, got, s)
+}
+
 func BenchmarkReaderReadBulkString(b *testing.B) {
 	for _, test := range []struct {
 		Name string
@@ -419,6 +435,11 @@ func TestReaderReadBulkStringHeader(t *testing.T) {
 			Err:  resp.ErrInvalidBulkStringLength,
 			In:   "$-2\r\n",
 		},
+		{
+			Name: "overflow",
+			Err:  resp.ErrInvalidBulkStringLength,
+			In:   "$99999999999999999999\r\n",
+		},
 		{
 			Name:     "null",
 			Expected: -1,
@@ -592,6 +613,11 @@ func TestReaderReadInteger(t *testing.T) {
 			Err:  resp.ErrUnexpectedType,
 			In:   "*",
 		},
+		{
+			Name: "invalid type 0xff",
+			Err:  resp.ErrUnexpectedType,
+			In:   "\xff",
+		},
 		{
 			Name:     "negative",
 			Expected: -2,
@@ -647,6 +673,46 @@ func TestReaderReadInteger(t *testing.T) {
 			Err:  resp.ErrInvalidInteger,
 			In:   ":0a\n",
 		},
+		{
+			Name:     "no digits",
+			Expected: 0,
+			In:       ":\r\n",
+		},
+		{
+			Name:     "only sign",
+			Expected: 0,
+			In:       ":-\r\n",
+		},
+		{
+			Name:     "negative zero",
+			Expected: 0,
+			In:       ":-0\r\n",
+		},
+		{
+			Name:     "leading zero",
+			Expected: 1,
+			In:       ":01\r\n",
+		},
+		{
+			Name:     "max",
+			Expected: math.MaxInt64,
+			In:       ":9223372036854775807\r\n",
+		},
+		{
+			Name:     "min",
+			Expected: math.MinInt64,
+			In:       ":-9223372036854775808\r\n",
+		},
+		{
+			Name: "overflow",
+			Err:  resp.ErrInvalidInteger,
+			In:   ":9223372036854775808\r\n",
+		},
+		{
+			Name: "negative overflow",
+			Err:  resp.ErrInvalidInteger,
+			In:   ":-9223372036854775809\r\n",
+		},
 	} {
 		test := test
 
@@ -782,3 +848,486 @@ func TestReaderReadMixed(t *testing.T) {
 		t.Fatalf("failed to read array header: %s", err)
 	}
 }
+
+func TestReaderReadAggregateHeaders(t *testing.T) {
+	for _, test := range []struct {
+		Name       string
+		Prefix     string
+		InvalidErr error
+		Fn         func(*resp.Reader) (int, error)
+	}{
+		{
+			Name:       "attribute",
+			Prefix:     "|",
+			InvalidErr: resp.ErrInvalidAttributeLength,
+			Fn:         (*resp.Reader).ReadAttributeHeader,
+		},
+		{
+			Name:       "map",
+			Prefix:     "%",
+			InvalidErr: resp.ErrInvalidMapLength,
+			Fn:         (*resp.Reader).ReadMapHeader,
+		},
+		{
+			Name:       "push",
+			Prefix:     ">",
+			InvalidErr: resp.ErrInvalidPushLength,
+			Fn:         (*resp.Reader).ReadPushHeader,
+		},
+		{
+			Name:       "set",
+			Prefix:     "~",
+			InvalidErr: resp.ErrInvalidSetLength,
+			Fn:         (*resp.Reader).ReadSetHeader,
+		},
+	} {
+		test := test
+
+		t.Run(test.Name, func(t *testing.T) {
+			testSimpleIntegerRead(t, "", 0, io.EOF, test.Fn)
+			testSimpleIntegerRead(t, "$", 0, resp.ErrUnexpectedType, test.Fn)
+			testSimpleIntegerRead(t, test.Prefix+"-1\r\n", 0, test.InvalidErr, test.Fn)
+			testSimpleIntegerRead(t, test.Prefix+"a\r\n", 0, test.InvalidErr, test.Fn)
+			testSimpleIntegerRead(t, test.Prefix+"0\r\n", 0, nil, test.Fn)
+			testSimpleIntegerRead(t, test.Prefix+"10\r\n", 10, nil, test.Fn)
+			testSimpleIntegerRead(t, test.Prefix+"10\n", 0, resp.ErrUnexpectedEOL, test.Fn)
+		})
+	}
+}
+
+func TestReaderReadBigNumber(t *testing.T) {
+	testSimpleRead(t, "(3492890328409238509324850943850943825024385\r\n",
+		[]byte("3492890328409238509324850943850943825024385"), nil, (*resp.Reader).ReadBigNumber)
+	testSimpleRead(t, "(-1\r\n", []byte("-1"), nil, (*resp.Reader).ReadBigNumber)
+	testSimpleRead(t, ":1\r\n", nil, resp.ErrUnexpectedType, (*resp.Reader).ReadBigNumber)
+	testSimpleRead(t, "(1", nil, resp.ErrUnexpectedEOL, (*resp.Reader).ReadBigNumber)
+}
+
+func TestReaderReadBlobError(t *testing.T) {
+	testSimpleRead(t, "!21\r\nSYNTAX invalid syntax\r\n", []byte("SYNTAX invalid syntax"), nil,
+		(*resp.Reader).ReadBlobError)
+	testSimpleRead(t, "!0\r\n\r\n", []byte{}, nil, (*resp.Reader).ReadBlobError)
+	testSimpleRead(t, "!-1\r\n", nil, resp.ErrInvalidBlobErrorLength, (*resp.Reader).ReadBlobError)
+	testSimpleRead(t, "!", nil, io.EOF, (*resp.Reader).ReadBlobError)
+	testSimpleRead(t, "$0\r\n\r\n", nil, resp.ErrUnexpectedType, (*resp.Reader).ReadBlobError)
+	testSimpleRead(t, "!5\r\nhello world\r\n", nil, resp.ErrUnexpectedEOL, (*resp.Reader).ReadBlobError)
+}
+
+func TestReaderReadBoolean(t *testing.T) {
+	for _, test := range []struct {
+		Name     string
+		Expected bool
+		Err      error
+		In       string
+	}{
+		{
+			Name: "empty",
+			Err:  io.EOF,
+			In:   "",
+		},
+		{
+			Name: "wrong type",
+			Err:  resp.ErrUnexpectedType,
+			In:   ":1\r\n",
+		},
+		{
+			Name:     "true",
+			Expected: true,
+			In:       "#t\r\n",
+		},
+		{
+			Name:     "false",
+			Expected: false,
+			In:       "#f\r\n",
+		},
+		{
+			Name: "invalid",
+			Err:  resp.ErrInvalidBoolean,
+			In:   "#x\r\n",
+		},
+		{
+			Name: "too long",
+			Err:  resp.ErrInvalidBoolean,
+			In:   "#tt\r\n",
+		},
+		{
+			Name: "no \\r",
+			Err:  resp.ErrUnexpectedEOL,
+			In:   "#t\n",
+		},
+	} {
+		test := test
+
+		t.Run(test.Name, func(t *testing.T) {
+			r := resp.NewReader(strings.NewReader(test.In))
+
+			if got, err := r.ReadBoolean(); err != test.Err {
+				t.Errorf("got error %v, expected %v", err, test.Err)
+			} else if got != test.Expected {
+				t.Errorf("got %t, expected %t", got, test.Expected)
+			}
+		})
+	}
+}
+
+func TestReaderReadDouble(t *testing.T) {
+	for _, test := range []struct {
+		Name     string
+		Expected float64
+		Err      error
+		In       string
+	}{
+		{
+			Name: "empty",
+			Err:  io.EOF,
+			In:   "",
+		},
+		{
+			Name: "wrong type",
+			Err:  resp.ErrUnexpectedType,
+			In:   ":1\r\n",
+		},
+		{
+			Name:     "integral",
+			Expected: 10,
+			In:       ",10\r\n",
+		},
+		{
+			Name:     "fraction",
+			Expected: 1.23,
+			In:       ",1.23\r\n",
+		},
+		{
+			Name:     "exponent",
+			Expected: -1.5e10,
+			In:       ",-1.5e10\r\n",
+		},
+		{
+			Name:     "inf",
+			Expected: math.Inf(1),
+			In:       ",inf\r\n",
+		},
+		{
+			Name:     "negative inf",
+			Expected: math.Inf(-1),
+			In:       ",-inf\r\n",
+		},
+		{
+			Name: "invalid",
+			Err:  resp.ErrInvalidDouble,
+			In:   ",abc\r\n",
+		},
+		{
+			Name: "no \\r\\n",
+			Err:  resp.ErrUnexpectedEOL,
+			In:   ",1.5",
+		},
+	} {
+		test := test
+
+		t.Run(test.Name, func(t *testing.T) {
+			r := resp.NewReader(strings.NewReader(test.In))
+
+			if got, err := r.ReadDouble(); err != test.Err {
+				t.Errorf("got error %v, expected %v", err, test.Err)
+			} else if got != test.Expected {
+				t.Errorf("got %v, expected %v", got, test.Expected)
+			}
+		})
+	}
+
+	r := resp.NewReader(strings.NewReader(",nan\r\n"))
+	if got, err := r.ReadDouble(); err != nil || !math.IsNaN(got) {
+		t.Errorf("got %v (error %v), expected NaN", got, err)
+	}
+}
+
+func TestReaderReadNull(t *testing.T) {
+	for _, test := range []struct {
+		Name string
+		Err  error
+		In   string
+	}{
+		{
+			Name: "empty",
+			Err:  io.EOF,
+			In:   "",
+		},
+		{
+			Name: "wrong type",
+			Err:  resp.ErrUnexpectedType,
+			In:   "$-1\r\n",
+		},
+		{
+			Name: "null",
+			In:   "_\r\n",
+		},
+		{
+			Name: "with content",
+			Err:  resp.ErrUnexpectedEOL,
+			In:   "_a\r\n",
+		},
+		{
+			Name: "no \\r\\n",
+			Err:  resp.ErrUnexpectedEOL,
+			In:   "_",
+		},
+	} {
+		test := test
+
+		t.Run(test.Name, func(t *testing.T) {
+			r := resp.NewReader(strings.NewReader(test.In))
+
+			if err := r.ReadNull(); err != test.Err {
+				t.Errorf("got error %v, expected %v", err, test.Err)
+			}
+		})
+	}
+}
+
+func TestReaderReadVerbatimString(t *testing.T) {
+	testSimpleRead(t, "=15\r\ntxt:Some string\r\n", []byte("txt:Some string"), nil, (*resp.Reader).ReadVerbatimString)
+	testSimpleRead(t, "=4\r\ntxt:\r\n", []byte("txt:"), nil, (*resp.Reader).ReadVerbatimString)
+	testSimpleRead(t, "=3\r\ntxt\r\n", nil, resp.ErrInvalidVerbatimStringLength, (*resp.Reader).ReadVerbatimString)
+	testSimpleRead(t, "=", nil, io.EOF, (*resp.Reader).ReadVerbatimString)
+	testSimpleRead(t, "$0\r\n\r\n", nil, resp.ErrUnexpectedType, (*resp.Reader).ReadVerbatimString)
+}
+
+// stallingReader returns the data of r followed by err for every further read.
+type stallingReader struct {
+	r   io.Reader
+	err error
+}
+
+func (s *stallingReader) Read(p []byte) (int, error) {
+	n, err := s.r.Read(p)
+	if err == io.EOF {
+		return n, s.err
+	}
+	return n, err
+}
+
+var errStalled = errors.New("stalled")
+
+func TestReaderBroken(t *testing.T) {
+	for _, test := range []struct {
+		Name string
+		In   string
+		Fn   func(*resp.Reader) error
+	}{
+		{
+			Name: "bulk string",
+			In:   "$10\r\nhello",
+			Fn: func(r *resp.Reader) error {
+				_, err := r.ReadBulkString(nil)
+				return err
+			},
+		},
+		{
+			Name: "integer",
+			In:   ":12",
+			Fn: func(r *resp.Reader) error {
+				_, err := r.ReadInteger()
+				return err
+			},
+		},
+		{
+			Name: "simple string",
+			In:   "+OK",
+			Fn: func(r *resp.Reader) error {
+				_, err := r.ReadSimpleString(nil)
+				return err
+			},
+		},
+		{
+			Name: "nested value",
+			In:   "*2\r\n:1\r\n",
+			Fn: func(r *resp.Reader) error {
+				_, err := r.ReadValue()
+				return err
+			},
+		},
+		{
+			Name: "next",
+			In:   "*2\r\n:1\r\n",
+			Fn: func(r *resp.Reader) error {
+				for {
+					if _, err := r.Next(); err != nil {
+						return err
+					}
+				}
+			},
+		},
+	} {
+		test := test
+
+		t.Run(test.Name, func(t *testing.T) {
+			r := resp.NewReader(&stallingReader{r: strings.NewReader(test.In), err: errStalled})
+
+			if err := test.Fn(r); err != errStalled {
+				t.Fatalf("got error %v, expected %v", err, errStalled)
+			}
+			if err := r.Err(); err != errStalled {
+				t.Errorf("got error %v from Err, expected %v", err, errStalled)
+			}
+			if err := test.Fn(r); err != resp.ErrReaderBroken {
+				t.Errorf("got error %v, expected %v", err, resp.ErrReaderBroken)
+			}
+			if _, err := r.Read(make([]byte, 1)); err != resp.ErrReaderBroken {
+				t.Errorf("got error %v, expected %v", err, resp.ErrReaderBroken)
+			}
+
+			r.Reset(strings.NewReader(":1\r\n"))
+
+			if err := r.Err(); err != nil {
+				t.Errorf("got error %v from Err after Reset, expected nil", err)
+			}
+			if n, err := r.ReadInteger(); err != nil || n != 1 {
+				t.Errorf("got %d (error %v), expected 1", n, err)
+			}
+		})
+	}
+}
+
+func TestReaderBrokenInvalidLength(t *testing.T) {
+	for _, test := range []struct {
+		In  string
+		Err error
+	}{
+		{"$-2\r\n", resp.ErrInvalidBulkStringLength},
+		{"$x\r\n", resp.ErrInvalidBulkStringLength},
+		{"*-2\r\n", resp.ErrInvalidArrayLength},
+		{"%-1\r\n", resp.ErrInvalidMapLength},
+		{"=2\r\n", resp.ErrInvalidVerbatimStringLength},
+	} {
+		r := resp.NewReader(strings.NewReader(test.In))
+
+		if _, err := r.ReadValue(); err != test.Err {
+			t.Errorf("got error %v for %q, expected %v", err, test.In, test.Err)
+		}
+		if err := r.Err(); err != test.Err {
+			t.Errorf("got error %v from Err for %q, expected %v", err, test.In, test.Err)
+		}
+	}
+}
+
+func TestReaderNotBrokenBeforeValue(t *testing.T) {
+	sr := &stallingReader{r: strings.NewReader(""), err: errStalled}
+	r := resp.NewReader(sr)
+
+	if _, err := r.ReadInteger(); err != errStalled {
+		t.Fatalf("got error %v, expected %v", err, errStalled)
+	}
+	if err := r.Err(); err != nil {
+		t.Fatalf("got error %v from Err, expected nil", err)
+	}
+
+	sr.r, sr.err = strings.NewReader(":5\r\n"), io.EOF
+
+	if n, err := r.ReadInteger(); err != nil || n != 5 {
+		t.Errorf("got %d (error %v), expected 5", n, err)
+	}
+}
+
+func TestReaderNext(t *testing.T) {
+	r := resp.NewReader(strings.NewReader("*3\r\n$3\r\nkey\r\n%1\r\n+a\r\n*0\r\n*-1\r\n" +
+		"$-1\r\n,1.5\r\n>2\r\n~1\r\n#t\r\n=9\r\ntxt:hello\r\n_\r\n"))
+
+	var got []resp.Event
+	for {
+		ev, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("got error %v after %d events", err, len(got))
+		}
+		if ev.Str != nil {
+			ev.Str = append([]byte{}, ev.Str...)
+		}
+		got = append(got, ev)
+	}
+
+	expected := []resp.Event{
+		{Kind: resp.EventStart, Type: resp.TypeArray, Len: 3},
+		{Kind: resp.EventString, Type: resp.TypeBulkString, Depth: 1, Len: 3, Str: []byte("key")},
+		{Kind: resp.EventStart, Type: resp.TypeMap, Depth: 1, Len: 1},
+		{Kind: resp.EventValue, Type: resp.TypeSimpleString, Depth: 2, Str: []byte("a")},
+		{Kind: resp.EventStart, Type: resp.TypeArray, Depth: 2},
+		{Kind: resp.EventEnd, Type: resp.TypeArray, Depth: 2},
+		{Kind: resp.EventEnd, Type: resp.TypeMap, Depth: 1},
+		{Kind: resp.EventValue, Type: resp.TypeArray, Depth: 1, Null: true},
+		{Kind: resp.EventEnd, Type: resp.TypeArray},
+		{Kind: resp.EventValue, Type: resp.TypeBulkString, Null: true},
+		{Kind: resp.EventValue, Type: resp.TypeDouble, Str: []byte("1.5"), Float: 1.5},
+		{Kind: resp.EventStart, Type: resp.TypePush, Len: 2},
+		{Kind: resp.EventStart, Type: resp.TypeSet, Depth: 1, Len: 1},
+		{Kind: resp.EventValue, Type: resp.TypeBoolean, Depth: 2, Bool: true},
+		{Kind: resp.EventEnd, Type: resp.TypeSet, Depth: 1},
+		{Kind: resp.EventString, Type: resp.TypeVerbatimString, Depth: 1, Len: 9, Str: []byte("txt:hello")},
+		{Kind: resp.EventEnd, Type: resp.TypePush},
+		{Kind: resp.EventValue, Type: resp.TypeNull, Null: true},
+	}
+
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("got events\n%+v\nexpected\n%+v", got, expected)
+	}
+}
+
+func TestReaderNextReset(t *testing.T) {
+	r := resp.NewReader(strings.NewReader("*2\r\n:1\r\n"))
+
+	for i := 0; i < 2; i++ {
+		if _, err := r.Next(); err != nil {
+			t.Fatalf("got error %v", err)
+		}
+	}
+
+	r.Reset(strings.NewReader(":2\r\n"))
+
+	ev, err := r.Next()
+	if err != nil {
+		t.Fatalf("got error %v after Reset", err)
+	}
+	if expected := (resp.Event{Kind: resp.EventValue, Type: resp.TypeInteger, Int: 2}); !reflect.DeepEqual(ev, expected) {
+		t.Errorf("got %+v after Reset, expected %+v", ev, expected)
+	}
+}
+
+func TestReaderReadRaw(t *testing.T) {
+	in := "*3\r\n$3\r\nkey\r\n%1\r\n+a\r\n:1\r\n~2\r\n#f\r\n_\r\n" +
+		"$-1\r\n*-1\r\n-ERR failed\r\n,1.5\r\n(123\r\n!9\r\nERR error\r\n=9\r\ntxt:hello\r\n" +
+		">2\r\n+message\r\n$0\r\n\r\n|1\r\n+ttl\r\n:100\r\n"
+
+	r := resp.NewReader(strings.NewReader(in))
+
+	var got []byte
+	for {
+		b, err := r.ReadRaw(got)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("got error %v after reading %q", err, got)
+		}
+		got = b
+	}
+
+	if string(got) != in {
+		t.Errorf("got %q, expected %q", got, in)
+	}
+
+	for _, test := range []struct {
+		In  string
+		Err error
+	}{
+		{"A\r\n", resp.ErrUnexpectedType},
+		{"*2\r\n:1\r\n:a\r\n", resp.ErrInvalidInteger},
+		{"$3\r\nab\r\n", resp.ErrUnexpectedEOL},
+	} {
+		r := resp.NewReader(strings.NewReader(test.In))
+		if _, err := r.ReadRaw(nil); err != test.Err {
+			t.Errorf("got error %v for %q, expected %v", err, test.In, test.Err)
+		}
+	}
+}
@@ -10,12 +10,41 @@ var (
 	// ErrInvalidArrayLength is returned when reading or writing an array header with an invalid length.
 	ErrInvalidArrayLength = errors.New("array length must be >= -1")
 
+	// ErrInvalidAttributeLength is returned when reading or writing an attribute header with an invalid length.
+	ErrInvalidAttributeLength = errors.New("attribute length must be >= 0")
+
+	// ErrInvalidBlobErrorLength is returned when reading a blob error with an invalid length.
+	ErrInvalidBlobErrorLength = errors.New("blob error length must be >= 0")
+
+	// ErrInvalidBoolean is returned when decoding an invalid boolean.
+	ErrInvalidBoolean = errors.New("invalid boolean")
+
 	// ErrInvalidBulkStringLength is returned when reading or writing a bulk string with an invalid length.
 	ErrInvalidBulkStringLength = errors.New("bulk string length must be >= -1")
 
+	// ErrInvalidDouble is returned when decoding an invalid double.
+	ErrInvalidDouble = errors.New("invalid double")
+
 	// ErrInvalidInteger is returned when decoding an invalid integer.
 	ErrInvalidInteger = errors.New("invalid integer")
 
+	// ErrInvalidMapLength is returned when reading or writing a map header with an invalid length.
+	ErrInvalidMapLength = errors.New("map length must be >= 0")
+
+	// ErrInvalidPushLength is returned when reading or writing a push header with an invalid length.
+	ErrInvalidPushLength = errors.New("push length must be >= 0")
+
+	// ErrInvalidSetLength is returned when reading or writing a set header with an invalid length.
+	ErrInvalidSetLength = errors.New("set length must be >= 0")
+
+	// ErrInvalidVerbatimStringLength is returned when reading a verbatim string with an invalid length.
+	ErrInvalidVerbatimStringLength = errors.New("verbatim string length must be >= 4")
+
+	// ErrReaderBroken is returned by Reader after a previous read failed in the middle of a value.
+	//
+	// See Reader.Err for more information.
+	ErrReaderBroken = errors.New("reader is broken after a previous error and must be reset")
+
 	// ErrUnexpectedEOL is returned when reading a line that does not end in \r.\n
 	ErrUnexpectedEOL = errors.New("missing or invalid EOL")
 
@@ -39,16 +68,48 @@ const (
 	TypeInteger Type = ':'
 	// TypeSimpleString signifies a simple string.
 	TypeSimpleString Type = '+'
+
+	// TypeAttribute signifies a RESP3 attribute map.
+	TypeAttribute Type = '|'
+	// TypeBigNumber signifies a RESP3 big number.
+	TypeBigNumber Type = '('
+	// TypeBlobError signifies a RESP3 blob error.
+	TypeBlobError Type = '!'
+	// TypeBoolean signifies a RESP3 boolean.
+	TypeBoolean Type = '#'
+	// TypeDouble signifies a RESP3 double.
+	TypeDouble Type = ','
+	// TypeMap signifies a RESP3 map.
+	TypeMap Type = '%'
+	// TypeNull signifies a RESP3 null.
+	TypeNull Type = '_'
+	// TypePush signifies a RESP3 push message.
+	TypePush Type = '>'
+	// TypeSet signifies a RESP3 set.
+	TypeSet Type = '~'
+	// TypeVerbatimString signifies a RESP3 verbatim string.
+	TypeVerbatimString Type = '='
 )
 
 var _ fmt.Stringer = TypeInvalid
 
-var types = [255]Type{
+var types = [256]Type{
 	TypeArray:        TypeArray,
 	TypeBulkString:   TypeBulkString,
 	TypeError:        TypeError,
 	TypeInteger:      TypeInteger,
 	TypeSimpleString: TypeSimpleString,
+
+	TypeAttribute:      TypeAttribute,
+	TypeBigNumber:      TypeBigNumber,
+	TypeBlobError:      TypeBlobError,
+	TypeBoolean:        TypeBoolean,
+	TypeDouble:         TypeDouble,
+	TypeMap:            TypeMap,
+	TypeNull:           TypeNull,
+	TypePush:           TypePush,
+	TypeSet:            TypeSet,
+	TypeVerbatimString: TypeVerbatimString,
 }
 
 // String implements the fmt.Stringer interface.
@@ -56,6 +117,16 @@ func (t Type) String() string {
 	return string(t)
 }
 
+// Protocol is a version of the RESP protocol.
+type Protocol int
+
+const (
+	// RESP2 is the protocol version spoken by Redis by default.
+	RESP2 Protocol = 2
+	// RESP3 is the protocol version negotiated using the HELLO command, which adds new types like maps and doubles.
+	RESP3 Protocol = 3
+)
+
 // ReadWriter embeds a Reader and a Writer in a single allocation for an io.ReadWriter.
 //
 // A single Reader and a single Writer method can be called concurrently, given the Read and Write methods of the
@@ -8,6 +8,8 @@ import (
 	"os"
 	"strings"
 	"testing"
+
+	"github.com/nussjustin/resp/resptest"
 )
 
 const (
@@ -20,6 +22,13 @@ func dialRedis(tb testing.TB) io.ReadWriteCloser {
 		host = defaultRedisHost
 	}
 
+	// run against the in-memory fake server
+	if host == "resptest" {
+		s := resptest.NewServer()
+		tb.Cleanup(s.Close)
+		return s.Pipe()
+	}
+
 	proto := "tcp"
 	if strings.HasPrefix(host, "/") {
 		proto = "unix"
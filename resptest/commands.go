@@ -0,0 +1,1271 @@
+package resptest
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nussjustin/resp"
+)
+
+// Error messages as used by Redis.
+const (
+	msgNotFloat   = "ERR value is not a valid float"
+	msgNotInteger = "ERR value is not an integer or out of range"
+	msgOverflow   = "ERR increment or decrement would overflow"
+	msgSyntax     = "ERR syntax error"
+	msgWrongType  = "WRONGTYPE Operation against a key holding the wrong kind of value"
+)
+
+type commandFunc func(s *Server, w resp.ReplyWriter, cmd *resp.Command)
+
+var commands = []struct {
+	info resp.CommandInfo
+	fn   commandFunc
+}{
+	// connection
+	{resp.CommandInfo{Name: "ECHO", Arity: 2, Group: "connection"}, (*Server).cmdEcho},
+	{resp.CommandInfo{Name: "HELLO", Arity: -1, Group: "connection"}, (*Server).cmdHello},
+	{resp.CommandInfo{Name: "PING", Arity: -1, Group: "connection"}, (*Server).cmdPing},
+	{resp.CommandInfo{Name: "QUIT", Arity: -1, Group: "connection"}, (*Server).cmdQuit},
+	{resp.CommandInfo{Name: "SELECT", Arity: 2, Group: "connection"}, (*Server).cmdSelect},
+
+	// server
+	{resp.CommandInfo{Name: "DBSIZE", Arity: 1, Group: "server"}, (*Server).cmdDBSize},
+	{resp.CommandInfo{Name: "FLUSHALL", Arity: -1, Group: "server"}, (*Server).cmdFlush},
+	{resp.CommandInfo{Name: "FLUSHDB", Arity: -1, Group: "server"}, (*Server).cmdFlush},
+
+	// generic
+	{keysCommand("DEL", -2, 1, "generic"), (*Server).cmdDel},
+	{keysCommand("EXISTS", -2, 1, "generic"), (*Server).cmdExists},
+	{keyCommand("EXPIRE", 3, "generic"), (*Server).cmdExpire},
+	{resp.CommandInfo{Name: "KEYS", Arity: 2, Group: "generic"}, (*Server).cmdKeys},
+	{keyCommand("PERSIST", 2, "generic"), (*Server).cmdPersist},
+	{keyCommand("PEXPIRE", 3, "generic"), (*Server).cmdExpire},
+	{keyCommand("PTTL", 2, "generic"), (*Server).cmdTTL},
+	{keyCommand("TTL", 2, "generic"), (*Server).cmdTTL},
+	{keyCommand("TYPE", 2, "generic"), (*Server).cmdType},
+	{keysCommand("UNLINK", -2, 1, "generic"), (*Server).cmdDel},
+
+	// strings
+	{keyCommand("APPEND", 3, "string"), (*Server).cmdAppend},
+	{keyCommand("DECR", 2, "string"), (*Server).cmdIncr},
+	{keyCommand("DECRBY", 3, "string"), (*Server).cmdIncr},
+	{keyCommand("GET", 2, "string"), (*Server).cmdGet},
+	{keyCommand("GETDEL", 2, "string"), (*Server).cmdGet},
+	{keyCommand("INCR", 2, "string"), (*Server).cmdIncr},
+	{keyCommand("INCRBY", 3, "string"), (*Server).cmdIncr},
+	{keyCommand("INCRBYFLOAT", 3, "string"), (*Server).cmdIncrByFloat},
+	{keysCommand("MGET", -2, 1, "string"), (*Server).cmdMGet},
+	{keysCommand("MSET", -3, 2, "string"), (*Server).cmdMSet},
+	{keyCommand("SET", -3, "string"), (*Server).cmdSet},
+	{keyCommand("SETNX", 3, "string"), (*Server).cmdSetNX},
+	{keyCommand("STRLEN", 2, "string"), (*Server).cmdStrlen},
+
+	// hashes
+	{keyCommand("HDEL", -3, "hash"), (*Server).cmdHDel},
+	{keyCommand("HEXISTS", 3, "hash"), (*Server).cmdHExists},
+	{keyCommand("HGET", 3, "hash"), (*Server).cmdHGet},
+	{keyCommand("HGETALL", 2, "hash"), (*Server).cmdHGetAll},
+	{keyCommand("HINCRBY", 4, "hash"), (*Server).cmdHIncrBy},
+	{keyCommand("HKEYS", 2, "hash"), (*Server).cmdHKeys},
+	{keyCommand("HLEN", 2, "hash"), (*Server).cmdHLen},
+	{keyCommand("HMGET", -3, "hash"), (*Server).cmdHMGet},
+	{keyCommand("HSET", -4, "hash"), (*Server).cmdHSet},
+	{keyCommand("HVALS", 2, "hash"), (*Server).cmdHKeys},
+
+	// lists
+	{keyCommand("LINDEX", 3, "list"), (*Server).cmdLIndex},
+	{keyCommand("LLEN", 2, "list"), (*Server).cmdLLen},
+	{keyCommand("LPOP", -2, "list"), (*Server).cmdPop},
+	{keyCommand("LPUSH", -3, "list"), (*Server).cmdPush},
+	{keyCommand("LRANGE", 4, "list"), (*Server).cmdLRange},
+	{keyCommand("RPOP", -2, "list"), (*Server).cmdPop},
+	{keyCommand("RPUSH", -3, "list"), (*Server).cmdPush},
+
+	// sets
+	{keyCommand("SADD", -3, "set"), (*Server).cmdSAdd},
+	{keyCommand("SCARD", 2, "set"), (*Server).cmdSCard},
+	{keyCommand("SISMEMBER", 3, "set"), (*Server).cmdSIsMember},
+	{keyCommand("SMEMBERS", 2, "set"), (*Server).cmdSMembers},
+	{keyCommand("SREM", -3, "set"), (*Server).cmdSRem},
+
+	// sorted sets
+	{keyCommand("ZADD", -4, "sorted-set"), (*Server).cmdZAdd},
+	{keyCommand("ZCARD", 2, "sorted-set"), (*Server).cmdZCard},
+	{keyCommand("ZINCRBY", 4, "sorted-set"), (*Server).cmdZIncrBy},
+	{keyCommand("ZRANGE", -4, "sorted-set"), (*Server).cmdZRange},
+	{keyCommand("ZRANK", 3, "sorted-set"), (*Server).cmdZRank},
+	{keyCommand("ZREM", -3, "sorted-set"), (*Server).cmdZRem},
+	{keyCommand("ZSCORE", 3, "sorted-set"), (*Server).cmdZScore},
+
+	// transactions
+	{resp.CommandInfo{Name: "DISCARD", Arity: 1, Group: "transactions"}, (*Server).cmdDiscard},
+	{resp.CommandInfo{Name: "EXEC", Arity: 1, Group: "transactions"}, (*Server).cmdExec},
+	{resp.CommandInfo{Name: "MULTI", Arity: 1, Group: "transactions"}, (*Server).cmdMulti},
+}
+
+// keyCommand returns the CommandInfo of a command with a single key as first argument.
+func keyCommand(name string, arity int, group string) resp.CommandInfo {
+	return resp.CommandInfo{Name: name, Arity: arity, FirstKey: 1, LastKey: 1, Step: 1, Group: group}
+}
+
+// keysCommand returns the CommandInfo of a command with keys starting at the first argument, each followed by step-1
+// other arguments.
+func keysCommand(name string, arity, step int, group string) resp.CommandInfo {
+	return resp.CommandInfo{Name: name, Arity: arity, FirstKey: 1, LastKey: -1, Step: step, Group: group}
+}
+
+func invalidExpireError(cmd *resp.Command) string {
+	return "ERR invalid expire time in '" + strings.ToLower(cmd.Name()) + "' command"
+}
+
+// reply helpers
+
+func writeOK(w resp.ReplyWriter) {
+	_, _ = w.WriteSimpleString("OK")
+}
+
+func writeError(w resp.ReplyWriter, msg string) {
+	_, _ = w.WriteError(msg)
+}
+
+func writeNil(w resp.ReplyWriter) {
+	if w.Protocol() == resp.RESP3 {
+		_, _ = w.WriteNull()
+	} else {
+		_, _ = w.WriteBulkStringHeader(-1)
+	}
+}
+
+func writeNilArray(w resp.ReplyWriter) {
+	if w.Protocol() == resp.RESP3 {
+		_, _ = w.WriteNull()
+	} else {
+		_, _ = w.WriteArrayHeader(-1)
+	}
+}
+
+func writeStrings(w resp.ReplyWriter, ss []string) {
+	_, _ = w.WriteArrayHeader(len(ss))
+	for _, s := range ss {
+		_, _ = w.WriteBulkString(s)
+	}
+}
+
+func writeSet(w resp.ReplyWriter, ss []string) {
+	if w.Protocol() == resp.RESP3 {
+		_, _ = w.WriteSetHeader(len(ss))
+	} else {
+		_, _ = w.WriteArrayHeader(len(ss))
+	}
+	for _, s := range ss {
+		_, _ = w.WriteBulkString(s)
+	}
+}
+
+func writeMapHeader(w resp.ReplyWriter, n int) {
+	if w.Protocol() == resp.RESP3 {
+		_, _ = w.WriteMapHeader(n)
+	} else {
+		_, _ = w.WriteArrayHeader(n * 2)
+	}
+}
+
+func writeFloat(w resp.ReplyWriter, f float64) {
+	if w.Protocol() == resp.RESP3 {
+		_, _ = w.WriteDouble(f)
+	} else {
+		_, _ = w.WriteBulkString(formatFloat(f))
+	}
+}
+
+func writeBool(w resp.ReplyWriter, b bool) {
+	if b {
+		_, _ = w.WriteInteger(1)
+	} else {
+		_, _ = w.WriteInteger(0)
+	}
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	default:
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+}
+
+// argument helpers
+
+func parseInt(b []byte) (int64, bool) {
+	if len(b) == 0 || b[0] == '+' {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(string(b), 10, 64)
+	return n, err == nil
+}
+
+func parseFloat(b []byte) (float64, bool) {
+	switch strings.ToLower(string(b)) {
+	case "inf", "+inf":
+		return math.Inf(1), true
+	case "-inf":
+		return math.Inf(-1), true
+	}
+	f, err := strconv.ParseFloat(string(b), 64)
+	return f, err == nil && !math.IsNaN(f) && !math.IsInf(f, 0)
+}
+
+// lookup returns the entry for key if it exists and has the given type, or nil if it does not exist. If the key
+// holds a value of another type, a WRONGTYPE error is written and ok is false.
+func (s *Server) lookup(w resp.ReplyWriter, key []byte, typ string) (e *entry, ok bool) {
+	e = s.db.lookup(string(key))
+	if e != nil && typeName(e.value) != typ {
+		writeError(w, msgWrongType)
+		return nil, false
+	}
+	return e, true
+}
+
+// lookupOrCreate is like lookup, but creates a new entry with the value zero if the key does not exist.
+func (s *Server) lookupOrCreate(w resp.ReplyWriter, key []byte, typ string, zero interface{}) (*entry, bool) {
+	e, ok := s.lookup(w, key, typ)
+	if ok && e == nil {
+		e = &entry{value: zero}
+		s.db.keys[string(key)] = e
+	}
+	return e, ok
+}
+
+// connection
+
+func (s *Server) cmdEcho(w resp.ReplyWriter, cmd *resp.Command) {
+	_, _ = w.WriteBulkStringBytes(cmd.Args[1])
+}
+
+func (s *Server) cmdHello(w resp.ReplyWriter, cmd *resp.Command) {
+	proto := w.Protocol()
+	if len(cmd.Args) > 1 {
+		n, ok := parseInt(cmd.Args[1])
+		if !ok {
+			writeError(w, "ERR Protocol version is not an integer or out of range")
+			return
+		}
+		if n != 2 && n != 3 {
+			writeError(w, "NOPROTO unsupported protocol version")
+			return
+		}
+		proto = resp.Protocol(n)
+	}
+
+	for i := 2; i < len(cmd.Args); i++ {
+		switch {
+		case strings.EqualFold(string(cmd.Args[i]), "AUTH") && i+2 < len(cmd.Args):
+			i += 2
+		case strings.EqualFold(string(cmd.Args[i]), "SETNAME") && i+1 < len(cmd.Args):
+			i++
+		default:
+			writeError(w, "ERR Syntax error in HELLO option '"+string(cmd.Args[i])+"'")
+			return
+		}
+	}
+
+	if c := cmd.Conn(); c != nil {
+		c.SetProtocol(proto)
+	}
+
+	writeMapHeader(w, 7)
+	_, _ = w.WriteBulkString("server")
+	_, _ = w.WriteBulkString("redis")
+	_, _ = w.WriteBulkString("version")
+	_, _ = w.WriteBulkString("7.0.0")
+	_, _ = w.WriteBulkString("proto")
+	_, _ = w.WriteInteger(int(proto))
+	_, _ = w.WriteBulkString("id")
+	_, _ = w.WriteInteger(1)
+	_, _ = w.WriteBulkString("mode")
+	_, _ = w.WriteBulkString("standalone")
+	_, _ = w.WriteBulkString("role")
+	_, _ = w.WriteBulkString("master")
+	_, _ = w.WriteBulkString("modules")
+	_, _ = w.WriteArrayHeader(0)
+}
+
+func (s *Server) cmdPing(w resp.ReplyWriter, cmd *resp.Command) {
+	switch len(cmd.Args) {
+	case 1:
+		_, _ = w.WriteSimpleString("PONG")
+	case 2:
+		_, _ = w.WriteBulkStringBytes(cmd.Args[1])
+	default:
+		writeError(w, "ERR wrong number of arguments for 'ping' command")
+	}
+}
+
+func (s *Server) cmdQuit(w resp.ReplyWriter, cmd *resp.Command) {
+	writeOK(w)
+	if c := cmd.Conn(); c != nil {
+		c.Close()
+	}
+}
+
+func (s *Server) cmdSelect(w resp.ReplyWriter, cmd *resp.Command) {
+	n, ok := parseInt(cmd.Args[1])
+	switch {
+	case !ok:
+		writeError(w, msgNotInteger)
+	case n != 0:
+		writeError(w, "ERR DB index is out of range")
+	default:
+		writeOK(w)
+	}
+}
+
+// server
+
+func (s *Server) cmdDBSize(w resp.ReplyWriter, cmd *resp.Command) {
+	_, _ = w.WriteInteger(len(s.db.sortedKeys()))
+}
+
+func (s *Server) cmdFlush(w resp.ReplyWriter, cmd *resp.Command) {
+	for _, arg := range cmd.Args[1:] {
+		if !strings.EqualFold(string(arg), "SYNC") && !strings.EqualFold(string(arg), "ASYNC") {
+			writeError(w, msgSyntax)
+			return
+		}
+	}
+	s.db.keys = make(map[string]*entry)
+	writeOK(w)
+}
+
+// generic
+
+func (s *Server) cmdDel(w resp.ReplyWriter, cmd *resp.Command) {
+	var n int
+	for _, key := range cmd.Args[1:] {
+		if s.db.del(string(key)) {
+			n++
+		}
+	}
+	_, _ = w.WriteInteger(n)
+}
+
+func (s *Server) cmdExists(w resp.ReplyWriter, cmd *resp.Command) {
+	var n int
+	for _, key := range cmd.Args[1:] {
+		if s.db.lookup(string(key)) != nil {
+			n++
+		}
+	}
+	_, _ = w.WriteInteger(n)
+}
+
+func (s *Server) cmdExpire(w resp.ReplyWriter, cmd *resp.Command) {
+	n, ok := parseInt(cmd.Args[2])
+	if !ok {
+		writeError(w, msgNotInteger)
+		return
+	}
+
+	unit := time.Second
+	if cmd.Is("PEXPIRE") {
+		unit = time.Millisecond
+	}
+	if n > math.MaxInt64/int64(unit) || n < math.MinInt64/int64(unit) {
+		writeError(w, invalidExpireError(cmd))
+		return
+	}
+
+	e := s.db.lookup(string(cmd.Args[1]))
+	if e == nil {
+		writeBool(w, false)
+		return
+	}
+
+	if n <= 0 {
+		s.db.del(string(cmd.Args[1]))
+	} else {
+		e.expireAt = s.db.now().Add(time.Duration(n) * unit)
+	}
+	writeBool(w, true)
+}
+
+func (s *Server) cmdKeys(w resp.ReplyWriter, cmd *resp.Command) {
+	pattern := string(cmd.Args[1])
+
+	var keys []string
+	for _, key := range s.db.sortedKeys() {
+		if match(pattern, key) {
+			keys = append(keys, key)
+		}
+	}
+	writeStrings(w, keys)
+}
+
+func (s *Server) cmdPersist(w resp.ReplyWriter, cmd *resp.Command) {
+	e := s.db.lookup(string(cmd.Args[1]))
+	if e == nil || e.expireAt.IsZero() {
+		writeBool(w, false)
+		return
+	}
+	e.expireAt = time.Time{}
+	writeBool(w, true)
+}
+
+func (s *Server) cmdTTL(w resp.ReplyWriter, cmd *resp.Command) {
+	e := s.db.lookup(string(cmd.Args[1]))
+	switch {
+	case e == nil:
+		_, _ = w.WriteInteger(-2)
+	case e.expireAt.IsZero():
+		_, _ = w.WriteInteger(-1)
+	default:
+		ttl := e.expireAt.Sub(s.db.now())
+		if cmd.Is("PTTL") {
+			_, _ = w.WriteInteger(int(ttl / time.Millisecond))
+		} else {
+			_, _ = w.WriteInteger(int((ttl + 500*time.Millisecond) / time.Second))
+		}
+	}
+}
+
+func (s *Server) cmdType(w resp.ReplyWriter, cmd *resp.Command) {
+	var v interface{}
+	if e := s.db.lookup(string(cmd.Args[1])); e != nil {
+		v = e.value
+	}
+	_, _ = w.WriteSimpleString(typeName(v))
+}
+
+// strings
+
+func (s *Server) cmdAppend(w resp.ReplyWriter, cmd *resp.Command) {
+	e, ok := s.lookupOrCreate(w, cmd.Args[1], "string", "")
+	if !ok {
+		return
+	}
+	v := e.value.(string) + string(cmd.Args[2])
+	e.value = v
+	_, _ = w.WriteInteger(len(v))
+}
+
+func (s *Server) cmdGet(w resp.ReplyWriter, cmd *resp.Command) {
+	e, ok := s.lookup(w, cmd.Args[1], "string")
+	switch {
+	case !ok:
+	case e == nil:
+		writeNil(w)
+	default:
+		if cmd.Is("GETDEL") {
+			s.db.del(string(cmd.Args[1]))
+		}
+		_, _ = w.WriteBulkString(e.value.(string))
+	}
+}
+
+func (s *Server) cmdIncr(w resp.ReplyWriter, cmd *resp.Command) {
+	by := int64(1)
+	if len(cmd.Args) > 2 {
+		var ok bool
+		if by, ok = parseInt(cmd.Args[2]); !ok {
+			writeError(w, msgNotInteger)
+			return
+		}
+	}
+	if cmd.Is("DECR") || cmd.Is("DECRBY") {
+		if by == math.MinInt64 {
+			writeError(w, "ERR decrement would overflow")
+			return
+		}
+		by = -by
+	}
+
+	e, ok := s.lookup(w, cmd.Args[1], "string")
+	if !ok {
+		return
+	}
+
+	var n int64
+	if e != nil {
+		if n, ok = parseInt([]byte(e.value.(string))); !ok {
+			writeError(w, msgNotInteger)
+			return
+		}
+	}
+	if (by > 0 && n > math.MaxInt64-by) || (by < 0 && n < math.MinInt64-by) {
+		writeError(w, msgOverflow)
+		return
+	}
+	n += by
+
+	if e == nil {
+		s.db.set(string(cmd.Args[1]), strconv.FormatInt(n, 10))
+	} else {
+		e.value = strconv.FormatInt(n, 10)
+	}
+	_, _ = w.WriteInteger(int(n))
+}
+
+func (s *Server) cmdIncrByFloat(w resp.ReplyWriter, cmd *resp.Command) {
+	by, ok := parseFloat(cmd.Args[2])
+	if !ok {
+		writeError(w, msgNotFloat)
+		return
+	}
+
+	e, ok := s.lookup(w, cmd.Args[1], "string")
+	if !ok {
+		return
+	}
+
+	var f float64
+	if e != nil {
+		if f, ok = parseFloat([]byte(e.value.(string))); !ok {
+			writeError(w, msgNotFloat)
+			return
+		}
+	}
+	f += by
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		writeError(w, "ERR increment would produce NaN or Infinity")
+		return
+	}
+
+	v := strconv.FormatFloat(f, 'f', -1, 64)
+	if e == nil {
+		s.db.set(string(cmd.Args[1]), v)
+	} else {
+		e.value = v
+	}
+	_, _ = w.WriteBulkString(v)
+}
+
+func (s *Server) cmdMGet(w resp.ReplyWriter, cmd *resp.Command) {
+	_, _ = w.WriteArrayHeader(len(cmd.Args) - 1)
+	for _, key := range cmd.Args[1:] {
+		e := s.db.lookup(string(key))
+		if v, ok := valueOf(e).(string); ok {
+			_, _ = w.WriteBulkString(v)
+		} else {
+			writeNil(w)
+		}
+	}
+}
+
+func valueOf(e *entry) interface{} {
+	if e == nil {
+		return nil
+	}
+	return e.value
+}
+
+func (s *Server) cmdMSet(w resp.ReplyWriter, cmd *resp.Command) {
+	if len(cmd.Args)%2 != 1 {
+		writeError(w, "ERR wrong number of arguments for 'mset' command")
+		return
+	}
+	for i := 1; i < len(cmd.Args); i += 2 {
+		s.db.set(string(cmd.Args[i]), string(cmd.Args[i+1]))
+	}
+	writeOK(w)
+}
+
+func (s *Server) cmdSet(w resp.ReplyWriter, cmd *resp.Command) {
+	var nx, xx, get, keepTTL bool
+	var expireAt time.Time
+
+	for i := 3; i < len(cmd.Args); i++ {
+		switch opt := strings.ToUpper(string(cmd.Args[i])); {
+		case opt == "NX" && !xx:
+			nx = true
+		case opt == "XX" && !nx:
+			xx = true
+		case opt == "GET":
+			get = true
+		case opt == "KEEPTTL" && expireAt.IsZero():
+			keepTTL = true
+		case (opt == "EX" || opt == "PX") && !keepTTL && expireAt.IsZero() && i+1 < len(cmd.Args):
+			i++
+			n, ok := parseInt(cmd.Args[i])
+			if !ok {
+				writeError(w, msgNotInteger)
+				return
+			}
+			unit := time.Second
+			if opt == "PX" {
+				unit = time.Millisecond
+			}
+			if n <= 0 || n > math.MaxInt64/int64(unit) {
+				writeError(w, invalidExpireError(cmd))
+				return
+			}
+			expireAt = s.db.now().Add(time.Duration(n) * unit)
+		default:
+			writeError(w, msgSyntax)
+			return
+		}
+	}
+
+	key := string(cmd.Args[1])
+
+	// SET overwrites values of any type, unless GET is used
+	e := s.db.lookup(key)
+	if get && e != nil && typeName(e.value) != "string" {
+		writeError(w, msgWrongType)
+		return
+	}
+
+	if (nx && e != nil) || (xx && e == nil) {
+		if get {
+			s.writeOldValue(w, e)
+		} else {
+			writeNil(w)
+		}
+		return
+	}
+
+	ne := &entry{value: string(cmd.Args[2]), expireAt: expireAt}
+	if keepTTL && e != nil {
+		ne.expireAt = e.expireAt
+	}
+	s.db.keys[key] = ne
+
+	if get {
+		s.writeOldValue(w, e)
+	} else {
+		writeOK(w)
+	}
+}
+
+func (s *Server) writeOldValue(w resp.ReplyWriter, e *entry) {
+	if e == nil {
+		writeNil(w)
+	} else {
+		_, _ = w.WriteBulkString(e.value.(string))
+	}
+}
+
+func (s *Server) cmdSetNX(w resp.ReplyWriter, cmd *resp.Command) {
+	if s.db.lookup(string(cmd.Args[1])) != nil {
+		writeBool(w, false)
+		return
+	}
+	s.db.set(string(cmd.Args[1]), string(cmd.Args[2]))
+	writeBool(w, true)
+}
+
+func (s *Server) cmdStrlen(w resp.ReplyWriter, cmd *resp.Command) {
+	if e, ok := s.lookup(w, cmd.Args[1], "string"); ok {
+		v, _ := valueOf(e).(string)
+		_, _ = w.WriteInteger(len(v))
+	}
+}
+
+// hashes
+
+func (s *Server) cmdHDel(w resp.ReplyWriter, cmd *resp.Command) {
+	e, ok := s.lookup(w, cmd.Args[1], "hash")
+	if !ok {
+		return
+	}
+	var n int
+	if e != nil {
+		h := e.value.(hash)
+		for _, field := range cmd.Args[2:] {
+			if _, ok := h[string(field)]; ok {
+				delete(h, string(field))
+				n++
+			}
+		}
+		s.db.removeIfEmpty(string(cmd.Args[1]))
+	}
+	_, _ = w.WriteInteger(n)
+}
+
+func (s *Server) cmdHExists(w resp.ReplyWriter, cmd *resp.Command) {
+	if e, ok := s.lookup(w, cmd.Args[1], "hash"); ok {
+		h, _ := valueOf(e).(hash)
+		_, exists := h[string(cmd.Args[2])]
+		writeBool(w, exists)
+	}
+}
+
+func (s *Server) cmdHGet(w resp.ReplyWriter, cmd *resp.Command) {
+	if e, ok := s.lookup(w, cmd.Args[1], "hash"); ok {
+		h, _ := valueOf(e).(hash)
+		if v, ok := h[string(cmd.Args[2])]; ok {
+			_, _ = w.WriteBulkString(v)
+		} else {
+			writeNil(w)
+		}
+	}
+}
+
+func (s *Server) cmdHGetAll(w resp.ReplyWriter, cmd *resp.Command) {
+	e, ok := s.lookup(w, cmd.Args[1], "hash")
+	if !ok {
+		return
+	}
+	h, _ := valueOf(e).(hash)
+	fields := sortedFields(h)
+
+	writeMapHeader(w, len(fields))
+	for _, field := range fields {
+		_, _ = w.WriteBulkString(field)
+		_, _ = w.WriteBulkString(h[field])
+	}
+}
+
+func sortedFields(h hash) []string {
+	fields := make([]string, 0, len(h))
+	for field := range h {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+func (s *Server) cmdHIncrBy(w resp.ReplyWriter, cmd *resp.Command) {
+	by, ok := parseInt(cmd.Args[3])
+	if !ok {
+		writeError(w, msgNotInteger)
+		return
+	}
+
+	e, ok := s.lookupOrCreate(w, cmd.Args[1], "hash", hash{})
+	if !ok {
+		return
+	}
+	h := e.value.(hash)
+
+	var n int64
+	if v, exists := h[string(cmd.Args[2])]; exists {
+		if n, ok = parseInt([]byte(v)); !ok {
+			writeError(w, "ERR hash value is not an integer")
+			return
+		}
+	}
+	if (by > 0 && n > math.MaxInt64-by) || (by < 0 && n < math.MinInt64-by) {
+		writeError(w, msgOverflow)
+		return
+	}
+	n += by
+
+	h[string(cmd.Args[2])] = strconv.FormatInt(n, 10)
+	_, _ = w.WriteInteger(int(n))
+}
+
+func (s *Server) cmdHKeys(w resp.ReplyWriter, cmd *resp.Command) {
+	e, ok := s.lookup(w, cmd.Args[1], "hash")
+	if !ok {
+		return
+	}
+	h, _ := valueOf(e).(hash)
+	fields := sortedFields(h)
+
+	if cmd.Is("HVALS") {
+		for i, field := range fields {
+			fields[i] = h[field]
+		}
+	}
+	writeStrings(w, fields)
+}
+
+func (s *Server) cmdHLen(w resp.ReplyWriter, cmd *resp.Command) {
+	if e, ok := s.lookup(w, cmd.Args[1], "hash"); ok {
+		h, _ := valueOf(e).(hash)
+		_, _ = w.WriteInteger(len(h))
+	}
+}
+
+func (s *Server) cmdHMGet(w resp.ReplyWriter, cmd *resp.Command) {
+	e, ok := s.lookup(w, cmd.Args[1], "hash")
+	if !ok {
+		return
+	}
+	h, _ := valueOf(e).(hash)
+
+	_, _ = w.WriteArrayHeader(len(cmd.Args) - 2)
+	for _, field := range cmd.Args[2:] {
+		if v, ok := h[string(field)]; ok {
+			_, _ = w.WriteBulkString(v)
+		} else {
+			writeNil(w)
+		}
+	}
+}
+
+func (s *Server) cmdHSet(w resp.ReplyWriter, cmd *resp.Command) {
+	if len(cmd.Args)%2 != 0 {
+		writeError(w, "ERR wrong number of arguments for 'hset' command")
+		return
+	}
+
+	e, ok := s.lookupOrCreate(w, cmd.Args[1], "hash", hash{})
+	if !ok {
+		return
+	}
+	h := e.value.(hash)
+
+	var n int
+	for i := 2; i < len(cmd.Args); i += 2 {
+		if _, exists := h[string(cmd.Args[i])]; !exists {
+			n++
+		}
+		h[string(cmd.Args[i])] = string(cmd.Args[i+1])
+	}
+	_, _ = w.WriteInteger(n)
+}
+
+// lists
+
+// listRange converts the start and stop indexes, which may be negative, into a range [start, stop) for a list of
+// length n.
+func listRange(start, stop int64, n int) (int, int) {
+	if start < 0 {
+		start += int64(n)
+	}
+	if stop < 0 {
+		stop += int64(n)
+	}
+	if start < 0 {
+		start = 0
+	}
+	if stop >= int64(n) {
+		stop = int64(n) - 1
+	}
+	if start > stop || start >= int64(n) {
+		return 0, 0
+	}
+	return int(start), int(stop) + 1
+}
+
+func (s *Server) cmdLIndex(w resp.ReplyWriter, cmd *resp.Command) {
+	idx, ok := parseInt(cmd.Args[2])
+	if !ok {
+		writeError(w, msgNotInteger)
+		return
+	}
+	e, ok := s.lookup(w, cmd.Args[1], "list")
+	if !ok {
+		return
+	}
+	l, _ := valueOf(e).(list)
+	if idx < 0 {
+		idx += int64(len(l))
+	}
+	if idx < 0 || idx >= int64(len(l)) {
+		writeNil(w)
+		return
+	}
+	_, _ = w.WriteBulkString(l[idx])
+}
+
+func (s *Server) cmdLLen(w resp.ReplyWriter, cmd *resp.Command) {
+	if e, ok := s.lookup(w, cmd.Args[1], "list"); ok {
+		l, _ := valueOf(e).(list)
+		_, _ = w.WriteInteger(len(l))
+	}
+}
+
+func (s *Server) cmdPop(w resp.ReplyWriter, cmd *resp.Command) {
+	count, withCount := int64(1), len(cmd.Args) > 2
+	if len(cmd.Args) > 3 {
+		writeError(w, msgSyntax)
+		return
+	}
+	if withCount {
+		var ok bool
+		if count, ok = parseInt(cmd.Args[2]); !ok || count < 0 {
+			writeError(w, "ERR value is out of range, must be positive")
+			return
+		}
+	}
+
+	e, ok := s.lookup(w, cmd.Args[1], "list")
+	if !ok {
+		return
+	}
+	if e == nil {
+		if withCount {
+			writeNilArray(w)
+		} else {
+			writeNil(w)
+		}
+		return
+	}
+
+	l := e.value.(list)
+	if count > int64(len(l)) {
+		count = int64(len(l))
+	}
+
+	popped := make([]string, count)
+	if cmd.Is("LPOP") {
+		copy(popped, l[:count])
+		l = l[count:]
+	} else {
+		for i := range popped {
+			popped[i] = l[len(l)-1-i]
+		}
+		l = l[:len(l)-int(count)]
+	}
+	e.value = l
+	s.db.removeIfEmpty(string(cmd.Args[1]))
+
+	if withCount {
+		writeStrings(w, popped)
+	} else {
+		_, _ = w.WriteBulkString(popped[0])
+	}
+}
+
+func (s *Server) cmdPush(w resp.ReplyWriter, cmd *resp.Command) {
+	e, ok := s.lookupOrCreate(w, cmd.Args[1], "list", list{})
+	if !ok {
+		return
+	}
+
+	l := e.value.(list)
+	for _, v := range cmd.Args[2:] {
+		if cmd.Is("LPUSH") {
+			l = append(list{string(v)}, l...)
+		} else {
+			l = append(l, string(v))
+		}
+	}
+	e.value = l
+	_, _ = w.WriteInteger(len(l))
+}
+
+func (s *Server) cmdLRange(w resp.ReplyWriter, cmd *resp.Command) {
+	start, ok1 := parseInt(cmd.Args[2])
+	stop, ok2 := parseInt(cmd.Args[3])
+	if !ok1 || !ok2 {
+		writeError(w, msgNotInteger)
+		return
+	}
+	e, ok := s.lookup(w, cmd.Args[1], "list")
+	if !ok {
+		return
+	}
+	l, _ := valueOf(e).(list)
+	from, to := listRange(start, stop, len(l))
+	writeStrings(w, l[from:to])
+}
+
+// sets
+
+func (s *Server) cmdSAdd(w resp.ReplyWriter, cmd *resp.Command) {
+	e, ok := s.lookupOrCreate(w, cmd.Args[1], "set", set{})
+	if !ok {
+		return
+	}
+	st := e.value.(set)
+
+	var n int
+	for _, member := range cmd.Args[2:] {
+		if _, exists := st[string(member)]; !exists {
+			st[string(member)] = struct{}{}
+			n++
+		}
+	}
+	_, _ = w.WriteInteger(n)
+}
+
+func (s *Server) cmdSCard(w resp.ReplyWriter, cmd *resp.Command) {
+	if e, ok := s.lookup(w, cmd.Args[1], "set"); ok {
+		st, _ := valueOf(e).(set)
+		_, _ = w.WriteInteger(len(st))
+	}
+}
+
+func (s *Server) cmdSIsMember(w resp.ReplyWriter, cmd *resp.Command) {
+	if e, ok := s.lookup(w, cmd.Args[1], "set"); ok {
+		st, _ := valueOf(e).(set)
+		_, exists := st[string(cmd.Args[2])]
+		writeBool(w, exists)
+	}
+}
+
+func (s *Server) cmdSMembers(w resp.ReplyWriter, cmd *resp.Command) {
+	e, ok := s.lookup(w, cmd.Args[1], "set")
+	if !ok {
+		return
+	}
+	st, _ := valueOf(e).(set)
+
+	members := make([]string, 0, len(st))
+	for member := range st {
+		members = append(members, member)
+	}
+	sort.Strings(members)
+	writeSet(w, members)
+}
+
+func (s *Server) cmdSRem(w resp.ReplyWriter, cmd *resp.Command) {
+	e, ok := s.lookup(w, cmd.Args[1], "set")
+	if !ok {
+		return
+	}
+	var n int
+	if e != nil {
+		st := e.value.(set)
+		for _, member := range cmd.Args[2:] {
+			if _, exists := st[string(member)]; exists {
+				delete(st, string(member))
+				n++
+			}
+		}
+		s.db.removeIfEmpty(string(cmd.Args[1]))
+	}
+	_, _ = w.WriteInteger(n)
+}
+
+// sorted sets
+
+func (s *Server) cmdZAdd(w resp.ReplyWriter, cmd *resp.Command) {
+	var nx, xx, ch bool
+
+	i := 2
+loop:
+	for ; i < len(cmd.Args); i++ {
+		switch strings.ToUpper(string(cmd.Args[i])) {
+		case "NX":
+			nx = true
+		case "XX":
+			xx = true
+		case "CH":
+			ch = true
+		default:
+			break loop
+		}
+	}
+
+	pairs := cmd.Args[i:]
+	if len(pairs) == 0 || len(pairs)%2 != 0 {
+		writeError(w, msgSyntax)
+		return
+	}
+	if nx && xx {
+		writeError(w, "ERR XX and NX options at the same time are not compatible")
+		return
+	}
+
+	scores := make([]float64, len(pairs)/2)
+	for j := range scores {
+		var ok bool
+		if scores[j], ok = parseFloat(pairs[j*2]); !ok {
+			writeError(w, msgNotFloat)
+			return
+		}
+	}
+
+	e, ok := s.lookup(w, cmd.Args[1], "zset")
+	if !ok {
+		return
+	}
+	if e == nil {
+		if xx {
+			_, _ = w.WriteInteger(0)
+			return
+		}
+		e = &entry{value: zset{}}
+		s.db.keys[string(cmd.Args[1])] = e
+	}
+	z := e.value.(zset)
+
+	var added, changed int
+	for j, score := range scores {
+		member := string(pairs[j*2+1])
+		old, exists := z[member]
+		switch {
+		case exists && nx, !exists && xx:
+			continue
+		case !exists:
+			added++
+		case old != score:
+			changed++
+		}
+		z[member] = score
+	}
+	s.db.removeIfEmpty(string(cmd.Args[1]))
+
+	if ch {
+		_, _ = w.WriteInteger(added + changed)
+	} else {
+		_, _ = w.WriteInteger(added)
+	}
+}
+
+func (s *Server) cmdZCard(w resp.ReplyWriter, cmd *resp.Command) {
+	if e, ok := s.lookup(w, cmd.Args[1], "zset"); ok {
+		z, _ := valueOf(e).(zset)
+		_, _ = w.WriteInteger(len(z))
+	}
+}
+
+func (s *Server) cmdZIncrBy(w resp.ReplyWriter, cmd *resp.Command) {
+	by, ok := parseFloat(cmd.Args[2])
+	if !ok {
+		writeError(w, msgNotFloat)
+		return
+	}
+	e, ok := s.lookupOrCreate(w, cmd.Args[1], "zset", zset{})
+	if !ok {
+		return
+	}
+	z := e.value.(zset)
+
+	score := z[string(cmd.Args[3])] + by
+	if math.IsNaN(score) {
+		writeError(w, "ERR resulting score is not a number (NaN)")
+		return
+	}
+	z[string(cmd.Args[3])] = score
+	writeFloat(w, score)
+}
+
+func (s *Server) cmdZRange(w resp.ReplyWriter, cmd *resp.Command) {
+	start, ok1 := parseInt(cmd.Args[2])
+	stop, ok2 := parseInt(cmd.Args[3])
+	if !ok1 || !ok2 {
+		writeError(w, msgNotInteger)
+		return
+	}
+
+	withScores := false
+	for _, arg := range cmd.Args[4:] {
+		if !strings.EqualFold(string(arg), "WITHSCORES") {
+			writeError(w, msgSyntax)
+			return
+		}
+		withScores = true
+	}
+
+	e, ok := s.lookup(w, cmd.Args[1], "zset")
+	if !ok {
+		return
+	}
+	z, _ := valueOf(e).(zset)
+	ms := z.sorted()
+	from, to := listRange(start, stop, len(ms))
+	ms = ms[from:to]
+
+	switch {
+	case !withScores:
+		_, _ = w.WriteArrayHeader(len(ms))
+	case w.Protocol() == resp.RESP3:
+		_, _ = w.WriteArrayHeader(len(ms))
+	default:
+		_, _ = w.WriteArrayHeader(len(ms) * 2)
+	}
+	for _, m := range ms {
+		if withScores && w.Protocol() == resp.RESP3 {
+			_, _ = w.WriteArrayHeader(2)
+		}
+		_, _ = w.WriteBulkString(m.member)
+		if withScores {
+			writeFloat(w, m.score)
+		}
+	}
+}
+
+func (s *Server) cmdZRank(w resp.ReplyWriter, cmd *resp.Command) {
+	e, ok := s.lookup(w, cmd.Args[1], "zset")
+	if !ok {
+		return
+	}
+	z, _ := valueOf(e).(zset)
+	for i, m := range z.sorted() {
+		if m.member == string(cmd.Args[2]) {
+			_, _ = w.WriteInteger(i)
+			return
+		}
+	}
+	writeNil(w)
+}
+
+func (s *Server) cmdZRem(w resp.ReplyWriter, cmd *resp.Command) {
+	e, ok := s.lookup(w, cmd.Args[1], "zset")
+	if !ok {
+		return
+	}
+	var n int
+	if e != nil {
+		z := e.value.(zset)
+		for _, member := range cmd.Args[2:] {
+			if _, exists := z[string(member)]; exists {
+				delete(z, string(member))
+				n++
+			}
+		}
+		s.db.removeIfEmpty(string(cmd.Args[1]))
+	}
+	_, _ = w.WriteInteger(n)
+}
+
+func (s *Server) cmdZScore(w resp.ReplyWriter, cmd *resp.Command) {
+	if e, ok := s.lookup(w, cmd.Args[1], "zset"); ok {
+		z, _ := valueOf(e).(zset)
+		if score, ok := z[string(cmd.Args[2])]; ok {
+			writeFloat(w, score)
+		} else {
+			writeNil(w)
+		}
+	}
+}
+
+// transactions
+
+func (s *Server) cmdDiscard(w resp.ReplyWriter, cmd *resp.Command) {
+	st := getConnState(cmd)
+	if !st.multi {
+		writeError(w, "ERR DISCARD without MULTI")
+		return
+	}
+	*st = connState{}
+	writeOK(w)
+}
+
+func (s *Server) cmdExec(w resp.ReplyWriter, cmd *resp.Command) {
+	st := getConnState(cmd)
+	if !st.multi {
+		writeError(w, "ERR EXEC without MULTI")
+		return
+	}
+
+	queue, dirty := st.queue, st.dirty
+	*st = connState{}
+
+	if dirty {
+		writeError(w, "EXECABORT Transaction discarded because of previous errors.")
+		return
+	}
+
+	// reuse cmd, so that the queued commands have the same context and connection
+	args := cmd.Args
+	defer func() { cmd.Args = args }()
+
+	_, _ = w.WriteArrayHeader(len(queue))
+	for _, qargs := range queue {
+		cmd.Args = qargs
+		s.mux.ServeRESP(w, cmd)
+	}
+}
+
+func (s *Server) cmdMulti(w resp.ReplyWriter, cmd *resp.Command) {
+	st := getConnState(cmd)
+	if st.multi {
+		writeError(w, "ERR MULTI calls can not be nested")
+		return
+	}
+	st.multi = true
+	writeOK(w)
+}
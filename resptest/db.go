@@ -0,0 +1,227 @@
+package resptest
+
+import (
+	"sort"
+	"time"
+)
+
+// entry is a single key stored in a db.
+type entry struct {
+	// value is one of string, hash, list, set or zset.
+	value interface{}
+
+	// expireAt is the time at which the key expires or the zero time if the key does not expire.
+	expireAt time.Time
+}
+
+type (
+	hash map[string]string
+	list []string
+	set  map[string]struct{}
+	zset map[string]float64
+)
+
+// db is the keyspace of a Server. All access must be synchronized by the caller.
+type db struct {
+	keys   map[string]*entry
+	offset time.Duration
+}
+
+func newDB() *db {
+	return &db{keys: make(map[string]*entry)}
+}
+
+// now returns the current time as seen by the db, including any fast-forwarded duration.
+func (d *db) now() time.Time {
+	return time.Now().Add(d.offset)
+}
+
+// lookup returns the entry for key, removing it first if it is expired.
+func (d *db) lookup(key string) *entry {
+	e := d.keys[key]
+	if e == nil {
+		return nil
+	}
+	if !e.expireAt.IsZero() && !d.now().Before(e.expireAt) {
+		delete(d.keys, key)
+		return nil
+	}
+	return e
+}
+
+// set stores value under key, removing any expiry.
+func (d *db) set(key string, value interface{}) {
+	d.keys[key] = &entry{value: value}
+}
+
+// del removes key and reports whether it existed.
+func (d *db) del(key string) bool {
+	if d.lookup(key) == nil {
+		return false
+	}
+	delete(d.keys, key)
+	return true
+}
+
+// removeIfEmpty removes key if its value is an empty collection, like Redis does.
+func (d *db) removeIfEmpty(key string) {
+	e := d.keys[key]
+	if e == nil {
+		return
+	}
+
+	var n int
+	switch v := e.value.(type) {
+	case hash:
+		n = len(v)
+	case list:
+		n = len(v)
+	case set:
+		n = len(v)
+	case zset:
+		n = len(v)
+	default:
+		return
+	}
+	if n == 0 {
+		delete(d.keys, key)
+	}
+}
+
+// sortedKeys returns all keys that are not expired in lexicographical order.
+func (d *db) sortedKeys() []string {
+	keys := make([]string, 0, len(d.keys))
+	for key := range d.keys {
+		if d.lookup(key) != nil {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// typeName returns the name of the type of v as returned by the TYPE command.
+func typeName(v interface{}) string {
+	switch v.(type) {
+	case string:
+		return "string"
+	case hash:
+		return "hash"
+	case list:
+		return "list"
+	case set:
+		return "set"
+	case zset:
+		return "zset"
+	default:
+		return "none"
+	}
+}
+
+// zsetMember is a single member of a sorted set.
+type zsetMember struct {
+	member string
+	score  float64
+}
+
+// sorted returns the members of z ordered by score and member.
+func (z zset) sorted() []zsetMember {
+	ms := make([]zsetMember, 0, len(z))
+	for member, score := range z {
+		ms = append(ms, zsetMember{member: member, score: score})
+	}
+	sort.Slice(ms, func(i, j int) bool {
+		if ms[i].score != ms[j].score {
+			return ms[i].score < ms[j].score
+		}
+		return ms[i].member < ms[j].member
+	})
+	return ms
+}
+
+// match reports whether s matches the glob-style pattern as used by the KEYS command.
+func match(pattern, s string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for len(pattern) > 1 && pattern[1] == '*' {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 1 {
+				return true
+			}
+			for i := 0; i <= len(s); i++ {
+				if match(pattern[1:], s[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(s) == 0 {
+				return false
+			}
+			s = s[1:]
+			pattern = pattern[1:]
+		case '[':
+			if len(s) == 0 {
+				return false
+			}
+			end := 1
+			for end < len(pattern) && pattern[end] != ']' {
+				if pattern[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(pattern) {
+				// unterminated class, match literally
+				if s[0] != '[' {
+					return false
+				}
+				s, pattern = s[1:], pattern[1:]
+				continue
+			}
+			if !matchClass(pattern[1:end], s[0]) {
+				return false
+			}
+			s, pattern = s[1:], pattern[end+1:]
+		case '\\':
+			if len(pattern) > 1 {
+				pattern = pattern[1:]
+			}
+			fallthrough
+		default:
+			if len(s) == 0 || s[0] != pattern[0] {
+				return false
+			}
+			s, pattern = s[1:], pattern[1:]
+		}
+	}
+	return len(s) == 0
+}
+
+func matchClass(class string, c byte) bool {
+	not := len(class) > 0 && class[0] == '^'
+	if not {
+		class = class[1:]
+	}
+
+	matched := false
+	for i := 0; i < len(class); i++ {
+		switch {
+		case class[i] == '\\' && i+1 < len(class):
+			i++
+			matched = matched || class[i] == c
+		case i+2 < len(class) && class[i+1] == '-':
+			lo, hi := class[i], class[i+2]
+			if lo > hi {
+				lo, hi = hi, lo
+			}
+			matched = matched || (c >= lo && c <= hi)
+			i += 2
+		default:
+			matched = matched || class[i] == c
+		}
+	}
+	return matched != not
+}
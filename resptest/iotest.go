@@ -0,0 +1,175 @@
+package resptest
+
+import (
+	"io"
+	"math/rand"
+)
+
+// ChunkReader returns an io.Reader that reads from r in chunks of random size between 1 and max bytes, using rnd
+// to choose the size of each chunk.
+func ChunkReader(r io.Reader, rnd *rand.Rand, max int) io.Reader {
+	if max < 1 {
+		panic("resptest: invalid chunk size")
+	}
+	return &chunkReader{r: r, rnd: rnd, max: max}
+}
+
+type chunkReader struct {
+	r   io.Reader
+	rnd *rand.Rand
+	max int
+}
+
+// Read implements the io.Reader interface.
+func (cr *chunkReader) Read(p []byte) (int, error) {
+	if n := 1 + cr.rnd.Intn(cr.max); len(p) > n {
+		p = p[:n]
+	}
+	return cr.r.Read(p)
+}
+
+// OneByteReader returns an io.Reader that reads from r one byte at a time.
+func OneByteReader(r io.Reader) io.Reader {
+	return oneByteReader{r: r}
+}
+
+type oneByteReader struct {
+	r io.Reader
+}
+
+// Read implements the io.Reader interface.
+func (or oneByteReader) Read(p []byte) (int, error) {
+	if len(p) > 1 {
+		p = p[:1]
+	}
+	return or.r.Read(p)
+}
+
+// ErrAfterReader returns an io.Reader that reads at most n bytes from r and then returns err for all further reads.
+//
+// If r returns an error before n bytes were read, the error is returned as is.
+func ErrAfterReader(r io.Reader, n int, err error) io.Reader {
+	return &errAfterReader{r: r, n: n, err: err}
+}
+
+type errAfterReader struct {
+	r   io.Reader
+	n   int
+	err error
+}
+
+// Read implements the io.Reader interface.
+func (er *errAfterReader) Read(p []byte) (int, error) {
+	if er.n <= 0 {
+		return 0, er.err
+	}
+	if len(p) > er.n {
+		p = p[:er.n]
+	}
+	n, err := er.r.Read(p)
+	er.n -= n
+	return n, err
+}
+
+// ChunkWriter returns an io.Writer that writes to w in chunks of random size between 1 and max bytes, using rnd
+// to choose the size of each chunk.
+func ChunkWriter(w io.Writer, rnd *rand.Rand, max int) io.Writer {
+	if max < 1 {
+		panic("resptest: invalid chunk size")
+	}
+	return &chunkWriter{w: w, rnd: rnd, max: max}
+}
+
+type chunkWriter struct {
+	w   io.Writer
+	rnd *rand.Rand
+	max int
+}
+
+// Write implements the io.Writer interface.
+func (cw *chunkWriter) Write(p []byte) (int, error) {
+	var written int
+	for len(p) > 0 {
+		chunk := p
+		if n := 1 + cw.rnd.Intn(cw.max); len(chunk) > n {
+			chunk = chunk[:n]
+		}
+		n, err := cw.w.Write(chunk)
+		written += n
+		if err != nil {
+			return written, err
+		}
+		p = p[n:]
+	}
+	return written, nil
+}
+
+// OneByteWriter returns an io.Writer that writes to w one byte at a time.
+func OneByteWriter(w io.Writer) io.Writer {
+	return oneByteWriter{w: w}
+}
+
+type oneByteWriter struct {
+	w io.Writer
+}
+
+// Write implements the io.Writer interface.
+func (ow oneByteWriter) Write(p []byte) (int, error) {
+	for i := range p {
+		if _, err := ow.w.Write(p[i : i+1]); err != nil {
+			return i, err
+		}
+	}
+	return len(p), nil
+}
+
+// ErrAfterWriter returns an io.Writer that writes at most n bytes to w and then returns err for all further writes.
+//
+// A write that would exceed the limit writes the bytes up to the limit and returns err.
+func ErrAfterWriter(w io.Writer, n int, err error) io.Writer {
+	return &errAfterWriter{w: w, n: n, err: err}
+}
+
+type errAfterWriter struct {
+	w   io.Writer
+	n   int
+	err error
+}
+
+// Write implements the io.Writer interface.
+func (ew *errAfterWriter) Write(p []byte) (int, error) {
+	if len(p) <= ew.n {
+		n, err := ew.w.Write(p)
+		ew.n -= n
+		return n, err
+	}
+	n, err := ew.w.Write(p[:ew.n])
+	ew.n -= n
+	if err != nil {
+		return n, err
+	}
+	return n, ew.err
+}
+
+// ShortWriter returns an io.Writer that writes at most max bytes to w for each write, returning io.ErrShortWrite for
+// writes that were cut short.
+func ShortWriter(w io.Writer, max int) io.Writer {
+	return shortWriter{w: w, max: max}
+}
+
+type shortWriter struct {
+	w   io.Writer
+	max int
+}
+
+// Write implements the io.Writer interface.
+func (sw shortWriter) Write(p []byte) (int, error) {
+	if len(p) <= sw.max {
+		return sw.w.Write(p)
+	}
+	n, err := sw.w.Write(p[:sw.max])
+	if err != nil {
+		return n, err
+	}
+	return n, io.ErrShortWrite
+}
@@ -0,0 +1,124 @@
+package resptest_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/nussjustin/resp"
+	"github.com/nussjustin/resp/resptest"
+)
+
+func TestTestReader(t *testing.T) {
+	for _, test := range []struct {
+		Name string
+		Wrap func(io.Reader) io.Reader
+	}{
+		{"Identity", func(r io.Reader) io.Reader { return r }},
+		{"OneByteReader", resptest.OneByteReader},
+		{"ChunkReader", func(r io.Reader) io.Reader { return resptest.ChunkReader(r, rand.New(rand.NewSource(1)), 7) }},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			resptest.TestReader(t, test.Wrap)
+		})
+	}
+}
+
+func TestChunkReader(t *testing.T) {
+	const in = "hello world, this is a longer string"
+
+	r := resptest.ChunkReader(strings.NewReader(in), rand.New(rand.NewSource(1)), 3)
+
+	var got []byte
+	buf := make([]byte, 16)
+	for {
+		n, err := r.Read(buf)
+		if n > 3 {
+			t.Fatalf("got chunk of %d bytes, expected at most 3", n)
+		}
+		got = append(got, buf[:n]...)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("read failed: %s", err)
+		}
+	}
+
+	if string(got) != in {
+		t.Errorf("got %q, expected %q", got, in)
+	}
+}
+
+func TestErrAfterReader(t *testing.T) {
+	errTest := errors.New("test")
+
+	got, err := ioutil.ReadAll(resptest.ErrAfterReader(strings.NewReader("hello world"), 5, errTest))
+	if err != errTest {
+		t.Errorf("got error %v, expected %v", err, errTest)
+	}
+	if string(got) != "hello" {
+		t.Errorf("got %q, expected %q", got, "hello")
+	}
+
+	got, err = ioutil.ReadAll(resptest.ErrAfterReader(strings.NewReader("hello"), 10, errTest))
+	if err != nil {
+		t.Errorf("got error %v, expected no error", err)
+	}
+	if string(got) != "hello" {
+		t.Errorf("got %q, expected %q", got, "hello")
+	}
+}
+
+func TestWriterWrappers(t *testing.T) {
+	errTest := errors.New("test")
+
+	for _, test := range []struct {
+		Name     string
+		Wrap     func(io.Writer) io.Writer
+		Expected string
+		Err      error
+	}{
+		{
+			Name:     "ChunkWriter",
+			Wrap:     func(w io.Writer) io.Writer { return resptest.ChunkWriter(w, rand.New(rand.NewSource(1)), 3) },
+			Expected: "$5\r\nhello\r\n",
+		},
+		{
+			Name:     "OneByteWriter",
+			Wrap:     resptest.OneByteWriter,
+			Expected: "$5\r\nhello\r\n",
+		},
+		{
+			Name:     "ErrAfterWriter",
+			Wrap:     func(w io.Writer) io.Writer { return resptest.ErrAfterWriter(w, 6, errTest) },
+			Expected: "$5\r\nhe",
+			Err:      errTest,
+		},
+		{
+			Name:     "ShortWriter",
+			Wrap:     func(w io.Writer) io.Writer { return resptest.ShortWriter(w, 4) },
+			Expected: "$5\r\n",
+			Err:      io.ErrShortWrite,
+		},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			var buf bytes.Buffer
+
+			n, err := resp.NewWriter(test.Wrap(&buf)).WriteBulkString("hello")
+			if err != test.Err {
+				t.Errorf("got error %v, expected %v", err, test.Err)
+			}
+			if n != len(test.Expected) {
+				t.Errorf("got n = %d, expected %d", n, len(test.Expected))
+			}
+			if got := buf.String(); got != test.Expected {
+				t.Errorf("got %q, expected %q", got, test.Expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package resptest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/nussjustin/resp"
+)
+
+// MockConn is a net.Conn that replies to commands with replies declared by a test.
+//
+// Commands must be received in the order the expectations were declared using Expect. Unexpected commands, commands
+// that do not match the next expectation and expectations that were not met when the test finishes are reported as
+// test errors. Unexpected commands are answered with an error reply.
+//
+// MockConn is usually passed to resp.NewConn or resp.NewMultiplexer.
+type MockConn struct {
+	net.Conn
+
+	tb     testing.TB
+	server net.Conn
+	done   chan struct{}
+
+	mu           sync.Mutex
+	expectations []*Expectation
+	next         int
+}
+
+// Expectation is a single expected command declared using MockConn.Expect.
+type Expectation struct {
+	m       *MockConn
+	args    [][]byte
+	replies []resp.Value
+	close   bool
+}
+
+// NewMockConn returns a new MockConn.
+//
+// The connection is closed when the test finishes, after which all expectations are checked.
+func NewMockConn(tb testing.TB) *MockConn {
+	client, server := net.Pipe()
+
+	m := &MockConn{Conn: client, tb: tb, server: server, done: make(chan struct{})}
+	go m.serve()
+
+	tb.Cleanup(func() {
+		_ = m.Conn.Close()
+		_ = m.server.Close()
+		<-m.done
+
+		if err := m.ExpectationsWereMet(); err != nil {
+			tb.Error(err)
+		}
+	})
+
+	return m
+}
+
+// Expect declares that the next command consists of the given arguments.
+//
+// Arguments are converted in the same way as by resp.Conn.Do. If an argument can not be converted, the error is
+// reported as test error and the returned Expectation is not used.
+func (m *MockConn) Expect(args ...interface{}) *Expectation {
+	m.tb.Helper()
+
+	e := &Expectation{m: m}
+
+	var cmd resp.Command
+	b, err := resp.AppendCommand(nil, args...)
+	if err == nil {
+		_, err = resp.ParseCommand(b, &cmd)
+	}
+	if err != nil {
+		m.tb.Errorf("resptest: invalid command: %s", err)
+		return e
+	}
+	e.args = cmd.Args
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.expectations = append(m.expectations, e)
+	return e
+}
+
+// Reply sets the values that are sent when the command is received.
+//
+// Multiple values can be given for commands that reply with multiple values, for example SUBSCRIBE. If no value is
+// given, nothing is sent.
+func (e *Expectation) Reply(vs ...resp.Value) *Expectation {
+	e.m.mu.Lock()
+	defer e.m.mu.Unlock()
+	e.replies = vs
+	return e
+}
+
+// Close causes the connection to be closed after sending the replies, if any.
+func (e *Expectation) Close() *Expectation {
+	e.m.mu.Lock()
+	defer e.m.mu.Unlock()
+	e.close = true
+	return e
+}
+
+// ExpectationsWereMet returns an error if any declared expectation was not met.
+func (m *MockConn) ExpectationsWereMet() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if n := len(m.expectations) - m.next; n > 0 {
+		return fmt.Errorf("resptest: %d expected command(s) not received, next: %s",
+			n, formatArgs(m.expectations[m.next].args))
+	}
+	return nil
+}
+
+func (m *MockConn) serve() {
+	defer close(m.done)
+
+	r, w := resp.NewReader(m.server), resp.NewWriter(m.server)
+
+	var cmd resp.Command
+	for {
+		if err := r.ReadCommand(&cmd); err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrClosedPipe) {
+				m.tb.Errorf("resptest: failed to read command: %s", err)
+			}
+			return
+		}
+
+		replies, closeConn, err := m.match(cmd.Args)
+		if err != nil {
+			m.tb.Error(err)
+			if _, err := w.WriteError("ERR " + err.Error()); err != nil {
+				return
+			}
+			continue
+		}
+
+		for _, v := range replies {
+			if _, err := w.WriteValue(v); err != nil {
+				return
+			}
+		}
+		if closeConn {
+			_ = m.server.Close()
+			return
+		}
+	}
+}
+
+// match returns the replies of the next expectation and whether to close the connection afterwards, if the
+// expectation matches the given arguments.
+func (m *MockConn) match(args [][]byte) ([]resp.Value, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.next >= len(m.expectations) {
+		return nil, false, fmt.Errorf("resptest: unexpected command %s", formatArgs(args))
+	}
+
+	e := m.expectations[m.next]
+	if len(e.args) != len(args) {
+		return nil, false, fmt.Errorf("resptest: got command %s, expected %s", formatArgs(args), formatArgs(e.args))
+	}
+	for i := range args {
+		if !bytes.Equal(e.args[i], args[i]) {
+			return nil, false, fmt.Errorf("resptest: got command %s, expected %s", formatArgs(args), formatArgs(e.args))
+		}
+	}
+
+	m.next++
+	return e.replies, e.close, nil
+}
+
+func formatArgs(args [][]byte) string {
+	ss := make([]string, len(args))
+	for i, arg := range args {
+		ss[i] = strconv.Quote(string(arg))
+	}
+	return "[" + strings.Join(ss, " ") + "]"
+}
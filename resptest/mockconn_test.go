@@ -0,0 +1,176 @@
+package resptest_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/nussjustin/resp"
+	"github.com/nussjustin/resp/resptest"
+)
+
+// recordingTB is a testing.TB that records errors instead of failing the test.
+type recordingTB struct {
+	testing.TB
+
+	mu       sync.Mutex
+	errors   []string
+	cleanups []func()
+}
+
+func (tb *recordingTB) Helper() {}
+
+func (tb *recordingTB) Cleanup(fn func()) {
+	tb.cleanups = append(tb.cleanups, fn)
+}
+
+func (tb *recordingTB) Error(args ...interface{}) {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	tb.errors = append(tb.errors, fmt.Sprint(args...))
+}
+
+func (tb *recordingTB) Errorf(format string, args ...interface{}) {
+	tb.Error(fmt.Sprintf(format, args...))
+}
+
+func (tb *recordingTB) finish() []string {
+	for i := len(tb.cleanups) - 1; i >= 0; i-- {
+		tb.cleanups[i]()
+	}
+	return tb.errors
+}
+
+func TestMockConn(t *testing.T) {
+	m := resptest.NewMockConn(t)
+	m.Expect("SET", "k", 1).Reply(resp.SimpleString("OK"))
+	m.Expect("GET", "k").Reply(resp.BulkString("1"))
+	m.Expect("GET", "missing").Reply(resp.NullBulkString())
+	m.Expect("SUBSCRIBE", "a", "b").Reply(
+		resp.Array(resp.BulkString("subscribe"), resp.BulkString("a"), resp.Integer(1)),
+		resp.Array(resp.BulkString("subscribe"), resp.BulkString("b"), resp.Integer(2)))
+
+	c := resp.NewConn(m)
+	defer c.Close()
+
+	ctx := context.Background()
+
+	for _, s := range []step{
+		{cmd("SET", "k", 1), resp.SimpleString("OK")},
+		{cmd("GET", "k"), resp.BulkString("1")},
+		{cmd("GET", "missing"), resp.NullBulkString()},
+	} {
+		got, err := c.Do(ctx, s.Args...)
+		if err != nil {
+			t.Fatalf("failed to run %q: %s", s.Args, err)
+		}
+		if !reflect.DeepEqual(got, s.Expected) {
+			t.Errorf("got %#v for %q, expected %#v", got, s.Args, s.Expected)
+		}
+	}
+
+	if err := c.Send(ctx, "SUBSCRIBE", "a", "b"); err != nil {
+		t.Fatalf("failed to send SUBSCRIBE: %s", err)
+	}
+	for i := 1; i <= 2; i++ {
+		got, err := c.Receive(ctx)
+		if err != nil {
+			t.Fatalf("failed to receive reply: %s", err)
+		}
+		if n := got.Elems[2].Int; n != i {
+			t.Errorf("got count %d, expected %d", n, i)
+		}
+	}
+
+	if err := m.ExpectationsWereMet(); err != nil {
+		t.Errorf("got error %q, expected no error", err)
+	}
+}
+
+func TestMockConnClose(t *testing.T) {
+	m := resptest.NewMockConn(t)
+	m.Expect("QUIT").Reply(resp.SimpleString("OK")).Close()
+
+	c := resp.NewConn(m)
+	defer c.Close()
+
+	ctx := context.Background()
+
+	if got, err := c.Do(ctx, "QUIT"); err != nil || !reflect.DeepEqual(got, resp.SimpleString("OK")) {
+		t.Fatalf("got (%#v, %v), expected (%#v, nil)", got, err, resp.SimpleString("OK"))
+	}
+	if _, err := c.Do(ctx, "PING"); !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrClosedPipe) {
+		t.Errorf("got error %v, expected EOF", err)
+	}
+}
+
+func TestMockConnErrors(t *testing.T) {
+	for _, test := range []struct {
+		Name     string
+		Expect   [][]interface{}
+		Send     [][]interface{}
+		Expected []string
+	}{
+		{
+			Name:   "unexpected command",
+			Expect: [][]interface{}{cmd("GET", "a")},
+			Send:   [][]interface{}{cmd("GET", "a"), cmd("GET", "b")},
+			Expected: []string{
+				`resptest: unexpected command ["GET" "b"]`,
+			},
+		},
+		{
+			Name:   "mismatch",
+			Expect: [][]interface{}{cmd("GET", "a"), cmd("GET", "b")},
+			Send:   [][]interface{}{cmd("GET", "b"), cmd("GET", "a"), cmd("GET", "b")},
+			Expected: []string{
+				`resptest: got command ["GET" "b"], expected ["GET" "a"]`,
+			},
+		},
+		{
+			Name:   "leftover",
+			Expect: [][]interface{}{cmd("GET", "a"), cmd("GET", "b"), cmd("GET", "c")},
+			Send:   [][]interface{}{cmd("GET", "a")},
+			Expected: []string{
+				`resptest: 2 expected command(s) not received, next: ["GET" "b"]`,
+			},
+		},
+		{
+			Name:   "nil argument",
+			Expect: [][]interface{}{cmd("GET", nil)},
+			Expected: []string{
+				`resptest: invalid command: unsupported value: nil`,
+			},
+		},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			tb := &recordingTB{}
+
+			m := resptest.NewMockConn(tb)
+			for _, args := range test.Expect {
+				m.Expect(args...).Reply(resp.SimpleString("OK"))
+			}
+
+			c := resp.NewConn(m)
+			for _, args := range test.Send {
+				got, err := c.Do(context.Background(), args...)
+				if err != nil && got.Err() == nil {
+					t.Fatalf("failed to run %q: %s", args, err)
+				}
+				if got.Type == resp.TypeError && !bytes.HasPrefix(got.Str, []byte("ERR resptest: ")) {
+					t.Errorf("got error reply %q, expected resptest error", got.Str)
+				}
+			}
+			_ = c.Close()
+
+			if got := tb.finish(); !reflect.DeepEqual(got, test.Expected) {
+				t.Errorf("got errors %q, expected %q", got, test.Expected)
+			}
+		})
+	}
+}
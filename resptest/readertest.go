@@ -0,0 +1,251 @@
+package resptest
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/nussjustin/resp"
+)
+
+// errInjected is the error injected by TestReader using ErrAfterReader.
+var errInjected = errors.New("resptest: injected error")
+
+// readerTest is a single input for TestReader together with the method used for reading it.
+type readerTest struct {
+	Name     string
+	In       string
+	Read     func(r *resp.Reader) (interface{}, error)
+	Expected interface{}
+	Err      error
+}
+
+func readBytes(fn func(r *resp.Reader, dst []byte) ([]byte, error)) func(r *resp.Reader) (interface{}, error) {
+	return func(r *resp.Reader) (interface{}, error) {
+		b, err := fn(r, nil)
+		return b, err
+	}
+}
+
+func readInt(fn func(r *resp.Reader) (int, error)) func(r *resp.Reader) (interface{}, error) {
+	return func(r *resp.Reader) (interface{}, error) {
+		n, err := fn(r)
+		return n, err
+	}
+}
+
+func readBoolean(r *resp.Reader) (interface{}, error) {
+	b, err := r.ReadBoolean()
+	return b, err
+}
+
+func readDouble(r *resp.Reader) (interface{}, error) {
+	f, err := r.ReadDouble()
+	return f, err
+}
+
+func readNull(r *resp.Reader) (interface{}, error) {
+	return nil, r.ReadNull()
+}
+
+func readValue(r *resp.Reader) (interface{}, error) {
+	v, err := r.ReadValue()
+	return v, err
+}
+
+func readCommand(r *resp.Reader) (interface{}, error) {
+	var cmd resp.Command
+	err := r.ReadCommand(&cmd)
+	return cmd.Args, err
+}
+
+var (
+	readArrayHeader      = readInt((*resp.Reader).ReadArrayHeader)
+	readAttributeHeader  = readInt((*resp.Reader).ReadAttributeHeader)
+	readBulkStringHeader = readInt((*resp.Reader).ReadBulkStringHeader)
+	readInteger          = readInt((*resp.Reader).ReadInteger)
+	readMapHeader        = readInt((*resp.Reader).ReadMapHeader)
+	readPushHeader       = readInt((*resp.Reader).ReadPushHeader)
+	readSetHeader        = readInt((*resp.Reader).ReadSetHeader)
+	readBigNumber        = readBytes((*resp.Reader).ReadBigNumber)
+	readBlobError        = readBytes((*resp.Reader).ReadBlobError)
+	readBulkString       = readBytes((*resp.Reader).ReadBulkString)
+	readError            = readBytes((*resp.Reader).ReadError)
+	readSimpleString     = readBytes((*resp.Reader).ReadSimpleString)
+	readVerbatimString   = readBytes((*resp.Reader).ReadVerbatimString)
+)
+
+var readerTests = []readerTest{
+	{Name: "ArrayHeader", In: "*3\r\n", Read: readArrayHeader, Expected: 3},
+	{Name: "ArrayHeader/null", In: "*-1\r\n", Read: readArrayHeader, Expected: -1},
+	{Name: "ArrayHeader/large", In: "*1234567890\r\n", Read: readArrayHeader, Expected: 1234567890},
+	{Name: "ArrayHeader/invalid", In: "*a\r\n", Read: readArrayHeader, Err: resp.ErrInvalidArrayLength},
+	{Name: "ArrayHeader/no \\n", In: "*5\r", Read: readArrayHeader, Err: resp.ErrUnexpectedEOL},
+	{Name: "ArrayHeader/wrong \\n", In: "*5\ra", Read: readArrayHeader, Err: resp.ErrUnexpectedEOL},
+	{Name: "ArrayHeader/no \\r", In: "*5\n", Read: readArrayHeader, Err: resp.ErrUnexpectedEOL},
+	{Name: "AttributeHeader", In: "|2\r\n", Read: readAttributeHeader, Expected: 2},
+	{Name: "MapHeader", In: "%2\r\n", Read: readMapHeader, Expected: 2},
+	{Name: "MapHeader/negative", In: "%-1\r\n", Read: readMapHeader, Err: resp.ErrInvalidMapLength},
+	{Name: "PushHeader", In: ">3\r\n", Read: readPushHeader, Expected: 3},
+	{Name: "SetHeader", In: "~0\r\n", Read: readSetHeader, Expected: 0},
+	{Name: "BulkStringHeader", In: "$5\r\n", Read: readBulkStringHeader, Expected: 5},
+	{Name: "Integer", In: ":-12345\r\n", Read: readInteger, Expected: -12345},
+	{Name: "Integer/invalid", In: ":1a\r\n", Read: readInteger, Err: resp.ErrInvalidInteger},
+	{Name: "BulkString", In: "$5\r\nhello\r\n", Read: readBulkString, Expected: []byte("hello")},
+	{Name: "BulkString/empty", In: "$0\r\n\r\n", Read: readBulkString, Expected: []byte{}},
+	{Name: "BulkString/null", In: "$-1\r\n", Read: readBulkString, Expected: []byte(nil)},
+	{
+		Name:     "BulkString/long",
+		In:       "$40\r\n" + strings.Repeat("0123456789", 4) + "\r\n",
+		Read:     readBulkString,
+		Expected: []byte(strings.Repeat("0123456789", 4)),
+	},
+	{Name: "BulkString/CRLF", In: "$4\r\n\r\n\r\n\r\n", Read: readBulkString, Expected: []byte("\r\n\r\n")},
+	{Name: "BulkString/missing EOL", In: "$2\r\nabc\r\n", Read: readBulkString, Err: resp.ErrUnexpectedEOL},
+	{Name: "BulkString/short", In: "$5\r\nab", Read: readBulkString, Err: resp.ErrUnexpectedEOL},
+	{Name: "BlobError", In: "!9\r\nERR error\r\n", Read: readBlobError, Expected: []byte("ERR error")},
+	{Name: "VerbatimString", In: "=9\r\ntxt:hello\r\n", Read: readVerbatimString, Expected: []byte("txt:hello")},
+	{
+		Name: "VerbatimString/short",
+		In:   "=3\r\ntxt\r\n",
+		Read: readVerbatimString,
+		Err:  resp.ErrInvalidVerbatimStringLength,
+	},
+	{Name: "SimpleString", In: "+OK\r\n", Read: readSimpleString, Expected: []byte("OK")},
+	{
+		Name:     "SimpleString/long",
+		In:       "+" + strings.Repeat("abcdefgh", 8) + "\r\n",
+		Read:     readSimpleString,
+		Expected: []byte(strings.Repeat("abcdefgh", 8)),
+	},
+	{Name: "SimpleString/EOF", In: "+OK", Read: readSimpleString, Err: resp.ErrUnexpectedEOL},
+	{Name: "Error", In: "-ERR failed\r\n", Read: readError, Expected: []byte("ERR failed")},
+	{Name: "BigNumber", In: "(12345678901234567890\r\n", Read: readBigNumber, Expected: []byte("12345678901234567890")},
+	{Name: "Boolean", In: "#t\r\n", Read: readBoolean, Expected: true},
+	{Name: "Boolean/invalid", In: "#x\r\n", Read: readBoolean, Err: resp.ErrInvalidBoolean},
+	{Name: "Double", In: ",1.5\r\n", Read: readDouble, Expected: 1.5},
+	{Name: "Double/invalid", In: ",1.5.\r\n", Read: readDouble, Err: resp.ErrInvalidDouble},
+	{Name: "Null", In: "_\r\n", Read: readNull},
+	{Name: "Null/invalid", In: "_a\r\n", Read: readNull, Err: resp.ErrUnexpectedEOL},
+	{
+		Name: "Value",
+		In:   "*3\r\n$3\r\nkey\r\n%1\r\n+a\r\n:1\r\n~2\r\n#f\r\n_\r\n",
+		Read: readValue,
+		Expected: resp.Array(
+			resp.BulkString("key"),
+			resp.Map(resp.SimpleString("a"), resp.Integer(1)),
+			resp.Set(resp.Boolean(false), resp.Null()),
+		),
+	},
+	{
+		Name:     "Value/attribute",
+		In:       "|1\r\n+ttl\r\n:100\r\n",
+		Read:     readValue,
+		Expected: resp.Value{Type: resp.TypeAttribute, Elems: []resp.Value{resp.SimpleString("ttl"), resp.Integer(100)}},
+	},
+	{
+		Name:     "Command",
+		In:       "*3\r\n$3\r\nSET\r\n$1\r\nk\r\n$5\r\nvalue\r\n",
+		Read:     readCommand,
+		Expected: [][]byte{[]byte("SET"), []byte("k"), []byte("value")},
+	},
+	{Name: "Command/invalid", In: "*1\r\n:1\r\n", Read: readCommand, Expected: [][]byte(nil), Err: resp.ErrInvalidCommand},
+}
+
+// TestReader runs the methods of resp.Reader against inputs read through the io.Reader returned by wrap and reports
+// any difference to the expected results.
+//
+// TestReader can be used to verify that a custom io.Reader, for example one that decrypts or decompresses data, works
+// with resp.Reader. It can also be used together with the other io.Reader wrappers in this package to exercise edge
+// cases in the Reader itself, like values split over multiple reads:
+//
+//	resptest.TestReader(t, resptest.OneByteReader)
+//
+// Each input is read using both the default buffer size and the minimum buffer size of a bufio.Reader. In addition
+// to reading the complete input, errors are injected after each byte of the input using ErrAfterReader, checking
+// that the injected error is returned and that the Reader breaks when the error occurs in the middle of a value.
+func TestReader(t *testing.T, wrap func(io.Reader) io.Reader) {
+	t.Helper()
+
+	for _, size := range []int{0, 16} {
+		newReader := func(r io.Reader) *resp.Reader {
+			r = wrap(r)
+			if size > 0 {
+				r = bufio.NewReaderSize(r, size)
+			}
+			return resp.NewReader(r)
+		}
+
+		for _, test := range readerTests {
+			test := test
+
+			t.Run(test.Name, func(t *testing.T) {
+				r := newReader(strings.NewReader(test.In))
+
+				got, err := test.Read(r)
+				if !errors.Is(err, test.Err) {
+					t.Fatalf("buffer size %d: got error %v, expected %v", size, err, test.Err)
+				}
+				if err == nil && !reflect.DeepEqual(got, test.Expected) {
+					t.Fatalf("buffer size %d: got %#v, expected %#v", size, got, test.Expected)
+				}
+				if test.Err != nil {
+					return
+				}
+
+				for i := 0; i < len(test.In); i++ {
+					r := newReader(ErrAfterReader(strings.NewReader(test.In), i, errInjected))
+
+					if _, err := test.Read(r); !errors.Is(err, errInjected) {
+						t.Fatalf("buffer size %d: got error %v with error after %d bytes, expected %v",
+							size, err, i, errInjected)
+					}
+
+					switch {
+					case i == 0 && r.Err() != nil:
+						t.Fatalf("buffer size %d: got broken Reader with error before first byte", size)
+					case i > 0 && r.Err() == nil:
+						t.Fatalf("buffer size %d: got usable Reader with error after %d bytes", size, i)
+					case i > 0:
+						if _, err := test.Read(r); !errors.Is(err, resp.ErrReaderBroken) {
+							t.Fatalf("buffer size %d: got error %v from broken Reader, expected %v",
+								size, err, resp.ErrReaderBroken)
+						}
+					}
+				}
+			})
+		}
+
+		t.Run("Stream", func(t *testing.T) {
+			var sb strings.Builder
+			for _, test := range readerTests {
+				if test.Err == nil {
+					sb.WriteString(test.In)
+				}
+			}
+
+			r := newReader(strings.NewReader(sb.String()))
+
+			for _, test := range readerTests {
+				if test.Err != nil {
+					continue
+				}
+
+				got, err := test.Read(r)
+				if err != nil {
+					t.Fatalf("buffer size %d: %s: got error %v", size, test.Name, err)
+				}
+				if !reflect.DeepEqual(got, test.Expected) {
+					t.Fatalf("buffer size %d: %s: got %#v, expected %#v", size, test.Name, got, test.Expected)
+				}
+			}
+
+			if _, err := r.Peek(); err != io.EOF {
+				t.Fatalf("buffer size %d: got error %v at end of stream, expected %v", size, err, io.EOF)
+			}
+		})
+	}
+}
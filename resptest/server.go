@@ -0,0 +1,306 @@
+// Package resptest implements an in-memory fake Redis server for use in tests.
+package resptest
+
+import (
+	"context"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/nussjustin/resp"
+)
+
+// Server is an in-memory fake Redis server implementing a subset of the Redis commands.
+//
+// The Server supports commands for strings, hashes, lists, sets and sorted sets, key expiry and transactions using
+// MULTI and EXEC. Clients can switch to RESP3 using HELLO. Only database 0 is supported.
+//
+// Error replies use the same messages as Redis, so that tests can check for specific errors like WRONGTYPE.
+type Server struct {
+	// Addr is the loopback address the Server listens on, in the form host:port.
+	Addr string
+
+	l     net.Listener
+	pipes *pipeListener
+	srv   resp.Server
+	mux   *resp.ServeMux
+	wg    sync.WaitGroup
+
+	// mu guards db and is held while executing a command, making each command (and each transaction) atomic.
+	mu sync.Mutex
+	db *db
+}
+
+// NewServer starts and returns a new Server listening on a loopback address.
+//
+// NewServer panics if listening fails. The caller should call Close when finished, to shut it down.
+func NewServer() *Server {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		if l, err = net.Listen("tcp6", "[::1]:0"); err != nil {
+			panic("resptest: failed to listen on a port: " + err.Error())
+		}
+	}
+
+	s := &Server{
+		Addr:  l.Addr().String(),
+		l:     l,
+		pipes: newPipeListener(l.Addr()),
+		mux:   resp.NewServeMux(),
+		db:    newDB(),
+	}
+	s.srv.Handler = resp.HandlerFunc(s.serveRESP)
+
+	for _, c := range commands {
+		c := c
+		s.mux.HandleFunc(c.info, func(w resp.ReplyWriter, cmd *resp.Command) {
+			c.fn(s, w, cmd)
+		})
+	}
+
+	s.wg.Add(2)
+	go s.serve(l)
+	go s.serve(s.pipes)
+
+	return s
+}
+
+func (s *Server) serve(l net.Listener) {
+	defer s.wg.Done()
+	_ = s.srv.Serve(l)
+}
+
+// Close shuts down the Server, closing all connections.
+func (s *Server) Close() {
+	_ = s.srv.Close()
+	s.wg.Wait()
+}
+
+// Dial returns a new resp.Conn connected to the Server via the loopback network.
+func (s *Server) Dial(ctx context.Context) (*resp.Conn, error) {
+	return resp.Dial(ctx, "tcp", s.Addr)
+}
+
+// Pipe returns a new in-memory connection to the Server, created using net.Pipe.
+//
+// Contrary to net.Pipe, data written by the Server is buffered by the returned connection until it is read, so that
+// clients can write pipelines of any size before reading the replies.
+func (s *Server) Pipe() net.Conn {
+	client, server := net.Pipe()
+	if !s.pipes.push(server) {
+		_ = server.Close()
+	}
+	return newReadAheadConn(client)
+}
+
+// FastForward moves the clock of the Server forward by d, expiring all keys whose time to live is less or equal.
+func (s *Server) FastForward(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.db.offset += d
+}
+
+// FlushAll removes all keys.
+func (s *Server) FlushAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.db.keys = make(map[string]*entry)
+}
+
+// connState is the per-connection state of a client.
+type connState struct {
+	multi bool
+	dirty bool
+	queue [][][]byte
+}
+
+type connStateKey struct{}
+
+func getConnState(cmd *resp.Command) *connState {
+	c := cmd.Conn()
+	if c == nil {
+		return &connState{}
+	}
+	st, _ := c.Value(connStateKey{}).(*connState)
+	if st == nil {
+		st = &connState{}
+		c.SetValue(connStateKey{}, st)
+	}
+	return st
+}
+
+func (s *Server) serveRESP(w resp.ReplyWriter, cmd *resp.Command) {
+	if st := getConnState(cmd); st.multi && !isTransactionCommand(cmd) {
+		if err := s.mux.Check(cmd); err != nil {
+			st.dirty = true
+			_, _ = w.WriteError(err.Error())
+			return
+		}
+
+		args := make([][]byte, len(cmd.Args))
+		for i, arg := range cmd.Args {
+			args[i] = append([]byte(nil), arg...)
+		}
+		st.queue = append(st.queue, args)
+
+		_, _ = w.WriteSimpleString("QUEUED")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mux.ServeRESP(w, cmd)
+}
+
+func isTransactionCommand(cmd *resp.Command) bool {
+	return cmd.Is("MULTI") || cmd.Is("EXEC") || cmd.Is("DISCARD") || cmd.Is("QUIT")
+}
+
+// pipeListener is a net.Listener that returns connections created by Server.Pipe.
+type pipeListener struct {
+	addr net.Addr
+
+	mu     sync.Mutex
+	closed bool
+	conns  chan net.Conn
+	done   chan struct{}
+}
+
+func newPipeListener(addr net.Addr) *pipeListener {
+	return &pipeListener{addr: addr, conns: make(chan net.Conn), done: make(chan struct{})}
+}
+
+func (pl *pipeListener) push(conn net.Conn) bool {
+	select {
+	case pl.conns <- conn:
+		return true
+	case <-pl.done:
+		return false
+	}
+}
+
+// Accept implements the net.Listener interface.
+func (pl *pipeListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-pl.conns:
+		return conn, nil
+	case <-pl.done:
+		return nil, net.ErrClosed
+	}
+}
+
+// Close implements the net.Listener interface.
+func (pl *pipeListener) Close() error {
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	if !pl.closed {
+		pl.closed = true
+		close(pl.done)
+	}
+	return nil
+}
+
+// Addr implements the net.Listener interface.
+func (pl *pipeListener) Addr() net.Addr {
+	return pl.addr
+}
+
+// readAheadConn is a net.Conn that reads all data from the underlying connection into a buffer of unlimited size as
+// soon as it arrives. This way writes on the other end of a net.Pipe never block, even if nothing is read.
+type readAheadConn struct {
+	net.Conn
+
+	mu sync.Mutex
+	// buf contains data that was received but not yet read.
+	buf []byte
+	// err is the error that stopped reading from the underlying connection.
+	err error
+	// deadline is the read deadline.
+	deadline time.Time
+	// changed is closed and replaced when new data was received or the deadline was changed.
+	changed chan struct{}
+}
+
+func newReadAheadConn(conn net.Conn) *readAheadConn {
+	c := &readAheadConn{Conn: conn, changed: make(chan struct{})}
+	go c.readLoop()
+	return c
+}
+
+func (c *readAheadConn) readLoop() {
+	b := make([]byte, 4096)
+	for {
+		n, err := c.Conn.Read(b)
+
+		c.mu.Lock()
+		c.buf = append(c.buf, b[:n]...)
+		if err != nil {
+			c.err = err
+		}
+		c.notify()
+		c.mu.Unlock()
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+// notify wakes up pending reads. c.mu must be held.
+func (c *readAheadConn) notify() {
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+// Read implements the net.Conn interface.
+func (c *readAheadConn) Read(b []byte) (int, error) {
+	for {
+		c.mu.Lock()
+		switch {
+		case len(c.buf) > 0:
+			n := copy(b, c.buf)
+			c.buf = c.buf[n:]
+			c.mu.Unlock()
+			return n, nil
+		case c.err != nil:
+			err := c.err
+			c.mu.Unlock()
+			return 0, err
+		case !c.deadline.IsZero() && !time.Now().Before(c.deadline):
+			c.mu.Unlock()
+			return 0, os.ErrDeadlineExceeded
+		}
+		changed, deadline := c.changed, c.deadline
+		c.mu.Unlock()
+
+		if deadline.IsZero() {
+			<-changed
+			continue
+		}
+
+		t := time.NewTimer(time.Until(deadline))
+		select {
+		case <-changed:
+		case <-t.C:
+		}
+		t.Stop()
+	}
+}
+
+// SetDeadline implements the net.Conn interface.
+func (c *readAheadConn) SetDeadline(t time.Time) error {
+	if err := c.Conn.SetWriteDeadline(t); err != nil {
+		return err
+	}
+	return c.SetReadDeadline(t)
+}
+
+// SetReadDeadline implements the net.Conn interface.
+func (c *readAheadConn) SetReadDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.deadline = t
+	c.notify()
+	return nil
+}
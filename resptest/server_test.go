@@ -0,0 +1,329 @@
+package resptest_test
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nussjustin/resp"
+	"github.com/nussjustin/resp/resptest"
+)
+
+func newTestConn(tb testing.TB, s *resptest.Server) *resp.Conn {
+	c := resp.NewConn(s.Pipe())
+	tb.Cleanup(func() { _ = c.Close() })
+	return c
+}
+
+type step struct {
+	Args     []interface{}
+	Expected resp.Value
+}
+
+func cmd(args ...interface{}) []interface{} {
+	return args
+}
+
+func runSteps(t *testing.T, c *resp.Conn, steps []step) {
+	t.Helper()
+
+	for _, s := range steps {
+		got, err := c.Do(context.Background(), s.Args...)
+		if err != nil && got.Err() == nil {
+			t.Fatalf("failed to run %q: %s", s.Args, err)
+		}
+		if !reflect.DeepEqual(got, s.Expected) {
+			t.Errorf("got %#v for %q, expected %#v", got, s.Args, s.Expected)
+		}
+	}
+}
+
+func bulkStrings(ss ...string) resp.Value {
+	vs := make([]resp.Value, len(ss))
+	for i, s := range ss {
+		vs[i] = resp.BulkString(s)
+	}
+	return resp.Array(vs...)
+}
+
+var wrongType = resp.Error("WRONGTYPE Operation against a key holding the wrong kind of value")
+
+func TestServer(t *testing.T) {
+	for _, test := range []struct {
+		Name  string
+		Steps []step
+	}{
+		{
+			Name: "connection",
+			Steps: []step{
+				{cmd("PING"), resp.SimpleString("PONG")},
+				{cmd("ping", "hello"), resp.BulkString("hello")},
+				{cmd("ECHO", "hello"), resp.BulkString("hello")},
+				{cmd("SELECT", 0), resp.SimpleString("OK")},
+				{cmd("SELECT", 1), resp.Error("ERR DB index is out of range")},
+				{cmd("FOO", "bar"), resp.Error("ERR unknown command 'FOO', with args beginning with: 'bar' ")},
+				{cmd("GET"), resp.Error("ERR wrong number of arguments for 'get' command")},
+			},
+		},
+		{
+			Name: "strings",
+			Steps: []step{
+				{cmd("GET", "s"), resp.NullBulkString()},
+				{cmd("SET", "s", "value1"), resp.SimpleString("OK")},
+				{cmd("SET", "s", "value2", "NX"), resp.NullBulkString()},
+				{cmd("SET", "s", "value3", "XX", "GET"), resp.BulkString("value1")},
+				{cmd("SET", "s", "value4", "NX", "XX"), resp.Error("ERR syntax error")},
+				{cmd("GET", "s"), resp.BulkString("value3")},
+				{cmd("APPEND", "s", "!"), resp.Integer(7)},
+				{cmd("STRLEN", "s"), resp.Integer(7)},
+				{cmd("MSET", "a", "1", "b", "2"), resp.SimpleString("OK")},
+				{cmd("MSET", "a", "1", "b"), resp.Error("ERR wrong number of arguments for 'mset' command")},
+				{cmd("MGET", "a", "b", "c"), resp.Array(resp.BulkString("1"), resp.BulkString("2"), resp.NullBulkString())},
+				{cmd("INCR", "a"), resp.Integer(2)},
+				{cmd("INCRBY", "a", 10), resp.Integer(12)},
+				{cmd("DECRBY", "a", 20), resp.Integer(-8)},
+				{cmd("INCR", "s"), resp.Error("ERR value is not an integer or out of range")},
+				{cmd("SET", "max", "9223372036854775807"), resp.SimpleString("OK")},
+				{cmd("INCR", "max"), resp.Error("ERR increment or decrement would overflow")},
+				{cmd("INCRBYFLOAT", "f", "1.5"), resp.BulkString("1.5")},
+				{cmd("GETDEL", "f"), resp.BulkString("1.5")},
+				{cmd("SETNX", "f", "x"), resp.Integer(1)},
+				{cmd("SETNX", "f", "y"), resp.Integer(0)},
+			},
+		},
+		{
+			Name: "hashes",
+			Steps: []step{
+				{cmd("HSET", "h", "a", "1", "b", "2"), resp.Integer(2)},
+				{cmd("HSET", "h", "a", "3"), resp.Integer(0)},
+				{cmd("HGET", "h", "a"), resp.BulkString("3")},
+				{cmd("HGET", "h", "c"), resp.NullBulkString()},
+				{cmd("HMGET", "h", "b", "c"), resp.Array(resp.BulkString("2"), resp.NullBulkString())},
+				{cmd("HGETALL", "h"), bulkStrings("a", "3", "b", "2")},
+				{cmd("HKEYS", "h"), bulkStrings("a", "b")},
+				{cmd("HVALS", "h"), bulkStrings("3", "2")},
+				{cmd("HINCRBY", "h", "b", 5), resp.Integer(7)},
+				{cmd("HEXISTS", "h", "b"), resp.Integer(1)},
+				{cmd("HDEL", "h", "a", "b", "c"), resp.Integer(2)},
+				{cmd("HLEN", "h"), resp.Integer(0)},
+				{cmd("EXISTS", "h"), resp.Integer(0)},
+			},
+		},
+		{
+			Name: "lists",
+			Steps: []step{
+				{cmd("RPUSH", "l", "b", "c"), resp.Integer(2)},
+				{cmd("LPUSH", "l", "a", "z"), resp.Integer(4)},
+				{cmd("LRANGE", "l", 0, -1), bulkStrings("z", "a", "b", "c")},
+				{cmd("LRANGE", "l", 1, 2), bulkStrings("a", "b")},
+				{cmd("LINDEX", "l", -1), resp.BulkString("c")},
+				{cmd("LPOP", "l"), resp.BulkString("z")},
+				{cmd("RPOP", "l", 2), bulkStrings("c", "b")},
+				{cmd("LLEN", "l"), resp.Integer(1)},
+				{cmd("RPOP", "l"), resp.BulkString("a")},
+				{cmd("LPOP", "l"), resp.NullBulkString()},
+				{cmd("LPOP", "l", 1), resp.NullArray()},
+			},
+		},
+		{
+			Name: "sets",
+			Steps: []step{
+				{cmd("SMEMBERS", "set"), resp.Array()},
+				{cmd("SADD", "set", "value3"), resp.Integer(1)},
+				{cmd("SADD", "set", "value3"), resp.Integer(0)},
+				{cmd("SADD", "set", "value1", "value2"), resp.Integer(2)},
+				{cmd("SMEMBERS", "set"), bulkStrings("value1", "value2", "value3")},
+				{cmd("SISMEMBER", "set", "value2"), resp.Integer(1)},
+				{cmd("SREM", "set", "value2", "value4"), resp.Integer(1)},
+				{cmd("SCARD", "set"), resp.Integer(2)},
+				{cmd("ZADD", "set", 100, "value4"), wrongType},
+				{cmd("GET", "set"), wrongType},
+				{cmd("TYPE", "set"), resp.SimpleString("set")},
+			},
+		},
+		{
+			Name: "sorted sets",
+			Steps: []step{
+				{cmd("ZADD", "z", 2, "b", 1, "a", 3, "c"), resp.Integer(3)},
+				{cmd("ZADD", "z", "CH", 5, "a"), resp.Integer(1)},
+				{cmd("ZADD", "z", "NX", 6, "a"), resp.Integer(0)},
+				{cmd("ZADD", "z", "x", "a"), resp.Error("ERR value is not a valid float")},
+				{cmd("ZRANGE", "z", 0, -1), bulkStrings("b", "c", "a")},
+				{cmd("ZRANGE", "z", 0, 0, "WITHSCORES"), bulkStrings("b", "2")},
+				{cmd("ZSCORE", "z", "a"), resp.BulkString("5")},
+				{cmd("ZINCRBY", "z", 0.5, "a"), resp.BulkString("5.5")},
+				{cmd("ZRANK", "z", "c"), resp.Integer(1)},
+				{cmd("ZRANK", "z", "d"), resp.NullBulkString()},
+				{cmd("ZREM", "z", "a", "d"), resp.Integer(1)},
+				{cmd("ZCARD", "z"), resp.Integer(2)},
+			},
+		},
+		{
+			Name: "keys",
+			Steps: []step{
+				{cmd("MSET", "key:1", "a", "key:2", "b", "other", "c"), resp.SimpleString("OK")},
+				{cmd("KEYS", "key:*"), bulkStrings("key:1", "key:2")},
+				{cmd("KEYS", "key:[^1]"), bulkStrings("key:2")},
+				{cmd("DEL", "key:1", "key:3"), resp.Integer(1)},
+				{cmd("EXISTS", "key:1", "key:2", "key:2"), resp.Integer(2)},
+				{cmd("TYPE", "key:1"), resp.SimpleString("none")},
+				{cmd("DBSIZE"), resp.Integer(2)},
+				{cmd("FLUSHALL"), resp.SimpleString("OK")},
+				{cmd("DBSIZE"), resp.Integer(0)},
+			},
+		},
+		{
+			Name: "transactions",
+			Steps: []step{
+				{cmd("MULTI"), resp.SimpleString("OK")},
+				{cmd("MULTI"), resp.Error("ERR MULTI calls can not be nested")},
+				{cmd("SET", "tx", "1"), resp.SimpleString("QUEUED")},
+				{cmd("INCR", "tx"), resp.SimpleString("QUEUED")},
+				{cmd("HSET", "tx", "a", "b"), resp.SimpleString("QUEUED")},
+				{cmd("EXEC"), resp.Array(resp.SimpleString("OK"), resp.Integer(2), wrongType)},
+				{cmd("EXEC"), resp.Error("ERR EXEC without MULTI")},
+				{cmd("MULTI"), resp.SimpleString("OK")},
+				{cmd("SET", "tx"), resp.Error("ERR wrong number of arguments for 'set' command")},
+				{cmd("SET", "tx", "2"), resp.SimpleString("QUEUED")},
+				{cmd("EXEC"), resp.Error("EXECABORT Transaction discarded because of previous errors.")},
+				{cmd("MULTI"), resp.SimpleString("OK")},
+				{cmd("SET", "tx", "3"), resp.SimpleString("QUEUED")},
+				{cmd("DISCARD"), resp.SimpleString("OK")},
+				{cmd("GET", "tx"), resp.BulkString("2")},
+			},
+		},
+	} {
+		test := test
+
+		t.Run(test.Name, func(t *testing.T) {
+			s := resptest.NewServer()
+			defer s.Close()
+
+			runSteps(t, newTestConn(t, s), test.Steps)
+		})
+	}
+}
+
+func TestServerExpire(t *testing.T) {
+	s := resptest.NewServer()
+	defer s.Close()
+
+	c := newTestConn(t, s)
+
+	runSteps(t, c, []step{
+		{cmd("SET", "a", "1", "EX", 10), resp.SimpleString("OK")},
+		{cmd("SET", "b", "1", "PX", 0), resp.Error("ERR invalid expire time in 'set' command")},
+		{cmd("SET", "b", "1"), resp.SimpleString("OK")},
+		{cmd("TTL", "a"), resp.Integer(10)},
+		{cmd("TTL", "b"), resp.Integer(-1)},
+		{cmd("TTL", "c"), resp.Integer(-2)},
+		{cmd("PEXPIRE", "b", 1500), resp.Integer(1)},
+		{cmd("EXPIRE", "c", 10), resp.Integer(0)},
+	})
+
+	s.FastForward(time.Second)
+
+	if v, err := c.Do(context.Background(), "PTTL", "b"); err != nil {
+		t.Fatalf("failed to run PTTL: %s", err)
+	} else if v.Int <= 0 || v.Int > 500 {
+		t.Errorf("got ttl %d, expected value in (0, 500]", v.Int)
+	}
+
+	runSteps(t, c, []step{
+		{cmd("PERSIST", "b"), resp.Integer(1)},
+		{cmd("TTL", "b"), resp.Integer(-1)},
+	})
+
+	s.FastForward(9 * time.Second)
+
+	runSteps(t, c, []step{
+		{cmd("GET", "a"), resp.NullBulkString()},
+		{cmd("GET", "b"), resp.BulkString("1")},
+	})
+}
+
+func TestServerRESP3(t *testing.T) {
+	s := resptest.NewServer()
+	defer s.Close()
+
+	c := newTestConn(t, s)
+
+	runSteps(t, c, []step{
+		{cmd("HELLO", 4), resp.Error("NOPROTO unsupported protocol version")},
+		{cmd("HELLO", 3), resp.Map(
+			resp.BulkString("server"), resp.BulkString("redis"),
+			resp.BulkString("version"), resp.BulkString("7.0.0"),
+			resp.BulkString("proto"), resp.Integer(3),
+			resp.BulkString("id"), resp.Integer(1),
+			resp.BulkString("mode"), resp.BulkString("standalone"),
+			resp.BulkString("role"), resp.BulkString("master"),
+			resp.BulkString("modules"), resp.Array(),
+		)},
+		{cmd("GET", "a"), resp.Null()},
+		{cmd("HSET", "h", "a", "1"), resp.Integer(1)},
+		{cmd("HGETALL", "h"), resp.Map(resp.BulkString("a"), resp.BulkString("1"))},
+		{cmd("SADD", "s", "a"), resp.Integer(1)},
+		{cmd("SMEMBERS", "s"), resp.Set(resp.BulkString("a"))},
+		{cmd("ZADD", "z", 1.5, "a"), resp.Integer(1)},
+		{cmd("ZRANGE", "z", 0, -1, "WITHSCORES"), resp.Array(resp.Array(resp.BulkString("a"),
+			resp.Value{Type: resp.TypeDouble, Float: 1.5, Str: []byte("1.5")}))},
+	})
+}
+
+func TestServerDial(t *testing.T) {
+	s := resptest.NewServer()
+	defer s.Close()
+
+	c, err := s.Dial(context.Background())
+	if err != nil {
+		t.Fatalf("failed to dial: %s", err)
+	}
+	defer c.Close()
+
+	runSteps(t, c, []step{{cmd("PING"), resp.SimpleString("PONG")}})
+}
+
+func TestServerPipeLargePipeline(t *testing.T) {
+	s := resptest.NewServer()
+	defer s.Close()
+
+	c := newTestConn(t, s)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	value := strings.Repeat("x", 1024)
+
+	p := c.Pipeline()
+	for i := 0; i < 256; i++ {
+		p.Queue("SET", "key", value)
+		p.Queue("GET", "key")
+	}
+
+	vs, err := p.Exec(ctx)
+	if err != nil {
+		t.Fatalf("exec failed: %s", err)
+	}
+	if len(vs) != 512 || string(vs[511].Str) != value {
+		t.Errorf("got %d replies, expected 512 replies with the last being the value", len(vs))
+	}
+}
+
+func TestServerPipeDeadline(t *testing.T) {
+	s := resptest.NewServer()
+	defer s.Close()
+
+	c := newTestConn(t, s)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if _, err := c.Receive(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("got error %v, expected %v", err, context.DeadlineExceeded)
+	}
+	if v, err := c.Do(context.Background(), "PING"); err != nil || string(v.Str) != "PONG" {
+		t.Errorf("got %#v (error %v), expected PONG", v, err)
+	}
+}
@@ -0,0 +1,382 @@
+package resp
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// CommandInfo describes a command registered with a ServeMux.
+//
+// The information is used for validating the number of arguments and to reply to the COMMAND command.
+type CommandInfo struct {
+	// Name is the name of the command. Subcommands are registered using the name of the container command followed
+	// by a space and the subcommand name, for example "CLIENT SETNAME".
+	//
+	// Names are case-insensitive.
+	Name string
+
+	// Arity is the number of arguments, including the command name (and for subcommands the subcommand name).
+	//
+	// If Arity is positive, commands must have exactly Arity arguments. If Arity is negative, commands must have at
+	// least -Arity arguments. If Arity is 0, the number of arguments is not checked.
+	Arity int
+
+	// Flags contains the command flags, for example "readonly" or "fast".
+	Flags []string
+
+	// FirstKey is the position of the first key in the arguments or 0 if the command has no keys.
+	FirstKey int
+
+	// LastKey is the position of the last key in the arguments. A negative value counts from the end.
+	LastKey int
+
+	// Step is the distance between keys.
+	Step int
+
+	// Summary is a short description of the command used for COMMAND DOCS.
+	Summary string
+
+	// Since is the version the command was added in, used for COMMAND DOCS.
+	Since string
+
+	// Group is the command group, for example "string" or "server", used for COMMAND DOCS.
+	Group string
+}
+
+// ServeMux is a Handler that dispatches commands to other handlers based on the command name.
+//
+// Command names are matched case-insensitively. If a command has registered subcommands and the second argument
+// matches one of them, the subcommand handler is used. Otherwise the handler of the command itself is used, if any.
+//
+// Before calling a handler, ServeMux checks the number of arguments against the registered arity and replies with
+// the same errors as Redis for commands with a wrong number of arguments and for unknown commands and subcommands.
+//
+// Unless a handler for COMMAND was registered, ServeMux handles COMMAND, COMMAND COUNT, COMMAND DOCS, COMMAND INFO
+// and COMMAND LIST using the registered CommandInfo.
+type ServeMux struct {
+	mu   sync.RWMutex
+	cmds map[string]*muxEntry
+}
+
+type muxEntry struct {
+	info CommandInfo
+	h    Handler
+	subs map[string]*muxEntry
+}
+
+// NewServeMux returns a new, empty ServeMux.
+func NewServeMux() *ServeMux {
+	return &ServeMux{}
+}
+
+// Handle registers the handler for the command described by info.
+//
+// If a handler for the command is already registered, Handle panics.
+func (m *ServeMux) Handle(info CommandInfo, h Handler) {
+	if h == nil {
+		panic("resp: nil handler")
+	}
+
+	parts := strings.Fields(strings.ToLower(info.Name))
+	if len(parts) == 0 || len(parts) > 2 {
+		panic("resp: invalid command name " + strconv.Quote(info.Name))
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.cmds == nil {
+		m.cmds = make(map[string]*muxEntry)
+	}
+
+	e := m.cmds[parts[0]]
+	if e == nil {
+		e = &muxEntry{info: CommandInfo{Name: strings.ToUpper(parts[0]), Arity: -2}}
+		m.cmds[parts[0]] = e
+	}
+
+	if len(parts) == 2 {
+		if e.subs == nil {
+			e.subs = make(map[string]*muxEntry)
+		}
+		if e.subs[parts[1]] != nil {
+			panic("resp: multiple registrations for " + strconv.Quote(info.Name))
+		}
+		e.subs[parts[1]] = &muxEntry{info: info, h: h}
+		return
+	}
+
+	if e.h != nil {
+		panic("resp: multiple registrations for " + strconv.Quote(info.Name))
+	}
+	e.info, e.h = info, h
+}
+
+// HandleFunc registers the handler function for the command described by info.
+func (m *ServeMux) HandleFunc(info CommandInfo, fn func(w ReplyWriter, cmd *Command)) {
+	m.Handle(info, HandlerFunc(fn))
+}
+
+// Commands returns the CommandInfo of all registered commands and subcommands, sorted by name.
+func (m *ServeMux) Commands() []CommandInfo {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var infos []CommandInfo
+	for _, e := range sortedEntries(m.cmds) {
+		infos = append(infos, e.info)
+		for _, sub := range sortedEntries(e.subs) {
+			infos = append(infos, sub.info)
+		}
+	}
+	return infos
+}
+
+// sortedEntries returns the entries in subs sorted by name.
+func sortedEntries(subs map[string]*muxEntry) []*muxEntry {
+	names := make([]string, 0, len(subs))
+	for name := range subs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	es := make([]*muxEntry, len(names))
+	for i, name := range names {
+		es[i] = subs[name]
+	}
+	return es
+}
+
+// ServeRESP implements the Handler interface.
+func (m *ServeMux) ServeRESP(w ReplyWriter, cmd *Command) {
+	if len(cmd.Args) == 0 {
+		return
+	}
+
+	e, msg := m.lookup(cmd)
+	switch {
+	case msg != "":
+		_, _ = w.WriteError(msg)
+	case e == nil:
+		m.serveCommand(w, cmd)
+	default:
+		e.h.ServeRESP(w, cmd)
+	}
+}
+
+// Check returns the error that ServeRESP replies with if cmd is an unknown command, has an unknown subcommand or has
+// the wrong number of arguments, as ReplyError. If cmd would be passed to a handler, Check returns nil.
+//
+// This can be used to validate commands without executing them, for example when queueing commands in a transaction.
+func (m *ServeMux) Check(cmd *Command) error {
+	if len(cmd.Args) == 0 {
+		return nil
+	}
+	if _, msg := m.lookup(cmd); msg != "" {
+		return ReplyError(msg)
+	}
+	return nil
+}
+
+// lookup returns the entry for cmd or the error message for commands that can not be handled. If the built-in
+// COMMAND command is used, both are empty.
+func (m *ServeMux) lookup(cmd *Command) (*muxEntry, string) {
+	name := strings.ToLower(string(cmd.Args[0]))
+
+	m.mu.RLock()
+	e := m.cmds[name]
+	var sub *muxEntry
+	if e != nil && e.subs != nil && len(cmd.Args) > 1 {
+		sub = e.subs[strings.ToLower(string(cmd.Args[1]))]
+	}
+	m.mu.RUnlock()
+
+	switch {
+	case sub != nil:
+		e = sub
+	case e == nil && name == "command":
+		return nil, ""
+	case e == nil:
+		return nil, unknownCommandError(cmd.Args)
+	case e.h == nil && len(cmd.Args) == 1:
+		return nil, wrongArityError(name)
+	case e.h == nil:
+		return nil, "ERR unknown subcommand '" + truncate(string(cmd.Args[1]), 128) + "'. Try " +
+			strings.ToUpper(name) + " HELP."
+	}
+
+	if !checkArity(e.info.Arity, len(cmd.Args)) {
+		return nil, wrongArityError(strings.ToLower(strings.Join(strings.Fields(e.info.Name), "|")))
+	}
+	return e, ""
+}
+
+func checkArity(arity, n int) bool {
+	switch {
+	case arity > 0:
+		return n == arity
+	case arity < 0:
+		return n >= -arity
+	default:
+		return true
+	}
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
+
+func unknownCommandError(args [][]byte) string {
+	// same format and limits as used by Redis
+	var sb strings.Builder
+	for _, arg := range args[1:] {
+		if sb.Len() >= 128 {
+			break
+		}
+		n := 128 - sb.Len()
+		sb.WriteByte('\'')
+		sb.WriteString(truncate(string(arg), n))
+		sb.WriteString("' ")
+	}
+	return "ERR unknown command '" + truncate(string(args[0]), 128) + "', with args beginning with: " + sb.String()
+}
+
+func wrongArityError(name string) string {
+	return "ERR wrong number of arguments for '" + name + "' command"
+}
+
+// serveCommand implements the COMMAND command and its subcommands.
+func (m *ServeMux) serveCommand(w ReplyWriter, cmd *Command) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var v Value
+
+	sub := ""
+	if len(cmd.Args) > 1 {
+		sub = strings.ToLower(string(cmd.Args[1]))
+	}
+
+	switch sub {
+	case "":
+		es := sortedEntries(m.cmds)
+		v = Array(make([]Value, len(es))...)
+		for i, e := range es {
+			v.Elems[i] = m.commandInfoValue(e)
+		}
+	case "count":
+		if len(cmd.Args) != 2 {
+			_, _ = w.WriteError(wrongArityError("command|count"))
+			return
+		}
+		v = Integer(len(m.cmds))
+	case "list":
+		es := sortedEntries(m.cmds)
+		v = Array(make([]Value, len(es))...)
+		for i, e := range es {
+			v.Elems[i] = BulkString(strings.ToLower(e.info.Name))
+		}
+	case "info", "docs":
+		es := m.lookupEntries(cmd.Args[2:])
+		if sub == "info" {
+			v = Array(make([]Value, len(es))...)
+			for i, e := range es {
+				if e == nil {
+					v.Elems[i] = NullArray()
+				} else {
+					v.Elems[i] = m.commandInfoValue(e)
+				}
+			}
+			break
+		}
+		v = Map()
+		for _, e := range es {
+			if e != nil {
+				v.Elems = append(v.Elems, BulkString(strings.ToLower(e.info.Name)), m.commandDocsValue(e))
+			}
+		}
+	default:
+		_, _ = w.WriteError("ERR unknown subcommand '" + truncate(string(cmd.Args[1]), 128) + "'. Try COMMAND HELP.")
+		return
+	}
+
+	if w.Protocol() != RESP3 {
+		v = ToRESP2(v, nil)
+	}
+	_, _ = w.WriteValue(v)
+}
+
+// lookupEntries returns the entries for the given names or all entries if no name is given. Unknown names are
+// returned as nil.
+func (m *ServeMux) lookupEntries(names [][]byte) []*muxEntry {
+	if len(names) == 0 {
+		return sortedEntries(m.cmds)
+	}
+
+	es := make([]*muxEntry, len(names))
+	for i, name := range names {
+		parts := strings.SplitN(strings.ToLower(string(name)), "|", 2)
+		e := m.cmds[parts[0]]
+		if e != nil && len(parts) == 2 {
+			e = e.subs[parts[1]]
+		}
+		es[i] = e
+	}
+	return es
+}
+
+// commandInfoValue returns the reply to COMMAND INFO for the entry e.
+func (m *ServeMux) commandInfoValue(e *muxEntry) Value {
+	info := e.info
+
+	flags := Set(make([]Value, len(info.Flags))...)
+	for i, flag := range info.Flags {
+		flags.Elems[i] = SimpleString(flag)
+	}
+
+	subs := Array()
+	for _, sub := range sortedEntries(e.subs) {
+		subs.Elems = append(subs.Elems, m.commandInfoValue(sub))
+	}
+
+	return Array(
+		BulkString(strings.ToLower(strings.Join(strings.Fields(info.Name), "|"))),
+		Integer(info.Arity),
+		flags,
+		Integer(info.FirstKey),
+		Integer(info.LastKey),
+		Integer(info.Step),
+		Set(),   // ACL categories
+		Set(),   // tips
+		Array(), // key specifications
+		subs,
+	)
+}
+
+// commandDocsValue returns the reply to COMMAND DOCS for the entry e.
+func (m *ServeMux) commandDocsValue(e *muxEntry) Value {
+	docs := Map()
+	if e.info.Summary != "" {
+		docs.Elems = append(docs.Elems, BulkString("summary"), BulkString(e.info.Summary))
+	}
+	if e.info.Since != "" {
+		docs.Elems = append(docs.Elems, BulkString("since"), BulkString(e.info.Since))
+	}
+	if e.info.Group != "" {
+		docs.Elems = append(docs.Elems, BulkString("group"), BulkString(e.info.Group))
+	}
+	if e.subs != nil {
+		subs := Map()
+		for _, sub := range sortedEntries(e.subs) {
+			name := strings.ToLower(strings.Join(strings.Fields(sub.info.Name), "|"))
+			subs.Elems = append(subs.Elems, BulkString(name), m.commandDocsValue(sub))
+		}
+		docs.Elems = append(docs.Elems, BulkString("subcommands"), subs)
+	}
+	return docs
+}
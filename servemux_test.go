@@ -0,0 +1,169 @@
+package resp_test
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/nussjustin/resp"
+)
+
+func newTestServeMux() *resp.ServeMux {
+	mux := resp.NewServeMux()
+	mux.HandleFunc(resp.CommandInfo{Name: "get", Arity: 2, Flags: []string{"readonly", "fast"},
+		FirstKey: 1, LastKey: 1, Step: 1,
+		Summary: "Returns the string value of a key.", Since: "1.0.0", Group: "string"},
+		func(w resp.ReplyWriter, cmd *resp.Command) {
+			_, _ = w.WriteBulkString("value of " + string(cmd.Args[1]))
+		})
+	mux.HandleFunc(resp.CommandInfo{Name: "DEL", Arity: -2}, func(w resp.ReplyWriter, cmd *resp.Command) {
+		_, _ = w.WriteInteger(len(cmd.Args) - 1)
+	})
+	mux.HandleFunc(resp.CommandInfo{Name: "CLIENT SETNAME", Arity: 3, Summary: "Sets the connection name."},
+		func(w resp.ReplyWriter, cmd *resp.Command) {
+			_, _ = w.WriteSimpleString("OK")
+		})
+	mux.HandleFunc(resp.CommandInfo{Name: "client getname", Arity: 2}, func(w resp.ReplyWriter, cmd *resp.Command) {
+		_, _ = w.WriteNull()
+	})
+	return mux
+}
+
+func serveTestCommand(h resp.Handler, proto resp.Protocol, args ...string) resp.Value {
+	var buf bytes.Buffer
+	w := resp.NewWriter(&buf)
+	w.SetProtocol(proto)
+
+	cmd := &resp.Command{}
+	for _, arg := range args {
+		cmd.Args = append(cmd.Args, []byte(arg))
+	}
+	h.ServeRESP(w, cmd)
+
+	v, err := resp.NewReader(&buf).ReadValue()
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+func TestServeMux(t *testing.T) {
+	mux := newTestServeMux()
+
+	for _, test := range []struct {
+		Args     []string
+		Expected resp.Value
+	}{
+		{[]string{"GET", "k"}, resp.BulkString("value of k")},
+		{[]string{"gEt", "k"}, resp.BulkString("value of k")},
+		{[]string{"GET"}, resp.Error("ERR wrong number of arguments for 'get' command")},
+		{[]string{"GET", "a", "b"}, resp.Error("ERR wrong number of arguments for 'get' command")},
+		{[]string{"DEL", "a", "b"}, resp.Integer(2)},
+		{[]string{"DEL"}, resp.Error("ERR wrong number of arguments for 'del' command")},
+		{[]string{"client", "SetName", "x"}, resp.SimpleString("OK")},
+		{[]string{"CLIENT", "SETNAME"}, resp.Error("ERR wrong number of arguments for 'client|setname' command")},
+		{[]string{"CLIENT", "GETNAME"}, resp.Null()},
+		{[]string{"CLIENT"}, resp.Error("ERR wrong number of arguments for 'client' command")},
+		{[]string{"CLIENT", "KILL"}, resp.Error("ERR unknown subcommand 'KILL'. Try CLIENT HELP.")},
+		{[]string{"FOO"}, resp.Error("ERR unknown command 'FOO', with args beginning with: ")},
+		{[]string{"FOO", "a", "b"}, resp.Error("ERR unknown command 'FOO', with args beginning with: 'a' 'b' ")},
+		{[]string{"FOO", strings.Repeat("x", 200)},
+			resp.Error("ERR unknown command 'FOO', with args beginning with: '" + strings.Repeat("x", 128) + "' ")},
+	} {
+		if got := serveTestCommand(mux, resp.RESP2, test.Args...); !reflect.DeepEqual(got, test.Expected) {
+			t.Errorf("got %#v for %q, expected %#v", got, test.Args, test.Expected)
+		}
+
+		cmd := &resp.Command{}
+		for _, arg := range test.Args {
+			cmd.Args = append(cmd.Args, []byte(arg))
+		}
+
+		var expectedErr error
+		if test.Expected.Type == resp.TypeError {
+			expectedErr = resp.ReplyError(test.Expected.Str)
+		}
+		if err := mux.Check(cmd); err != expectedErr {
+			t.Errorf("got error %v from Check for %q, expected %v", err, test.Args, expectedErr)
+		}
+	}
+}
+
+func TestServeMuxCommand(t *testing.T) {
+	mux := newTestServeMux()
+
+	getInfo := resp.Array(
+		resp.BulkString("get"),
+		resp.Integer(2),
+		resp.Set(resp.SimpleString("readonly"), resp.SimpleString("fast")),
+		resp.Integer(1),
+		resp.Integer(1),
+		resp.Integer(1),
+		resp.Set(),
+		resp.Set(),
+		resp.Array(),
+		resp.Array(),
+	)
+
+	if got := serveTestCommand(mux, resp.RESP3, "COMMAND", "COUNT"); !reflect.DeepEqual(got, resp.Integer(3)) {
+		t.Errorf("got %#v, expected %#v", got, resp.Integer(3))
+	}
+
+	list := resp.Array(resp.BulkString("client"), resp.BulkString("del"), resp.BulkString("get"))
+	if got := serveTestCommand(mux, resp.RESP3, "COMMAND", "LIST"); !reflect.DeepEqual(got, list) {
+		t.Errorf("got %#v, expected %#v", got, list)
+	}
+
+	info := serveTestCommand(mux, resp.RESP3, "COMMAND", "INFO", "get", "unknown")
+	if expected := resp.Array(getInfo, resp.NullArray()); !reflect.DeepEqual(info, expected) {
+		t.Errorf("got %#v, expected %#v", info, expected)
+	}
+
+	all := serveTestCommand(mux, resp.RESP3, "COMMAND")
+	if len(all.Elems) != 3 {
+		t.Fatalf("got %d commands, expected %d", len(all.Elems), 3)
+	}
+	if subs := all.Elems[0].Elems[9]; len(subs.Elems) != 2 || string(subs.Elems[0].Elems[0].Str) != "client|getname" {
+		t.Errorf("got subcommands %#v, expected client|getname and client|setname", subs)
+	}
+
+	docs := serveTestCommand(mux, resp.RESP3, "COMMAND", "DOCS", "GET", "client")
+	expectedDocs := resp.Map(
+		resp.BulkString("get"), resp.Map(
+			resp.BulkString("summary"), resp.BulkString("Returns the string value of a key."),
+			resp.BulkString("since"), resp.BulkString("1.0.0"),
+			resp.BulkString("group"), resp.BulkString("string"),
+		),
+		resp.BulkString("client"), resp.Map(
+			resp.BulkString("subcommands"), resp.Map(
+				resp.BulkString("client|getname"), resp.Map(),
+				resp.BulkString("client|setname"), resp.Map(
+					resp.BulkString("summary"), resp.BulkString("Sets the connection name."),
+				),
+			),
+		),
+	)
+	if !reflect.DeepEqual(docs, expectedDocs) {
+		t.Errorf("got %#v, expected %#v", docs, expectedDocs)
+	}
+
+	// RESP2 clients get arrays instead of maps and sets
+	docs2 := serveTestCommand(mux, resp.RESP2, "COMMAND", "DOCS", "GET")
+	if docs2.Type != resp.TypeArray || docs2.Elems[1].Type != resp.TypeArray {
+		t.Errorf("got %#v, expected nested arrays", docs2)
+	}
+}
+
+func TestServeMuxHandlePanics(t *testing.T) {
+	for _, name := range []string{"", "A B C", "GET"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected panic for name %q", name)
+				}
+			}()
+			newTestServeMux().HandleFunc(resp.CommandInfo{Name: name}, func(resp.ReplyWriter, *resp.Command) {})
+		}()
+	}
+}
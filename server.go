@@ -0,0 +1,432 @@
+package resp
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"log"
+	"net"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// ErrServerClosed is returned by Server.Serve and Server.ListenAndServe after a call to Shutdown or Close.
+var ErrServerClosed = errors.New("server closed")
+
+// ReplyWriter is used by a Handler to write replies.
+//
+// It is implemented by *Writer.
+type ReplyWriter interface {
+	Protocol() Protocol
+
+	Write(p []byte) (int, error)
+	WriteArrayHeader(n int) (int, error)
+	WriteBulkStringHeader(n int) (int, error)
+	WriteBulkString(s string) (int, error)
+	WriteBulkStringBytes(s []byte) (int, error)
+	WriteError(s string) (int, error)
+	WriteErrorBytes(s []byte) (int, error)
+	WriteInteger(i int) (int, error)
+	WriteSimpleString(s string) (int, error)
+	WriteSimpleStringBytes(s []byte) (int, error)
+
+	WriteAttributeHeader(n int) (int, error)
+	WriteBigNumber(s string) (int, error)
+	WriteBlobError(s string) (int, error)
+	WriteBlobErrorBytes(s []byte) (int, error)
+	WriteBoolean(b bool) (int, error)
+	WriteDouble(f float64) (int, error)
+	WriteMapHeader(n int) (int, error)
+	WriteNull() (int, error)
+	WritePushHeader(n int) (int, error)
+	WriteSetHeader(n int) (int, error)
+	WriteVerbatimString(format string, s string) (int, error)
+
+	WriteValue(v Value) (int, error)
+	Encode(v interface{}) error
+}
+
+var _ ReplyWriter = (*Writer)(nil)
+
+// Handler handles commands received by a Server.
+//
+// ServeRESP must write exactly one reply for each command, unless the connection is switched into a mode that
+// does not reply to commands or replies with multiple values (for example when implementing Pub/Sub).
+//
+// Replies are buffered and flushed once all pipelined commands that were already received were handled.
+type Handler interface {
+	ServeRESP(w ReplyWriter, cmd *Command)
+}
+
+// HandlerFunc is an adapter that allows using ordinary functions as Handler.
+type HandlerFunc func(w ReplyWriter, cmd *Command)
+
+// ServeRESP implements the Handler interface by calling f(w, cmd).
+func (f HandlerFunc) ServeRESP(w ReplyWriter, cmd *Command) {
+	f(w, cmd)
+}
+
+// Server accepts connections and dispatches the received commands to a Handler.
+//
+// A Server must not be copied after first use.
+type Server struct {
+	// Addr is the TCP address used by ListenAndServe.
+	Addr string
+
+	// Handler handles all commands. It must not be nil.
+	Handler Handler
+
+	// ErrorLog is used to log errors when accepting connections and panics in handlers. If nil, the standard logger
+	// of the log package is used.
+	ErrorLog *log.Logger
+
+	mu         sync.Mutex
+	listeners  map[*net.Listener]struct{}
+	conns      map[*ServerConn]struct{}
+	inShutdown bool
+}
+
+// ServerConn is a single connection accepted by a Server.
+//
+// ServerConn can be used to store per-connection state, which is available to handlers via Command.Conn.
+type ServerConn struct {
+	srv  *Server
+	conn net.Conn
+	bw   *bufio.Writer
+	rw   ReadWriter
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu      sync.Mutex
+	idle    bool
+	closing bool
+	values  map[interface{}]interface{}
+	onClose []func()
+}
+
+// ListenAndServe listens on the TCP address s.Addr and calls Serve to handle connections.
+func (s *Server) ListenAndServe() error {
+	if s.shuttingDown() {
+		return ErrServerClosed
+	}
+
+	l, err := net.Listen("tcp", s.Addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l)
+}
+
+// Serve accepts connections on the given listener and handles each connection in a new goroutine.
+//
+// Serve always returns a non-nil error and closes l. After Shutdown or Close, the returned error is ErrServerClosed.
+func (s *Server) Serve(l net.Listener) error {
+	if !s.trackListener(&l, true) {
+		_ = l.Close()
+		return ErrServerClosed
+	}
+	defer s.trackListener(&l, false)
+
+	var tempDelay time.Duration
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if s.shuttingDown() {
+				return ErrServerClosed
+			}
+			if nerr, ok := err.(interface{ Temporary() bool }); ok && nerr.Temporary() {
+				if tempDelay == 0 {
+					tempDelay = 5 * time.Millisecond
+				} else if tempDelay *= 2; tempDelay > time.Second {
+					tempDelay = time.Second
+				}
+				s.logf("resp: accept error: %v; retrying in %v", err, tempDelay)
+				time.Sleep(tempDelay)
+				continue
+			}
+			_ = l.Close()
+			return err
+		}
+		tempDelay = 0
+
+		c := s.newConn(conn)
+		if !s.trackConn(c, true) {
+			_ = conn.Close()
+			continue
+		}
+		go c.serve()
+	}
+}
+
+// shutdownPollInterval is the interval in which Shutdown checks for idle connections.
+const shutdownPollInterval = 10 * time.Millisecond
+
+// Shutdown gracefully shuts down the server by closing all listeners, and then closing connections once they are
+// idle, waiting until all connections were closed.
+//
+// A connection is idle when all received commands were handled and all replies were flushed.
+//
+// If ctx is done before all connections were closed, the context error is returned. Remaining connections can be
+// closed using Close.
+func (s *Server) Shutdown(ctx context.Context) error {
+	err := s.closeListeners()
+
+	ticker := time.NewTicker(shutdownPollInterval)
+	defer ticker.Stop()
+
+	for !s.closeIdleConns() {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return err
+}
+
+// Close immediately closes all listeners and connections.
+func (s *Server) Close() error {
+	err := s.closeListeners()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for c := range s.conns {
+		c.cancel()
+		_ = c.conn.Close()
+	}
+	return err
+}
+
+func (s *Server) closeListeners() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.inShutdown = true
+
+	var err error
+	for l := range s.listeners {
+		if cerr := (*l).Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// closeIdleConns closes all idle connections and reports whether all connections were closed.
+func (s *Server) closeIdleConns() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for c := range s.conns {
+		c.mu.Lock()
+		if c.idle {
+			c.closing = true
+			_ = c.conn.Close()
+		}
+		c.mu.Unlock()
+	}
+	return len(s.conns) == 0
+}
+
+func (s *Server) shuttingDown() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.inShutdown
+}
+
+func (s *Server) trackListener(l *net.Listener, add bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !add {
+		delete(s.listeners, l)
+		return true
+	}
+	if s.inShutdown {
+		return false
+	}
+	if s.listeners == nil {
+		s.listeners = make(map[*net.Listener]struct{})
+	}
+	s.listeners[l] = struct{}{}
+	return true
+}
+
+func (s *Server) trackConn(c *ServerConn, add bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !add {
+		delete(s.conns, c)
+		return true
+	}
+	if s.inShutdown {
+		return false
+	}
+	if s.conns == nil {
+		s.conns = make(map[*ServerConn]struct{})
+	}
+	s.conns[c] = struct{}{}
+	return true
+}
+
+func (s *Server) logf(format string, args ...interface{}) {
+	if s.ErrorLog != nil {
+		s.ErrorLog.Printf(format, args...)
+	} else {
+		log.Printf(format, args...)
+	}
+}
+
+func (s *Server) newConn(conn net.Conn) *ServerConn {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &ServerConn{srv: s, conn: conn, bw: bufio.NewWriter(conn), ctx: ctx, cancel: cancel}
+	c.rw.Reader.Reset(conn)
+	c.rw.Writer.Reset(c.bw)
+	return c
+}
+
+// Close closes the connection after the current command was handled and all buffered replies were flushed.
+func (c *ServerConn) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closing = true
+}
+
+// LocalAddr returns the local network address.
+func (c *ServerConn) LocalAddr() net.Addr {
+	return c.conn.LocalAddr()
+}
+
+// RemoteAddr returns the remote network address.
+func (c *ServerConn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+// OnClose registers a function that is called after the connection was closed.
+//
+// Functions are called in the order they were registered.
+func (c *ServerConn) OnClose(fn func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onClose = append(c.onClose, fn)
+}
+
+// Protocol returns the protocol used for replies.
+func (c *ServerConn) Protocol() Protocol {
+	return c.rw.Writer.Protocol()
+}
+
+// SetProtocol sets the protocol used for replies, for example after a client sent HELLO.
+//
+// SetProtocol must only be called by a Handler while handling a command on this connection.
+func (c *ServerConn) SetProtocol(p Protocol) {
+	c.rw.Writer.SetProtocol(p)
+}
+
+// Value returns the value associated with key or nil.
+func (c *ServerConn) Value(key interface{}) interface{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.values[key]
+}
+
+// SetValue associates the value with the given key, replacing any existing value. If value is nil, the key is
+// removed.
+func (c *ServerConn) SetValue(key, value interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if value == nil {
+		delete(c.values, key)
+		return
+	}
+	if c.values == nil {
+		c.values = make(map[interface{}]interface{})
+	}
+	c.values[key] = value
+}
+
+// setIdle marks the connection as idle or active and reports whether the connection should be closed.
+func (c *ServerConn) setIdle(idle bool) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.idle = idle
+	return c.closing
+}
+
+func (c *ServerConn) serve() {
+	defer func() {
+		if v := recover(); v != nil {
+			c.srv.logf("resp: panic serving %v: %v\n%s", c.conn.RemoteAddr(), v, debug.Stack())
+		}
+
+		c.cancel()
+		_ = c.conn.Close()
+		c.srv.trackConn(c, false)
+
+		c.mu.Lock()
+		onClose := c.onClose
+		c.onClose = nil
+		c.mu.Unlock()
+
+		for _, fn := range onClose {
+			fn()
+		}
+	}()
+
+	cmd := Command{ctx: c.ctx, conn: c}
+
+	for {
+		// only flush once all pipelined commands were handled
+		if c.rw.Reader.Buffered() == 0 {
+			if err := c.bw.Flush(); err != nil {
+				return
+			}
+			if c.setIdle(true) || c.srv.shuttingDown() {
+				return
+			}
+		}
+
+		err := c.rw.Reader.ReadCommand(&cmd)
+		if c.setIdle(false) {
+			return
+		}
+		if err != nil {
+			if isProtocolError(err) {
+				if _, werr := c.rw.Writer.WriteError("ERR Protocol error: " + err.Error()); werr == nil {
+					_ = c.bw.Flush()
+				}
+			}
+			return
+		}
+		if len(cmd.Args) == 0 {
+			continue
+		}
+
+		c.srv.Handler.ServeRESP(&c.rw.Writer, &cmd)
+
+		c.mu.Lock()
+		closing := c.closing
+		c.mu.Unlock()
+
+		if closing {
+			_ = c.bw.Flush()
+			return
+		}
+	}
+}
+
+// isProtocolError returns true if err was caused by a client sending invalid data.
+func isProtocolError(err error) bool {
+	switch err {
+	case ErrInvalidArrayLength, ErrInvalidBulkStringLength, ErrInvalidCommand, ErrInvalidInteger,
+		ErrUnexpectedEOL, ErrUnexpectedType:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,193 @@
+package resp_test
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nussjustin/resp"
+)
+
+func newTestServer(tb testing.TB, h resp.Handler) (*resp.Server, string) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		tb.Fatalf("failed to listen: %s", err)
+	}
+
+	srv := &resp.Server{Handler: h}
+	done := make(chan error, 1)
+	go func() { done <- srv.Serve(l) }()
+
+	tb.Cleanup(func() {
+		_ = srv.Close()
+		if err := <-done; err != resp.ErrServerClosed {
+			tb.Errorf("got error %v from Serve, expected %v", err, resp.ErrServerClosed)
+		}
+	})
+	return srv, l.Addr().String()
+}
+
+func dialTestServer(tb testing.TB, addr string) *resp.Conn {
+	c, err := resp.Dial(context.Background(), "tcp", addr)
+	if err != nil {
+		tb.Fatalf("failed to dial: %s", err)
+	}
+	tb.Cleanup(func() { _ = c.Close() })
+	return c
+}
+
+type counterKey struct{}
+
+func TestServer(t *testing.T) {
+	var closed int32
+
+	_, addr := newTestServer(t, resp.HandlerFunc(func(w resp.ReplyWriter, cmd *resp.Command) {
+		switch {
+		case cmd.Is("ECHO"):
+			_, _ = w.WriteBulkStringBytes(cmd.Args[1])
+		case cmd.Is("INCR"):
+			n, _ := cmd.Conn().Value(counterKey{}).(int)
+			if n == 0 {
+				cmd.Conn().OnClose(func() { atomic.AddInt32(&closed, 1) })
+			}
+			cmd.Conn().SetValue(counterKey{}, n+1)
+			_, _ = w.WriteInteger(n + 1)
+		case cmd.Is("QUIT"):
+			_, _ = w.WriteSimpleString("OK")
+			cmd.Conn().Close()
+		default:
+			_, _ = w.WriteError("ERR unknown command '" + cmd.Name() + "'")
+		}
+	}))
+	ctx := context.Background()
+
+	c1, c2 := dialTestServer(t, addr), dialTestServer(t, addr)
+
+	if v, err := c1.Do(ctx, "echo", "hello"); err != nil {
+		t.Fatalf("do failed: %s", err)
+	} else if string(v.Str) != "hello" {
+		t.Errorf("got %q, expected %q", v.Str, "hello")
+	}
+
+	if _, err := c1.Do(ctx, "FOO"); err != resp.ReplyError("ERR unknown command 'FOO'") {
+		t.Errorf("got error %v, expected reply error", err)
+	}
+
+	// state is kept per connection
+	p := c1.Pipeline()
+	p.Queue("INCR")
+	p.Queue("INCR")
+	p.Queue("INCR")
+	vs, err := p.Exec(ctx)
+	if err != nil {
+		t.Fatalf("exec failed: %s", err)
+	}
+	if vs[2].Int != 3 {
+		t.Errorf("got %d, expected %d", vs[2].Int, 3)
+	}
+	if v, err := c2.Do(ctx, "INCR"); err != nil {
+		t.Fatalf("do failed: %s", err)
+	} else if v.Int != 1 {
+		t.Errorf("got %d, expected %d", v.Int, 1)
+	}
+
+	if _, err := c1.Do(ctx, "QUIT"); err != nil {
+		t.Fatalf("do failed: %s", err)
+	}
+	if _, err := c1.Do(ctx, "ECHO", "x"); err == nil {
+		t.Error("expected error after QUIT")
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for atomic.LoadInt32(&closed) != 1 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if n := atomic.LoadInt32(&closed); n != 1 {
+		t.Errorf("got %d OnClose calls, expected %d", n, 1)
+	}
+}
+
+func TestServerProtocolError(t *testing.T) {
+	_, addr := newTestServer(t, resp.HandlerFunc(func(w resp.ReplyWriter, cmd *resp.Command) {
+		_, _ = w.WriteSimpleString("OK")
+	}))
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("failed to dial: %s", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte("*1\r\n:1\r\n")); err != nil {
+		t.Fatalf("failed to write: %s", err)
+	}
+
+	r := resp.NewReader(conn)
+	b, err := r.ReadError(nil)
+	if err != nil {
+		t.Fatalf("failed to read error: %s", err)
+	}
+	if !strings.HasPrefix(string(b), "ERR Protocol error") {
+		t.Errorf("got %q, expected protocol error", b)
+	}
+	if _, err := r.Peek(); err == nil {
+		t.Error("expected connection to be closed")
+	}
+}
+
+func TestServerShutdown(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+
+	srv, addr := newTestServer(t, resp.HandlerFunc(func(w resp.ReplyWriter, cmd *resp.Command) {
+		if cmd.Is("SLOW") {
+			close(started)
+			<-release
+		}
+		_, _ = w.WriteSimpleString("OK")
+	}))
+
+	busy, idle := dialTestServer(t, addr), dialTestServer(t, addr)
+
+	if _, err := idle.Do(context.Background(), "PING"); err != nil {
+		t.Fatalf("do failed: %s", err)
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		_, err := busy.Do(context.Background(), "SLOW")
+		errc <- err
+	}()
+	<-started
+
+	shutdownErr := make(chan error, 1)
+	go func() { shutdownErr <- srv.Shutdown(context.Background()) }()
+
+	// the idle connection is closed while the active command completes
+	if _, err := idle.Receive(context.Background()); err == nil {
+		t.Error("expected idle connection to be closed")
+	}
+	select {
+	case err := <-shutdownErr:
+		t.Fatalf("shutdown returned early with error %v", err)
+	default:
+	}
+
+	close(release)
+	if err := <-errc; err != nil {
+		t.Errorf("active command failed: %s", err)
+	}
+	if err := <-shutdownErr; err != nil {
+		t.Errorf("shutdown failed: %s", err)
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %s", err)
+	}
+	if err := srv.Serve(l); err != resp.ErrServerClosed {
+		t.Errorf("got error %v, expected %v", err, resp.ErrServerClosed)
+	}
+}
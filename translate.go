@@ -0,0 +1,308 @@
+package resp
+
+import "strings"
+
+// ReplyShape describes the RESP3 form of a command reply.
+//
+// RESP2 has fewer types than RESP3, so replies sent to RESP2 clients lose information. For example the reply to
+// HGETALL is a map when using RESP3, but a flat array of keys and values when using RESP2. ReplyShape describes how
+// the RESP2 reply of a command maps to its RESP3 form and is used by ToRESP2 and ToRESP3.
+type ReplyShape struct {
+	// Type is the RESP3 type of the reply.
+	//
+	// TypeMap describes maps that are sent as flat arrays of keys and values using RESP2. TypeSet describes sets,
+	// which are sent as arrays. TypeDouble describes doubles, which are sent as bulk strings. TypeBoolean describes
+	// booleans, which are sent as the integers 1 and 0. TypeArray describes arrays with nested values that are
+	// converted. Other types describe values that are the same for both protocols.
+	Type Type
+
+	// Pairs is true for RESP3 arrays of 2-element arrays, which are flattened using RESP2, for example the reply to
+	// ZRANGE with WITHSCORES. Pairs is only used if Type is TypeArray.
+	Pairs bool
+
+	// Elem is the shape of the elements of arrays and sets and of the values of maps. For arrays with Pairs set, Elem
+	// is the shape of each pair.
+	Elem *ReplyShape
+
+	// Tuple contains the shapes of the elements of arrays with a fixed layout. If not nil, Tuple is used instead of
+	// Elem.
+	Tuple []*ReplyShape
+}
+
+// elem returns the shape of the i-th element of an array, set or map described by s.
+func (s *ReplyShape) elem(i int) *ReplyShape {
+	switch {
+	case s == nil:
+		return nil
+	case s.Tuple != nil && i < len(s.Tuple):
+		return s.Tuple[i]
+	case s.Tuple != nil:
+		return nil
+	default:
+		return s.Elem
+	}
+}
+
+// aggregate returns true if s describes an aggregate, in which case null values are sent as null arrays using RESP2.
+func (s *ReplyShape) aggregate() bool {
+	return s != nil && (s.Type == TypeArray || s.Type == TypeMap || s.Type == TypeSet)
+}
+
+var (
+	arrayShape  = &ReplyShape{Type: TypeArray}
+	mapShape    = &ReplyShape{Type: TypeMap}
+	setShape    = &ReplyShape{Type: TypeSet}
+	doubleShape = &ReplyShape{Type: TypeDouble}
+
+	// scoreShape describes a member followed by its score.
+	scoreShape = &ReplyShape{Type: TypeArray, Tuple: []*ReplyShape{nil, doubleShape}}
+
+	// scorePairsShape describes an array of members and their scores.
+	scorePairsShape = &ReplyShape{Type: TypeArray, Pairs: true, Elem: scoreShape}
+)
+
+// replyShapes contains the shapes for commands whose replies do not depend on their arguments.
+//
+// Commands that reply with an array are included even if the reply is the same for RESP2 and RESP3, so that ToRESP2
+// converts a null reply into a null array.
+var replyShapes = map[string]*ReplyShape{
+	"blmpop":              arrayShape,
+	"blpop":               arrayShape,
+	"brpop":               arrayShape,
+	"bzpopmax":            {Type: TypeArray, Tuple: []*ReplyShape{nil, nil, doubleShape}},
+	"bzpopmin":            {Type: TypeArray, Tuple: []*ReplyShape{nil, nil, doubleShape}},
+	"client trackinginfo": mapShape,
+	"command docs":        {Type: TypeMap, Elem: mapShape},
+	"config get":          mapShape,
+	"exec":                arrayShape,
+	"hello":               mapShape,
+	"hgetall":             mapShape,
+	"lmpop":               arrayShape,
+	"memory stats":        mapShape,
+	"sdiff":               setShape,
+	"sinter":              setShape,
+	"smembers":            setShape,
+	"sunion":              setShape,
+	"xinfo consumers":     {Type: TypeArray, Elem: mapShape},
+	"xinfo groups":        {Type: TypeArray, Elem: mapShape},
+	"xinfo stream":        mapShape,
+	"zincrby":             doubleShape,
+	"zmscore":             {Type: TypeArray, Elem: doubleShape},
+	"zscore":              doubleShape,
+}
+
+// hasArg returns true if one of args is equal to name, ignoring case.
+func hasArg(args [][]byte, name string) bool {
+	for _, arg := range args {
+		if strings.EqualFold(string(arg), name) {
+			return true
+		}
+	}
+	return false
+}
+
+// CommandReplyShape returns the shape of the reply to the command with the given arguments, including the command
+// name, or nil if the reply is the same for RESP2 and RESP3.
+//
+// Shapes are known for the Redis commands whose replies differ between RESP2 and RESP3, including commands like
+// ZRANGE whose reply depends on options like WITHSCORES. Unknown commands return nil.
+func CommandReplyShape(args [][]byte) *ReplyShape {
+	if len(args) == 0 {
+		return nil
+	}
+
+	name := strings.ToLower(string(args[0]))
+
+	switch name {
+	case "zrange", "zrangebyscore", "zrevrange", "zrevrangebyscore", "zdiff", "zinter", "zunion", "zrandmember":
+		if hasArg(args[1:], "WITHSCORES") {
+			return scorePairsShape
+		}
+		return nil
+	case "zpopmax", "zpopmin":
+		if len(args) > 2 {
+			return scorePairsShape
+		}
+		return scoreShape
+	case "hrandfield":
+		if len(args) > 2 && hasArg(args[2:], "WITHVALUES") {
+			return &ReplyShape{Type: TypeArray, Pairs: true}
+		}
+		return nil
+	case "zadd":
+		if len(args) > 2 && hasArg(args[2:], "INCR") {
+			return doubleShape
+		}
+		return nil
+	}
+
+	if len(args) > 1 {
+		if s, ok := replyShapes[name+" "+strings.ToLower(string(args[1]))]; ok {
+			return s
+		}
+	}
+	return replyShapes[name]
+}
+
+// ToRESP2 returns the RESP2 equivalent of the RESP3 value v, as Redis would send it to a RESP2 client.
+//
+// Maps are flattened into arrays of keys and values, sets and push messages are converted into arrays, booleans into
+// the integers 1 and 0, doubles, big numbers and verbatim strings (without the format prefix) into bulk strings and
+// blob errors into simple errors. Attributes nested in aggregates are removed. Attributes that are not nested are
+// returned unchanged and must be dropped by the caller.
+//
+// RESP3 nulls are converted into null arrays if shape describes an aggregate and into null bulk strings otherwise.
+// The shape is also used for nested values, for example to flatten the pairs in the reply to ZRANGE with WITHSCORES.
+// If shape is nil, nested nulls are converted into null bulk strings.
+//
+// Values that are already valid RESP2 values are returned unchanged. The returned value may share memory with v, but
+// v is not modified.
+func ToRESP2(v Value, shape *ReplyShape) Value {
+	switch v.Type {
+	case TypeArray, TypeSet, TypePush, TypeMap:
+		if v.Null {
+			return NullArray()
+		}
+		if !hasRESP3(v) && !(shape != nil && shape.Pairs) {
+			v.Type = TypeArray
+			return v
+		}
+		elems := make([]Value, 0, len(v.Elems))
+		for i, e := range v.Elems {
+			if e.Type == TypeAttribute {
+				continue
+			}
+			es := shape.elem(i)
+			if v.Type == TypeMap {
+				es = nil
+				if i%2 == 1 {
+					es = shape.elem(i / 2)
+				}
+			}
+			e = ToRESP2(e, es)
+			if shape != nil && shape.Pairs && e.Type == TypeArray && !e.Null {
+				elems = append(elems, e.Elems...)
+				continue
+			}
+			elems = append(elems, e)
+		}
+		return Array(elems...)
+	case TypeNull:
+		if shape.aggregate() {
+			return NullArray()
+		}
+		return NullBulkString()
+	case TypeBoolean:
+		if v.Bool {
+			return Integer(1)
+		}
+		return Integer(0)
+	case TypeDouble:
+		return BulkStringBytes(appendDoubleString([]byte{}, v))
+	case TypeBigNumber:
+		return BulkStringBytes(append([]byte{}, v.Str...))
+	case TypeVerbatimString:
+		return BulkStringBytes(append([]byte{}, verbatimText(v)...))
+	case TypeBlobError:
+		// simple errors can not contain line breaks
+		s := append([]byte{}, v.Str...)
+		for i, c := range s {
+			if c == '\r' || c == '\n' {
+				s[i] = ' '
+			}
+		}
+		return Value{Type: TypeError, Str: s}
+	default:
+		return v
+	}
+}
+
+// hasRESP3 returns true if any of the nested values of v is not a valid RESP2 value.
+func hasRESP3(v Value) bool {
+	for _, e := range v.Elems {
+		switch e.Type {
+		case TypeArray:
+			if hasRESP3(e) {
+				return true
+			}
+		case TypeBulkString, TypeError, TypeInteger, TypeSimpleString:
+		default:
+			return true
+		}
+	}
+	return false
+}
+
+// ToRESP3 returns the RESP3 equivalent of the RESP2 value v, as Redis would send it to a RESP3 client, using the
+// shape of the reply to convert arrays into maps or sets, bulk strings into doubles and integers into booleans.
+//
+// Null bulk strings and null arrays are always converted into RESP3 nulls, including nested values. If a value does
+// not match the shape, for example if an array that should be converted into a map has an odd number of elements,
+// the value is not converted.
+//
+// The shape of the reply to a Redis command can be found using CommandReplyShape.
+//
+// The returned value may share memory with v, but v is not modified.
+func ToRESP3(v Value, shape *ReplyShape) Value {
+	if v.IsNull() {
+		return Null()
+	}
+
+	var typ Type
+	if shape != nil {
+		typ = shape.Type
+	}
+
+	switch v.Type {
+	case TypeArray:
+		switch {
+		case typ == TypeMap && len(v.Elems)%2 == 0:
+			elems := make([]Value, len(v.Elems))
+			for i, e := range v.Elems {
+				var es *ReplyShape
+				if i%2 == 1 {
+					es = shape.elem(i / 2)
+				}
+				elems[i] = ToRESP3(e, es)
+			}
+			return Map(elems...)
+		case typ == TypeSet:
+			elems := make([]Value, len(v.Elems))
+			for i, e := range v.Elems {
+				elems[i] = ToRESP3(e, shape.elem(i))
+			}
+			return Set(elems...)
+		case typ == TypeArray && shape.Pairs && len(v.Elems)%2 == 0:
+			elems := make([]Value, len(v.Elems)/2)
+			for i := range elems {
+				elems[i] = ToRESP3(Array(v.Elems[2*i], v.Elems[2*i+1]), shape.elem(i))
+			}
+			return Array(elems...)
+		default:
+			if typ != TypeArray {
+				shape = nil
+			}
+			elems := make([]Value, len(v.Elems))
+			for i, e := range v.Elems {
+				elems[i] = ToRESP3(e, shape.elem(i))
+			}
+			return Array(elems...)
+		}
+	case TypeBulkString:
+		if typ != TypeDouble {
+			return v
+		}
+		f, err := parseDouble(v.Str)
+		if err != nil {
+			return v
+		}
+		return Value{Type: TypeDouble, Float: f, Str: append([]byte{}, v.Str...)}
+	case TypeInteger:
+		if typ != TypeBoolean || (v.Int != 0 && v.Int != 1) {
+			return v
+		}
+		return Boolean(v.Int == 1)
+	default:
+		return v
+	}
+}
@@ -0,0 +1,224 @@
+package resp_test
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/nussjustin/resp"
+)
+
+func commandArgs(s string) [][]byte {
+	var args [][]byte
+	for _, f := range strings.Fields(s) {
+		args = append(args, []byte(f))
+	}
+	return args
+}
+
+func TestToRESP2(t *testing.T) {
+	for _, test := range []struct {
+		Name     string
+		In       resp.Value
+		Command  string
+		Expected resp.Value
+	}{
+		{Name: "bulk string", In: resp.BulkString("foo"), Expected: resp.BulkString("foo")},
+		{Name: "null", In: resp.Null(), Command: "GET key", Expected: resp.NullBulkString()},
+		{Name: "null aggregate", In: resp.Null(), Command: "ZPOPMIN key", Expected: resp.NullArray()},
+		{Name: "null array", In: resp.NullArray(), Expected: resp.NullArray()},
+		{Name: "boolean true", In: resp.Boolean(true), Expected: resp.Integer(1)},
+		{Name: "boolean false", In: resp.Boolean(false), Expected: resp.Integer(0)},
+		{Name: "double", In: resp.Double(1.5), Command: "ZSCORE key member", Expected: resp.BulkString("1.5")},
+		{Name: "double inf", In: resp.Double(math.Inf(1)), Expected: resp.BulkString("inf")},
+		{
+			Name:     "double with representation",
+			In:       resp.Value{Type: resp.TypeDouble, Float: 10, Str: []byte("1e1")},
+			Expected: resp.BulkString("1e1"),
+		},
+		{Name: "big number", In: resp.BigNumber("12345678901234567890"), Expected: resp.BulkString("12345678901234567890")},
+		{Name: "verbatim string", In: resp.VerbatimString("txt", "a\nb"), Expected: resp.BulkString("a\nb")},
+		{Name: "blob error", In: resp.BlobError("ERR a\r\nb"), Expected: resp.Error("ERR a  b")},
+		{
+			Name:     "map",
+			In:       resp.Map(resp.BulkString("a"), resp.Integer(1), resp.BulkString("b"), resp.Null()),
+			Command:  "HGETALL key",
+			Expected: resp.Array(resp.BulkString("a"), resp.Integer(1), resp.BulkString("b"), resp.NullBulkString()),
+		},
+		{
+			Name:     "set",
+			In:       resp.Set(resp.BulkString("a"), resp.BulkString("b")),
+			Command:  "SMEMBERS key",
+			Expected: resp.Array(resp.BulkString("a"), resp.BulkString("b")),
+		},
+		{
+			Name:     "push",
+			In:       resp.Push(resp.BulkString("message"), resp.BulkString("channel"), resp.BulkString("hello")),
+			Expected: resp.Array(resp.BulkString("message"), resp.BulkString("channel"), resp.BulkString("hello")),
+		},
+		{
+			Name: "nested",
+			In: resp.Array(
+				resp.Map(resp.SimpleString("a"), resp.Set(resp.Boolean(true))),
+				resp.Value{Type: resp.TypeAttribute, Elems: []resp.Value{resp.SimpleString("ttl"), resp.Integer(1)}},
+				resp.Double(2),
+			),
+			Expected: resp.Array(
+				resp.Array(resp.SimpleString("a"), resp.Array(resp.Integer(1))),
+				resp.BulkString("2"),
+			),
+		},
+		{
+			Name: "pairs",
+			In: resp.Array(
+				resp.Array(resp.BulkString("a"), resp.Double(1)),
+				resp.Array(resp.BulkString("b"), resp.Double(2.5)),
+			),
+			Command: "ZRANGE key 0 -1 WITHSCORES",
+			Expected: resp.Array(
+				resp.BulkString("a"), resp.BulkString("1"),
+				resp.BulkString("b"), resp.BulkString("2.5"),
+			),
+		},
+		{
+			Name:     "pairs without RESP3 values",
+			In:       resp.Array(resp.Array(resp.BulkString("f"), resp.BulkString("v"))),
+			Command:  "HRANDFIELD key 1 WITHVALUES",
+			Expected: resp.Array(resp.BulkString("f"), resp.BulkString("v")),
+		},
+	} {
+		test := test
+
+		t.Run(test.Name, func(t *testing.T) {
+			in, _ := resp.AppendValue(nil, test.In)
+
+			got := resp.ToRESP2(test.In, resp.CommandReplyShape(commandArgs(test.Command)))
+			assertValue(t, got, test.Expected)
+
+			if after, _ := resp.AppendValue(nil, test.In); string(after) != string(in) {
+				t.Errorf("input was modified from %q to %q", in, after)
+			}
+		})
+	}
+}
+
+func TestToRESP3(t *testing.T) {
+	for _, test := range []struct {
+		Name     string
+		In       resp.Value
+		Command  string
+		Expected resp.Value
+	}{
+		{Name: "bulk string", In: resp.BulkString("1.5"), Command: "GET key", Expected: resp.BulkString("1.5")},
+		{Name: "null bulk string", In: resp.NullBulkString(), Command: "GET key", Expected: resp.Null()},
+		{Name: "null array", In: resp.NullArray(), Command: "BLPOP key 0", Expected: resp.Null()},
+		{Name: "double", In: resp.BulkString("1.5"), Command: "ZSCORE key member", Expected: resp.Double(1.5)},
+		{Name: "invalid double", In: resp.BulkString("x"), Command: "ZSCORE key member", Expected: resp.BulkString("x")},
+		{
+			Name:     "double with INCR",
+			In:       resp.BulkString("inf"),
+			Command:  "ZADD key INCR inf member",
+			Expected: resp.Double(math.Inf(1)),
+		},
+		{Name: "integer", In: resp.Integer(1), Command: "EXISTS key", Expected: resp.Integer(1)},
+		{
+			Name:     "map",
+			In:       resp.Array(resp.BulkString("a"), resp.BulkString("1"), resp.BulkString("b"), resp.NullBulkString()),
+			Command:  "hgetall key",
+			Expected: resp.Map(resp.BulkString("a"), resp.BulkString("1"), resp.BulkString("b"), resp.Null()),
+		},
+		{
+			Name:     "odd map",
+			In:       resp.Array(resp.BulkString("a")),
+			Command:  "HGETALL key",
+			Expected: resp.Array(resp.BulkString("a")),
+		},
+		{
+			Name:     "subcommand",
+			In:       resp.Array(resp.BulkString("maxmemory"), resp.BulkString("0")),
+			Command:  "CONFIG GET maxmemory",
+			Expected: resp.Map(resp.BulkString("maxmemory"), resp.BulkString("0")),
+		},
+		{
+			Name:     "set",
+			In:       resp.Array(resp.BulkString("a"), resp.BulkString("b")),
+			Command:  "SMEMBERS key",
+			Expected: resp.Set(resp.BulkString("a"), resp.BulkString("b")),
+		},
+		{
+			Name:     "nested nulls",
+			In:       resp.Array(resp.NullBulkString(), resp.Array(resp.NullBulkString())),
+			Command:  "MGET a b",
+			Expected: resp.Array(resp.Null(), resp.Array(resp.Null())),
+		},
+		{
+			Name:    "scores",
+			In:      resp.Array(resp.BulkString("a"), resp.BulkString("1"), resp.BulkString("b"), resp.BulkString("2.5")),
+			Command: "ZRANGE key 0 -1 withscores",
+			Expected: resp.Array(
+				resp.Array(resp.BulkString("a"), resp.Double(1)),
+				resp.Array(resp.BulkString("b"), resp.Double(2.5)),
+			),
+		},
+		{
+			Name:     "single score",
+			In:       resp.Array(resp.BulkString("a"), resp.BulkString("1")),
+			Command:  "ZPOPMIN key",
+			Expected: resp.Array(resp.BulkString("a"), resp.Double(1)),
+		},
+		{
+			Name:     "blocking pop",
+			In:       resp.Array(resp.BulkString("key"), resp.BulkString("a"), resp.BulkString("1")),
+			Command:  "BZPOPMIN key 0",
+			Expected: resp.Array(resp.BulkString("key"), resp.BulkString("a"), resp.Double(1)),
+		},
+		{
+			Name:     "without scores",
+			In:       resp.Array(resp.BulkString("a"), resp.BulkString("1")),
+			Command:  "ZRANGE key 0 -1",
+			Expected: resp.Array(resp.BulkString("a"), resp.BulkString("1")),
+		},
+		{
+			Name: "array of maps",
+			In: resp.Array(
+				resp.Array(resp.BulkString("name"), resp.BulkString("g1")),
+				resp.Array(resp.BulkString("name"), resp.BulkString("g2")),
+			),
+			Command: "XINFO GROUPS key",
+			Expected: resp.Array(
+				resp.Map(resp.BulkString("name"), resp.BulkString("g1")),
+				resp.Map(resp.BulkString("name"), resp.BulkString("g2")),
+			),
+		},
+		{Name: "error", In: resp.Error("ERR failed"), Command: "HGETALL key", Expected: resp.Error("ERR failed")},
+	} {
+		test := test
+
+		t.Run(test.Name, func(t *testing.T) {
+			shape := resp.CommandReplyShape(commandArgs(test.Command))
+			got := resp.ToRESP3(test.In, shape)
+			assertValue(t, got, test.Expected)
+
+			// converting back must result in the original value
+			assertValue(t, resp.ToRESP2(got, shape), test.In)
+		})
+	}
+}
+
+func TestToRESP3Boolean(t *testing.T) {
+	shape := &resp.ReplyShape{Type: resp.TypeArray, Elem: &resp.ReplyShape{Type: resp.TypeBoolean}}
+
+	got := resp.ToRESP3(resp.Array(resp.Integer(1), resp.Integer(0), resp.Integer(2)), shape)
+	assertValue(t, got, resp.Array(resp.Boolean(true), resp.Boolean(false), resp.Integer(2)))
+}
+
+func TestCommandReplyShapeNameOnly(t *testing.T) {
+	for _, name := range []string{"HRANDFIELD", "ZADD", "ZRANGE", "CONFIG", "GET"} {
+		if shape := resp.CommandReplyShape(commandArgs(name)); shape != nil {
+			t.Errorf("got shape %+v for %s, expected nil", shape, name)
+		}
+	}
+	if shape := resp.CommandReplyShape(nil); shape != nil {
+		t.Errorf("got shape %+v for empty command, expected nil", shape)
+	}
+}
@@ -0,0 +1,267 @@
+package resp
+
+// Value is a generic representation of a single RESP value, including all nested values.
+//
+// Values are usually created by Reader.ReadValue or using the constructor functions like BulkString or Array.
+type Value struct {
+	// Type is the RESP type of the value.
+	Type Type
+
+	// Null is true for null bulk strings, null arrays and RESP3 nulls.
+	Null bool
+
+	// Str holds the content of simple strings, errors, bulk strings, blob errors, verbatim strings (including the
+	// format prefix) and big numbers.
+	//
+	// For doubles read using ReadValue, Str holds the original textual representation, which is used by WriteValue
+	// to write the double unchanged.
+	Str []byte
+
+	// Int holds the value of integers.
+	Int int
+
+	// Float holds the value of doubles.
+	Float float64
+
+	// Bool holds the value of booleans.
+	Bool bool
+
+	// Elems holds the elements of arrays, sets and push messages as well as the alternating keys and values of
+	// maps and attributes.
+	Elems []Value
+}
+
+var (
+	_ Marshaler   = Value{}
+	_ Unmarshaler = (*Value)(nil)
+)
+
+// Array returns a new array containing the given elements.
+func Array(elems ...Value) Value {
+	if elems == nil {
+		elems = []Value{}
+	}
+	return Value{Type: TypeArray, Elems: elems}
+}
+
+// BigNumber returns a new RESP3 big number. The number is not validated.
+func BigNumber(s string) Value {
+	return Value{Type: TypeBigNumber, Str: []byte(s)}
+}
+
+// BlobError returns a new RESP3 blob error.
+func BlobError(s string) Value {
+	return Value{Type: TypeBlobError, Str: []byte(s)}
+}
+
+// Boolean returns a new RESP3 boolean.
+func Boolean(b bool) Value {
+	return Value{Type: TypeBoolean, Bool: b}
+}
+
+// BulkString returns a new bulk string.
+func BulkString(s string) Value {
+	return Value{Type: TypeBulkString, Str: []byte(s)}
+}
+
+// BulkStringBytes returns a new bulk string using the given byte slice, which is not copied.
+//
+// If b is nil, a null bulk string is returned.
+func BulkStringBytes(b []byte) Value {
+	if b == nil {
+		return NullBulkString()
+	}
+	return Value{Type: TypeBulkString, Str: b}
+}
+
+// Double returns a new RESP3 double.
+func Double(f float64) Value {
+	return Value{Type: TypeDouble, Float: f}
+}
+
+// Error returns a new simple error.
+func Error(s string) Value {
+	return Value{Type: TypeError, Str: []byte(s)}
+}
+
+// Integer returns a new integer.
+func Integer(i int) Value {
+	return Value{Type: TypeInteger, Int: i}
+}
+
+// Map returns a new RESP3 map from the given alternating keys and values.
+//
+// If an odd number of values is given, Map panics.
+func Map(kvs ...Value) Value {
+	if len(kvs)%2 != 0 {
+		panic("resp: odd number of values given to Map")
+	}
+	if kvs == nil {
+		kvs = []Value{}
+	}
+	return Value{Type: TypeMap, Elems: kvs}
+}
+
+// Null returns a new RESP3 null.
+func Null() Value {
+	return Value{Type: TypeNull, Null: true}
+}
+
+// NullArray returns a new null array.
+func NullArray() Value {
+	return Value{Type: TypeArray, Null: true}
+}
+
+// NullBulkString returns a new null bulk string.
+func NullBulkString() Value {
+	return Value{Type: TypeBulkString, Null: true}
+}
+
+// Push returns a new RESP3 push message containing the given elements.
+func Push(elems ...Value) Value {
+	if elems == nil {
+		elems = []Value{}
+	}
+	return Value{Type: TypePush, Elems: elems}
+}
+
+// Set returns a new RESP3 set containing the given elements.
+func Set(elems ...Value) Value {
+	if elems == nil {
+		elems = []Value{}
+	}
+	return Value{Type: TypeSet, Elems: elems}
+}
+
+// SimpleString returns a new simple string.
+func SimpleString(s string) Value {
+	return Value{Type: TypeSimpleString, Str: []byte(s)}
+}
+
+// VerbatimString returns a new RESP3 verbatim string with the given 3 character format (e.g. txt).
+func VerbatimString(format, s string) Value {
+	return Value{Type: TypeVerbatimString, Str: []byte(format + ":" + s)}
+}
+
+// Err returns the value as ReplyError if the value is an error or blob error, or nil otherwise.
+func (v Value) Err() error {
+	if v.Type == TypeError || v.Type == TypeBlobError {
+		return ReplyError(v.Str)
+	}
+	return nil
+}
+
+// IsNull returns true if the value is a null bulk string, a null array or a RESP3 null.
+func (v Value) IsNull() bool {
+	return v.Null || v.Type == TypeNull
+}
+
+// MarshalRESP implements the Marshaler interface.
+func (v Value) MarshalRESP(w *Writer) error {
+	_, err := w.WriteValue(v)
+	return err
+}
+
+// UnmarshalRESP implements the Unmarshaler interface.
+func (v *Value) UnmarshalRESP(r *Reader) error {
+	nv, err := r.ReadValue()
+	if err != nil {
+		return err
+	}
+	*v = nv
+	return nil
+}
+
+// ReadValue reads the next value, including all nested values.
+//
+// RESP3 attributes are returned as separate values of type TypeAttribute, followed by the value they describe.
+//
+// The returned value does not reference any memory owned by the Reader.
+func (rr *Reader) ReadValue() (Value, error) {
+	t, err := rr.Peek()
+	if err != nil {
+		return Value{}, err
+	}
+
+	v := Value{Type: t}
+
+	switch t {
+	case TypeArray, TypeSet, TypePush, TypeMap, TypeAttribute:
+		var n int
+		switch t {
+		case TypeArray:
+			n, err = rr.ReadArrayHeader()
+		case TypeSet:
+			n, err = rr.ReadSetHeader()
+		case TypePush:
+			n, err = rr.ReadPushHeader()
+		case TypeMap:
+			n, err = rr.ReadMapHeader()
+		case TypeAttribute:
+			n, err = rr.ReadAttributeHeader()
+		}
+		if err != nil {
+			return Value{}, err
+		}
+		if n == -1 {
+			v.Null = true
+			return v, nil
+		}
+		if t == TypeMap || t == TypeAttribute {
+			n *= 2
+		}
+		v.Elems = make([]Value, 0, preallocSize(n, maxPreallocElems))
+		for i := 0; i < n; i++ {
+			e, err := rr.ReadValue()
+			if err != nil {
+				return Value{}, rr.broken(err)
+			}
+			v.Elems = append(v.Elems, e)
+		}
+	case TypeBulkString:
+		v.Str, err = rr.ReadBulkString(nil)
+		v.Null = err == nil && v.Str == nil
+	case TypeBlobError:
+		v.Str, err = rr.ReadBlobError(nil)
+	case TypeVerbatimString:
+		v.Str, err = rr.ReadVerbatimString(nil)
+	case TypeSimpleString:
+		v.Str, err = rr.ReadSimpleString(nil)
+	case TypeError:
+		v.Str, err = rr.ReadError(nil)
+	case TypeBigNumber:
+		v.Str, err = rr.ReadBigNumber(nil)
+	case TypeInteger:
+		v.Int, err = rr.ReadInteger()
+	case TypeDouble:
+		if v.Float, err = rr.ReadDouble(); err == nil {
+			v.Str = append([]byte(nil), rr.buf...)
+		}
+	case TypeBoolean:
+		v.Bool, err = rr.ReadBoolean()
+	case TypeNull:
+		err = rr.ReadNull()
+		v.Null = true
+	default:
+		return Value{}, ErrUnexpectedType
+	}
+
+	if err != nil {
+		return Value{}, err
+	}
+	return v, nil
+}
+
+// WriteValue writes the value v, including all nested values.
+//
+// The value is encoded using AppendValue and written using a single call to the underlying io.Writer. If the type of
+// the value or of a nested value is unknown, ErrUnexpectedType is returned and nothing is written. If a map or
+// attribute has an odd number of elements, ErrInvalidMapLength or ErrInvalidAttributeLength is returned and nothing is
+// written.
+func (rw *Writer) WriteValue(v Value) (int, error) {
+	b, err := AppendValue(rw.buf[:0], v)
+	if err != nil {
+		return 0, err
+	}
+	return rw.write(b)
+}
@@ -0,0 +1,167 @@
+package resp_test
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/nussjustin/resp"
+)
+
+func TestReadWriteValue(t *testing.T) {
+	for _, test := range []struct {
+		Name     string
+		In       string
+		Expected resp.Value
+	}{
+		{
+			Name:     "simple string",
+			In:       "+OK\r\n",
+			Expected: resp.SimpleString("OK"),
+		},
+		{
+			Name:     "error",
+			In:       "-ERR failed\r\n",
+			Expected: resp.Error("ERR failed"),
+		},
+		{
+			Name:     "integer",
+			In:       ":-5\r\n",
+			Expected: resp.Integer(-5),
+		},
+		{
+			Name:     "bulk string",
+			In:       "$5\r\nhello\r\n",
+			Expected: resp.BulkString("hello"),
+		},
+		{
+			Name:     "null bulk string",
+			In:       "$-1\r\n",
+			Expected: resp.NullBulkString(),
+		},
+		{
+			Name:     "null array",
+			In:       "*-1\r\n",
+			Expected: resp.NullArray(),
+		},
+		{
+			Name:     "array",
+			In:       "*2\r\n$1\r\na\r\n*1\r\n:1\r\n",
+			Expected: resp.Array(resp.BulkString("a"), resp.Array(resp.Integer(1))),
+		},
+		{
+			Name:     "empty array",
+			In:       "*0\r\n",
+			Expected: resp.Array(),
+		},
+		{
+			Name:     "null",
+			In:       "_\r\n",
+			Expected: resp.Null(),
+		},
+		{
+			Name:     "boolean",
+			In:       "#t\r\n",
+			Expected: resp.Boolean(true),
+		},
+		{
+			Name:     "double",
+			In:       ",1.5\r\n",
+			Expected: resp.Value{Type: resp.TypeDouble, Float: 1.5, Str: []byte("1.5")},
+		},
+		{
+			Name:     "big number",
+			In:       "(12345678901234567890\r\n",
+			Expected: resp.BigNumber("12345678901234567890"),
+		},
+		{
+			Name:     "blob error",
+			In:       "!7\r\nERR foo\r\n",
+			Expected: resp.BlobError("ERR foo"),
+		},
+		{
+			Name:     "verbatim string",
+			In:       "=7\r\ntxt:foo\r\n",
+			Expected: resp.VerbatimString("txt", "foo"),
+		},
+		{
+			Name:     "map",
+			In:       "%1\r\n+key\r\n~2\r\n:1\r\n:2\r\n",
+			Expected: resp.Map(resp.SimpleString("key"), resp.Set(resp.Integer(1), resp.Integer(2))),
+		},
+		{
+			Name:     "push",
+			In:       ">2\r\n+message\r\n$1\r\nx\r\n",
+			Expected: resp.Push(resp.SimpleString("message"), resp.BulkString("x")),
+		},
+	} {
+		test := test
+
+		t.Run(test.Name, func(t *testing.T) {
+			r := resp.NewReader(strings.NewReader(test.In))
+
+			got, err := r.ReadValue()
+			if err != nil {
+				t.Fatalf("failed to read value: %s", err)
+			}
+			if !reflect.DeepEqual(got, test.Expected) {
+				t.Errorf("got %#v, expected %#v", got, test.Expected)
+			}
+
+			var buf bytes.Buffer
+			if _, err := resp.NewWriter(&buf).WriteValue(got); err != nil {
+				t.Fatalf("failed to write value: %s", err)
+			}
+			assertBytes(t, buf.Bytes(), test.In)
+		})
+	}
+}
+
+func TestWriterWriteValueDouble(t *testing.T) {
+	var buf bytes.Buffer
+	if _, err := resp.NewWriter(&buf).WriteValue(resp.Double(2.5)); err != nil {
+		t.Fatalf("failed to write value: %s", err)
+	}
+	assertBytes(t, buf.Bytes(), ",2.5\r\n")
+}
+
+func TestWriterWriteValueOddMap(t *testing.T) {
+	odd := resp.Value{Type: resp.TypeMap, Elems: []resp.Value{resp.SimpleString("a")}}
+
+	var buf bytes.Buffer
+	if _, err := resp.NewWriter(&buf).WriteValue(odd); err != resp.ErrInvalidMapLength {
+		t.Errorf("got error %v, expected %v", err, resp.ErrInvalidMapLength)
+	}
+	assertBytes(t, buf.Bytes(), "")
+}
+
+func TestValueErr(t *testing.T) {
+	if err := resp.Error("ERR x").Err(); err != resp.ReplyError("ERR x") {
+		t.Errorf("got %v, expected %v", err, resp.ReplyError("ERR x"))
+	}
+	if err := resp.BlobError("ERR y").Err(); err != resp.ReplyError("ERR y") {
+		t.Errorf("got %v, expected %v", err, resp.ReplyError("ERR y"))
+	}
+	if err := resp.SimpleString("OK").Err(); err != nil {
+		t.Errorf("got %v, expected nil", err)
+	}
+}
+
+func TestValueEncodeDecode(t *testing.T) {
+	v := resp.Array(resp.BulkString("a"), resp.Integer(1))
+
+	b, err := resp.Marshal(struct{ V resp.Value }{v})
+	if err != nil {
+		t.Fatalf("failed to marshal: %s", err)
+	}
+	assertBytes(t, b, "*2\r\n$1\r\nV\r\n*2\r\n$1\r\na\r\n:1\r\n")
+
+	var got struct{ V resp.Value }
+	if err := resp.Unmarshal(b, &got); err != nil {
+		t.Fatalf("failed to unmarshal: %s", err)
+	}
+	if !reflect.DeepEqual(got.V, v) {
+		t.Errorf("got %#v, expected %#v", got.V, v)
+	}
+}
@@ -2,13 +2,13 @@ package resp
 
 import (
 	"io"
-	"strconv"
 )
 
 // Writer wraps an io.Writer and provides methods for writing the RESP protocol.
 type Writer struct {
-	w   io.Writer
-	buf []byte
+	w     io.Writer
+	buf   []byte
+	proto Protocol
 }
 
 // NewWriter returns a *Writer that uses the given io.Writer for writes.
@@ -21,36 +21,32 @@ func NewWriter(w io.Writer) *Writer {
 var _ io.Writer = (*Writer)(nil)
 
 // Reset sets the underlying io.Writer to w and resets all internal state.
+//
+// The protocol version set via SetProtocol is kept.
 func (rw *Writer) Reset(w io.Writer) {
 	rw.buf = rw.buf[:0]
 	rw.w = w
 }
 
-func (rw *Writer) writeBytes(prefix byte, s []byte) (int, error) {
-	rw.buf = rw.buf[:0]
-	rw.buf = append(rw.buf, prefix)
-	rw.buf = append(rw.buf, s...)
-	rw.buf = append(rw.buf, '\r', '\n')
-
-	return rw.w.Write(rw.buf)
+// Protocol returns the protocol version used by Encode. The default is RESP2.
+func (rw *Writer) Protocol() Protocol {
+	if rw.proto == 0 {
+		return RESP2
+	}
+	return rw.proto
 }
 
-func (rw *Writer) writeNumber(prefix byte, n int64) (int, error) {
-	rw.buf = rw.buf[:0]
-	rw.buf = append(rw.buf, prefix)
-	rw.buf = strconv.AppendInt(rw.buf, n, 10)
-	rw.buf = append(rw.buf, '\r', '\n')
-
-	return rw.w.Write(rw.buf)
+// SetProtocol sets the protocol version used by Encode.
+//
+// The protocol version has no effect on the other Write methods, which always write the requested type.
+func (rw *Writer) SetProtocol(p Protocol) {
+	rw.proto = p
 }
 
-func (rw *Writer) writeString(prefix byte, s string) (int, error) {
-	rw.buf = rw.buf[:0]
-	rw.buf = append(rw.buf, prefix)
-	rw.buf = append(rw.buf, s...)
-	rw.buf = append(rw.buf, '\r', '\n')
-
-	return rw.w.Write(rw.buf)
+// write writes b, which must be built in rw.buf, to the underlying io.Writer, keeping the buffer for reuse.
+func (rw *Writer) write(b []byte) (int, error) {
+	rw.buf = b
+	return rw.w.Write(b)
 }
 
 // Write allows writing raw data to the underlying io.Writer.
@@ -60,8 +56,6 @@ func (rw *Writer) Write(dst []byte) (int, error) {
 	return rw.w.Write(dst)
 }
 
-var nilArrayHeaderBytes = []byte("*-1\r\n")
-
 // WriteArrayHeader writes an array header for an array of length n.
 //
 // If n is < -1, ErrInvalidArrayLength is returned.
@@ -69,16 +63,9 @@ func (rw *Writer) WriteArrayHeader(n int) (int, error) {
 	if n < -1 {
 		return 0, ErrInvalidArrayLength
 	}
-
-	if n == -1 { // fast-path
-		return rw.w.Write(nilArrayHeaderBytes)
-	}
-
-	return rw.writeNumber('*', int64(n))
+	return rw.write(AppendArrayHeader(rw.buf[:0], n))
 }
 
-var nilBulkStringHeaderBytes = []byte("$-1\r\n")
-
 // WriteBulkStringHeader writes a bulk string header for an bulk string of length n.
 //
 // If n is < -1, ErrInvalidBulkStringLength is returned.
@@ -86,65 +73,121 @@ func (rw *Writer) WriteBulkStringHeader(n int) (int, error) {
 	if n < -1 {
 		return 0, ErrInvalidBulkStringLength
 	}
-
-	if n == -1 { // fast-path
-		return rw.w.Write(nilBulkStringHeaderBytes)
-	}
-
-	return rw.writeNumber('$', int64(n))
+	return rw.write(AppendBulkStringHeader(rw.buf[:0], n))
 }
 
 // WriteBulkString writes the string s as bulk string.
 //
 // If you need to write a nil bulk string, use WriteBulkStringBytes instead.
 func (rw *Writer) WriteBulkString(s string) (int, error) {
-	rw.buf = rw.buf[:0]
-	rw.buf = append(rw.buf, '$')
-	rw.buf = strconv.AppendUint(rw.buf, uint64(len(s)), 10)
-	rw.buf = append(rw.buf, '\r', '\n')
-	rw.buf = append(rw.buf, s...)
-	rw.buf = append(rw.buf, '\r', '\n')
-
-	return rw.w.Write(rw.buf)
+	return rw.write(AppendBulkString(rw.buf[:0], s))
 }
 
 // WriteBulkStringBytes writes the byte slice s as bulk string.
 func (rw *Writer) WriteBulkStringBytes(s []byte) (int, error) {
-	if s == nil {
-		return rw.WriteBulkStringHeader(-1)
-	}
-
-	rw.buf = rw.buf[:0]
-	rw.buf = append(rw.buf, '$')
-	rw.buf = strconv.AppendUint(rw.buf, uint64(len(s)), 10)
-	rw.buf = append(rw.buf, '\r', '\n')
-	rw.buf = append(rw.buf, s...)
-	rw.buf = append(rw.buf, '\r', '\n')
-
-	return rw.w.Write(rw.buf)
+	return rw.write(AppendBulkStringBytes(rw.buf[:0], s))
 }
 
 // WriteError writes the string s unvalidated as a simple error.
 func (rw *Writer) WriteError(s string) (int, error) {
-	return rw.writeString('-', s)
+	return rw.write(AppendError(rw.buf[:0], s))
 }
 
 // WriteErrorBytes writes the byte slice s unvalidated as a simple error.
 func (rw *Writer) WriteErrorBytes(s []byte) (int, error) {
-	return rw.writeBytes('-', s)
+	return rw.write(AppendErrorBytes(rw.buf[:0], s))
 }
 
 // WriteInteger writes the integer i as the native RESP integer type.
 func (rw *Writer) WriteInteger(i int) (int, error) {
-	return rw.writeNumber(':', int64(i))
+	return rw.write(AppendInteger(rw.buf[:0], i))
 }
 
 // WriteSimpleString writes the string s unvalidated as a simple string.
 func (rw *Writer) WriteSimpleString(s string) (int, error) {
-	return rw.writeString('+', s)
+	return rw.write(AppendSimpleString(rw.buf[:0], s))
 }
 
 // WriteSimpleStringBytes writes the byte slice s unvalidated as a simple string.
 func (rw *Writer) WriteSimpleStringBytes(s []byte) (int, error) {
-	return rw.writeBytes('+', s)
+	return rw.write(AppendSimpleStringBytes(rw.buf[:0], s))
+}
+
+// WriteAttributeHeader writes a RESP3 attribute header for an attribute map with n key-value pairs.
+//
+// If n is < 0, ErrInvalidAttributeLength is returned.
+func (rw *Writer) WriteAttributeHeader(n int) (int, error) {
+	if n < 0 {
+		return 0, ErrInvalidAttributeLength
+	}
+	return rw.write(AppendAttributeHeader(rw.buf[:0], n))
+}
+
+// WriteBigNumber writes the string s unvalidated as a RESP3 big number.
+func (rw *Writer) WriteBigNumber(s string) (int, error) {
+	return rw.write(AppendBigNumber(rw.buf[:0], s))
+}
+
+// WriteBlobError writes the string s as a RESP3 blob error.
+func (rw *Writer) WriteBlobError(s string) (int, error) {
+	return rw.write(AppendBlobError(rw.buf[:0], s))
+}
+
+// WriteBlobErrorBytes writes the byte slice s as a RESP3 blob error.
+func (rw *Writer) WriteBlobErrorBytes(s []byte) (int, error) {
+	return rw.write(AppendBlobErrorBytes(rw.buf[:0], s))
+}
+
+// WriteBoolean writes b as a RESP3 boolean.
+func (rw *Writer) WriteBoolean(b bool) (int, error) {
+	return rw.write(AppendBoolean(rw.buf[:0], b))
+}
+
+// WriteDouble writes f as a RESP3 double.
+//
+// Infinite values are written as inf and -inf and NaN is written as nan.
+func (rw *Writer) WriteDouble(f float64) (int, error) {
+	return rw.write(AppendDouble(rw.buf[:0], f))
+}
+
+// WriteMapHeader writes a RESP3 map header for a map with n key-value pairs.
+//
+// If n is < 0, ErrInvalidMapLength is returned.
+func (rw *Writer) WriteMapHeader(n int) (int, error) {
+	if n < 0 {
+		return 0, ErrInvalidMapLength
+	}
+	return rw.write(AppendMapHeader(rw.buf[:0], n))
+}
+
+// WriteNull writes a RESP3 null.
+func (rw *Writer) WriteNull() (int, error) {
+	return rw.write(AppendNull(rw.buf[:0]))
+}
+
+// WritePushHeader writes a RESP3 push header for a push message with n elements.
+//
+// If n is < 0, ErrInvalidPushLength is returned.
+func (rw *Writer) WritePushHeader(n int) (int, error) {
+	if n < 0 {
+		return 0, ErrInvalidPushLength
+	}
+	return rw.write(AppendPushHeader(rw.buf[:0], n))
+}
+
+// WriteSetHeader writes a RESP3 set header for a set with n elements.
+//
+// If n is < 0, ErrInvalidSetLength is returned.
+func (rw *Writer) WriteSetHeader(n int) (int, error) {
+	if n < 0 {
+		return 0, ErrInvalidSetLength
+	}
+	return rw.write(AppendSetHeader(rw.buf[:0], n))
+}
+
+// WriteVerbatimString writes the string s as a RESP3 verbatim string using the given 3 character format (e.g. txt).
+//
+// The format is not validated.
+func (rw *Writer) WriteVerbatimString(format string, s string) (int, error) {
+	return rw.write(AppendVerbatimString(rw.buf[:0], format, s))
 }
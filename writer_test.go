@@ -5,6 +5,7 @@ import (
 	"bytes"
 	"io"
 	"io/ioutil"
+	"math"
 	"strconv"
 	"strings"
 	"testing"
@@ -455,3 +456,186 @@ func BenchmarkWriterWriteSimpleString(b *testing.B) {
 		})
 	}
 }
+
+func TestWriterWriteAttributeHeader(t *testing.T) {
+	testWriteAggregateHeader(t, "|", resp.ErrInvalidAttributeLength, (*resp.Writer).WriteAttributeHeader)
+}
+
+func TestWriterWriteBigNumber(t *testing.T) {
+	var buf bytes.Buffer
+	w := resp.NewWriter(&buf)
+
+	if _, err := w.WriteBigNumber("3492890328409238509324850943850943825024385"); err != nil {
+		t.Fatalf("write failed: %s", err)
+	}
+	assertBytes(t, buf.Bytes(), "(3492890328409238509324850943850943825024385\r\n")
+}
+
+func TestWriterWriteBlobError(t *testing.T) {
+	for _, test := range []simpleWriteCase{
+		{
+			Name:     "empty",
+			Expected: "!0\r\n\r\n",
+			In:       []byte{},
+		},
+		{
+			Name:     "small",
+			Expected: "!21\r\nSYNTAX invalid syntax\r\n",
+			In:       []byte("SYNTAX invalid syntax"),
+		},
+		{
+			Name:     "with \\r\\n",
+			Expected: "!13\r\nhello\r\nworld!\r\n",
+			In:       []byte("hello\r\nworld!"),
+		},
+	} {
+		test.run(t,
+			(*resp.Writer).WriteBlobError,
+			(*resp.Writer).WriteBlobErrorBytes)
+	}
+}
+
+func TestWriterWriteBoolean(t *testing.T) {
+	var buf bytes.Buffer
+	w := resp.NewWriter(&buf)
+
+	if _, err := w.WriteBoolean(true); err != nil {
+		t.Fatalf("write failed: %s", err)
+	}
+	if _, err := w.WriteBoolean(false); err != nil {
+		t.Fatalf("write failed: %s", err)
+	}
+	assertBytes(t, buf.Bytes(), "#t\r\n#f\r\n")
+}
+
+func TestWriterWriteDouble(t *testing.T) {
+	for _, test := range []struct {
+		Name     string
+		Expected string
+		F        float64
+	}{
+		{
+			Name:     "zero",
+			Expected: ",0\r\n",
+			F:        0,
+		},
+		{
+			Name:     "fraction",
+			Expected: ",1.23\r\n",
+			F:        1.23,
+		},
+		{
+			Name:     "negative",
+			Expected: ",-1.5\r\n",
+			F:        -1.5,
+		},
+		{
+			Name:     "exponent",
+			Expected: ",1e+21\r\n",
+			F:        1e21,
+		},
+		{
+			Name:     "inf",
+			Expected: ",inf\r\n",
+			F:        math.Inf(1),
+		},
+		{
+			Name:     "negative inf",
+			Expected: ",-inf\r\n",
+			F:        math.Inf(-1),
+		},
+		{
+			Name:     "nan",
+			Expected: ",nan\r\n",
+			F:        math.NaN(),
+		},
+	} {
+		test := test
+
+		t.Run(test.Name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := resp.NewWriter(&buf)
+
+			if _, err := w.WriteDouble(test.F); err != nil {
+				t.Errorf("got error %q", err)
+			} else if got := buf.String(); got != test.Expected {
+				t.Errorf("got %q, expected %q", got, test.Expected)
+			}
+		})
+	}
+}
+
+func TestWriterWriteMapHeader(t *testing.T) {
+	testWriteAggregateHeader(t, "%", resp.ErrInvalidMapLength, (*resp.Writer).WriteMapHeader)
+}
+
+func TestWriterWriteNull(t *testing.T) {
+	var buf bytes.Buffer
+	w := resp.NewWriter(&buf)
+
+	if _, err := w.WriteNull(); err != nil {
+		t.Fatalf("write failed: %s", err)
+	}
+	assertBytes(t, buf.Bytes(), "_\r\n")
+}
+
+func TestWriterWritePushHeader(t *testing.T) {
+	testWriteAggregateHeader(t, ">", resp.ErrInvalidPushLength, (*resp.Writer).WritePushHeader)
+}
+
+func TestWriterWriteSetHeader(t *testing.T) {
+	testWriteAggregateHeader(t, "~", resp.ErrInvalidSetLength, (*resp.Writer).WriteSetHeader)
+}
+
+func TestWriterWriteVerbatimString(t *testing.T) {
+	var buf bytes.Buffer
+	w := resp.NewWriter(&buf)
+
+	if _, err := w.WriteVerbatimString("txt", "Some string"); err != nil {
+		t.Fatalf("write failed: %s", err)
+	}
+	assertBytes(t, buf.Bytes(), "=15\r\ntxt:Some string\r\n")
+}
+
+func testWriteAggregateHeader(t *testing.T, prefix string, invalidErr error, fn func(*resp.Writer, int) (int, error)) {
+	for _, test := range []struct {
+		Name     string
+		Expected string
+		Err      error
+		N        int
+	}{
+		{
+			Name:     "zero",
+			Expected: prefix + "0\r\n",
+			N:        0,
+		},
+		{
+			Name: "negative",
+			Err:  invalidErr,
+			N:    -1,
+		},
+		{
+			Name:     "one",
+			Expected: prefix + "1\r\n",
+			N:        1,
+		},
+		{
+			Name:     "big",
+			Expected: prefix + "123\r\n",
+			N:        123,
+		},
+	} {
+		test := test
+
+		t.Run(test.Name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := resp.NewWriter(&buf)
+
+			if _, err := fn(w, test.N); err != test.Err {
+				t.Errorf("got error %v, expected %v", err, test.Err)
+			} else if got := buf.String(); got != test.Expected {
+				t.Errorf("got %q, expected %q", got, test.Expected)
+			}
+		})
+	}
+}